@@ -0,0 +1,61 @@
+package main
+
+import (
+	"chip8emulator/chip8"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runCFG writes the control-flow graph of the program found by static analysis.
+func runCFG(args []string) error {
+	flags := flag.NewFlagSet("cfg", flag.ContinueOnError)
+	format := flags.String("format", "dot", "format of the graph: dot (basic blocks), calls (call graph in dot) or json")
+	platform := addPlatformFlag(flags)
+	entry := flags.String("entry", "", "hexadecimal address where the analysis starts, defaults to where the program starts")
+	output := flags.String("o", "", "output file, defaults to the standard output")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: cfg [flags] file.ch8")
+	}
+	selected, err := platform.platform()
+	if err != nil {
+		return err
+	}
+
+	headless, err := newHeadlessRun(flags.Arg(0), "", selected)
+	if err != nil {
+		return err
+	}
+	// the program starts at pc after loading, HIRES programs skip their jump to 0x260
+	address := headless.chip.Pc
+	if *entry != "" {
+		var ok bool
+		if address, ok = parseAddressInput(*entry); !ok {
+			return fmt.Errorf("invalid entry address %q", *entry)
+		}
+	}
+	graph := chip8.BuildControlFlowGraph(selected, headless.chip.Memory, address)
+
+	var write func(w io.Writer) error
+	switch *format {
+	case "dot":
+		write = graph.WriteDOT
+	case "calls":
+		write = graph.WriteCallGraphDOT
+	case "json":
+		write = graph.WriteJSON
+	default:
+		return fmt.Errorf("unknown graph format %q", *format)
+	}
+
+	if *output == "" {
+		return write(os.Stdout)
+	}
+	return writeFile(*output, write)
+}
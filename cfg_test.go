@@ -0,0 +1,97 @@
+package main
+
+import (
+	"chip8emulator/chip8"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunCFG(t *testing.T) {
+	t.Run("Writes JSON graph of snake.ch8", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "snake.json")
+
+		err := runCFG([]string{"-format", "json", "-o", path, "snake.ch8"})
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		var graph chip8.ControlFlowGraph
+		if err := json.Unmarshal(content, &graph); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		if graph.Entry != 0x200 || len(graph.Blocks) == 0 || graph.Functions[0].Name != "main" {
+			t.Errorf("got entry 0x%X with %d blocks, want main at 0x200 with some blocks", graph.Entry, len(graph.Blocks))
+		}
+	})
+
+	t.Run("Analysis starts at the entry of the platform", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "snake.json")
+
+		err := runCFG([]string{"-platform", "eti660", "-format", "json", "-o", path, "snake.ch8"})
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		var graph chip8.ControlFlowGraph
+		if err := json.Unmarshal(content, &graph); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		if graph.Entry != 0x600 {
+			t.Errorf("got entry 0x%X, want 0x600", graph.Entry)
+		}
+	})
+
+	t.Run("Analysis of HIRES programs starts after the jump to 0x260", func(t *testing.T) {
+		// 0x200: jump to 0x260, 0x2C0: jump to itself
+		program := make([]byte, 0xc2)
+		copy(program, []byte{0x12, 0x60})
+		copy(program[0xc0:], []byte{0x12, 0xc0})
+		programPath := filepath.Join(t.TempDir(), "hires.ch8")
+		if err := os.WriteFile(programPath, program, 0644); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		path := filepath.Join(t.TempDir(), "hires.json")
+
+		err := runCFG([]string{"-platform", "hires", "-format", "json", "-o", path, programPath})
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		var graph chip8.ControlFlowGraph
+		if err := json.Unmarshal(content, &graph); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		if graph.Entry != 0x2c0 {
+			t.Errorf("got entry 0x%X, want 0x2C0", graph.Entry)
+		}
+	})
+
+	t.Run("Return error for invalid entry", func(t *testing.T) {
+		err := runCFG([]string{"-entry", "xyz", "snake.ch8"})
+
+		assertErrorExpected(t, err)
+	})
+
+	t.Run("Return error for unknown format", func(t *testing.T) {
+		err := runCFG([]string{"-format", "svg", "snake.ch8"})
+
+		assertErrorExpected(t, err)
+	})
+}
@@ -0,0 +1,129 @@
+package chip8
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SampleRate is the number of audio samples per second written by AudioRecorder.
+const SampleRate = 44100
+
+// BuzzerFrequency is the pitch of the tone played while the sound timer is active.
+const BuzzerFrequency = 440
+
+const samplesPerFrame = SampleRate / 60
+const buzzerAmplitude = 8000
+
+// AudioRecorder collects sound of the buzzer for every frame and encodes it as 16-bit PCM WAV.
+type AudioRecorder struct {
+	// MaxFrames limits the length of the recording, 0 means no limit.
+	MaxFrames int
+	samples   []int16
+	frames    int
+}
+
+// NewAudioRecorder creates recorder which stops after maxFrames frames (60 frames is one second).
+func NewAudioRecorder(maxFrames int) *AudioRecorder {
+	return &AudioRecorder{MaxFrames: maxFrames}
+}
+
+// AddFrame appends 1/60 s of audio, a square wave if sound is true and silence otherwise.
+// It returns false if the recording reached MaxFrames and the frame was not added.
+func (a *AudioRecorder) AddFrame(sound bool) bool {
+	if a.Full() {
+		return false
+	}
+
+	for i := 0; i < samplesPerFrame; i++ {
+		var sample int16
+		if sound {
+			// position of the sample is counted from the beginning of the recording so the wave
+			// continues smoothly when the buzzer is active for several frames
+			n := len(a.samples)
+			if (n*2*BuzzerFrequency/SampleRate)%2 == 0 {
+				sample = buzzerAmplitude
+			} else {
+				sample = -buzzerAmplitude
+			}
+		}
+		a.samples = append(a.samples, sample)
+	}
+	a.frames++
+
+	return true
+}
+
+// Full reports whether the recording reached MaxFrames.
+func (a *AudioRecorder) Full() bool {
+	return a.MaxFrames > 0 && a.frames >= a.MaxFrames
+}
+
+// Frames returns the number of recorded frames.
+func (a *AudioRecorder) Frames() int {
+	return a.frames
+}
+
+// WriteWAV encodes the recording as mono 16-bit PCM WAV and writes it to w.
+func (a *AudioRecorder) WriteWAV(w io.Writer) error {
+	dataSize := uint32(len(a.samples) * 2)
+	header := struct {
+		ChunkID       [4]byte
+		ChunkSize     uint32
+		Format        [4]byte
+		Subchunk1ID   [4]byte
+		Subchunk1Size uint32
+		AudioFormat   uint16
+		NumChannels   uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		Subchunk2ID   [4]byte
+		Subchunk2Size uint32
+	}{
+		ChunkID:       [4]byte{'R', 'I', 'F', 'F'},
+		ChunkSize:     36 + dataSize,
+		Format:        [4]byte{'W', 'A', 'V', 'E'},
+		Subchunk1ID:   [4]byte{'f', 'm', 't', ' '},
+		Subchunk1Size: 16,
+		AudioFormat:   1,
+		NumChannels:   1,
+		SampleRate:    SampleRate,
+		ByteRate:      SampleRate * 2,
+		BlockAlign:    2,
+		BitsPerSample: 16,
+		Subchunk2ID:   [4]byte{'d', 'a', 't', 'a'},
+		Subchunk2Size: dataSize,
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, a.samples)
+}
+
+// SaveWAV writes the recording to a WAV file in dir named after rom and the current time.
+// It returns path of the created file.
+func (a *AudioRecorder) SaveWAV(dir, rom string) (string, error) {
+	path := filepath.Join(dir, AudioFilename(rom, time.Now()))
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err := a.WriteWAV(file); err != nil {
+		return "", err
+	}
+
+	return path, file.Close()
+}
+
+// AudioFilename returns name of the audio recording of rom started at time t, e.g. snake_20240131-154502.wav.
+func AudioFilename(rom string, t time.Time) string {
+	return captureFilename(rom, t, ".wav")
+}
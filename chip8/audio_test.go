@@ -0,0 +1,63 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestAudioRecorder(t *testing.T) {
+	t.Run("Every frame adds 1/60 s of samples", func(t *testing.T) {
+		recorder := NewAudioRecorder(0)
+
+		recorder.AddFrame(false)
+		recorder.AddFrame(true)
+
+		if len(recorder.samples) != 2*735 {
+			t.Fatalf("got %d samples, want %d", len(recorder.samples), 2*735)
+		}
+		if recorder.samples[0] != 0 {
+			t.Errorf("expected silence in the first frame, got %d", recorder.samples[0])
+		}
+		if recorder.samples[735] == 0 {
+			t.Errorf("expected tone in the second frame")
+		}
+	})
+
+	t.Run("Recording stops at MaxFrames", func(t *testing.T) {
+		recorder := NewAudioRecorder(1)
+
+		recorder.AddFrame(true)
+
+		if recorder.AddFrame(true) {
+			t.Errorf("expected frame over the limit to be rejected")
+		}
+	})
+
+	t.Run("WAV header describes mono 16-bit PCM", func(t *testing.T) {
+		recorder := NewAudioRecorder(0)
+		recorder.AddFrame(true)
+
+		var buf bytes.Buffer
+		if err := recorder.WriteWAV(&buf); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		data := buf.Bytes()
+
+		if string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" || string(data[36:40]) != "data" {
+			t.Fatalf("invalid WAV header %q", data[:44])
+		}
+		if got := binary.LittleEndian.Uint32(data[24:28]); got != SampleRate {
+			t.Errorf("got sample rate %d, want %d", got, SampleRate)
+		}
+		if got := binary.LittleEndian.Uint16(data[34:36]); got != 16 {
+			t.Errorf("got %d bits per sample, want 16", got)
+		}
+		if got := binary.LittleEndian.Uint32(data[40:44]); got != 735*2 {
+			t.Errorf("got data size %d, want %d", got, 735*2)
+		}
+		if len(data) != 44+735*2 {
+			t.Errorf("got file size %d, want %d", len(data), 44+735*2)
+		}
+	})
+}
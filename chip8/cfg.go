@@ -0,0 +1,256 @@
+package chip8
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Kinds of edges between basic blocks.
+const (
+	// EdgeNext continues with the next instruction, also after a call or a not taken skip.
+	EdgeNext = "next"
+	// EdgeJump is a jump by 1NNN.
+	EdgeJump = "jump"
+	// EdgeSkip jumps over the next instruction by 3XNN, 4XNN, 5XY0, 9XY0, EX9E or EXA1.
+	EdgeSkip = "skip"
+	// EdgeCall calls a subroutine by 2NNN.
+	EdgeCall = "call"
+)
+
+// BlockEdge is an edge from a basic block to the block starting at To.
+type BlockEdge struct {
+	To   uint16 `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// BlockInstruction is an instruction of a basic block.
+type BlockInstruction struct {
+	Address  uint16 `json:"address"`
+	Opcode   uint16 `json:"opcode"`
+	Mnemonic string `json:"mnemonic"`
+}
+
+// BasicBlock is a sequence of instructions executed one after another, only the first one can be
+// jumped to and only the last one can jump.
+type BasicBlock struct {
+	Start uint16 `json:"start"`
+	// End is the address after the last instruction.
+	End          uint16             `json:"end"`
+	Instructions []BlockInstruction `json:"instructions"`
+	Successors   []BlockEdge        `json:"successors"`
+	// Return is true if the block ends with 00EE.
+	Return bool `json:"return,omitempty"`
+	// ComputedJump is true if the block ends with BNNN, its target is not known statically.
+	ComputedJump bool `json:"computedJump,omitempty"`
+}
+
+// Function is the entry point of the program or a subroutine called by 2NNN.
+type Function struct {
+	Name  string `json:"name"`
+	Entry uint16 `json:"entry"`
+	// Blocks are starts of blocks reachable from the entry without calls.
+	Blocks []uint16 `json:"blocks"`
+	// Calls are entries of functions called by this function.
+	Calls []uint16 `json:"calls"`
+}
+
+// ControlFlowGraph contains basic blocks and the call graph of a program found by static analysis.
+// Code is followed from the entry by jumps, calls, returns and skips. Targets of BNNN computed
+// jumps depend on V0 so code reached only by them is missing and the jumps are listed instead.
+type ControlFlowGraph struct {
+	Entry         uint16       `json:"entry"`
+	Blocks        []BasicBlock `json:"blocks"`
+	Functions     []Function   `json:"functions"`
+	ComputedJumps []uint16     `json:"computedJumps"`
+}
+
+// controlFlow returns addresses where execution continues after the instruction at pc.
+// end is true if the instruction ends a basic block.
+func controlFlow(pc uint16, instruction Instruction) (edges []BlockEdge, end bool) {
+	switch instruction.Op {
+	case OpJump:
+		return []BlockEdge{{To: instruction.NNN, Kind: EdgeJump}}, true
+	case OpCall:
+		return []BlockEdge{{To: instruction.NNN, Kind: EdgeCall}, {To: pc + 2, Kind: EdgeNext}}, true
+	case OpReturn, OpJumpOffset:
+		return nil, true
+	case OpSkipEqual, OpSkipNotEqual, OpSkipEqualRegisters, OpSkipNotEqualRegisters, OpSkipKeyPressed, OpSkipKeyNotPressed:
+		return []BlockEdge{{To: pc + 2, Kind: EdgeNext}, {To: pc + 4, Kind: EdgeSkip}}, true
+	}
+	return []BlockEdge{{To: pc + 2, Kind: EdgeNext}}, false
+}
+
+// BuildControlFlowGraph analyzes the program of platform in memory starting at entry, usually 0x200.
+func BuildControlFlowGraph(platform Platform, memory []byte, entry uint16) *ControlFlowGraph {
+	valid := func(address uint16) bool { return int(address)+1 < len(memory) }
+	decode := func(address uint16) Instruction {
+		return DecodeFor(platform, uint16(memory[address])<<8|uint16(memory[address+1]))
+	}
+
+	// find all reachable instructions and addresses starting basic blocks
+	leaders := map[uint16]bool{entry: true}
+	visited := map[uint16]bool{}
+	functions := map[uint16]bool{entry: true}
+	work := []uint16{entry}
+	for len(work) > 0 {
+		pc := work[len(work)-1]
+		work = work[:len(work)-1]
+		if visited[pc] || !valid(pc) {
+			continue
+		}
+		visited[pc] = true
+
+		edges, end := controlFlow(pc, decode(pc))
+		for _, edge := range edges {
+			if end {
+				leaders[edge.To] = true
+			}
+			if edge.Kind == EdgeCall {
+				functions[edge.To] = true
+			}
+			work = append(work, edge.To)
+		}
+	}
+
+	graph := &ControlFlowGraph{Entry: entry, Blocks: []BasicBlock{}, Functions: []Function{}, ComputedJumps: []uint16{}}
+	for _, start := range sortedAddresses(leaders) {
+		if !valid(start) {
+			continue
+		}
+		block := BasicBlock{Start: start}
+		for pc := start; ; {
+			instruction := decode(pc)
+			block.Instructions = append(block.Instructions, BlockInstruction{
+				Address:  pc,
+				Opcode:   instruction.Opcode,
+				Mnemonic: DisassembleInstruction(instruction),
+			})
+			edges, end := controlFlow(pc, instruction)
+			block.End = pc + 2
+
+			if end || leaders[pc+2] || !valid(pc+2) {
+				for _, edge := range edges {
+					if valid(edge.To) {
+						block.Successors = append(block.Successors, edge)
+					}
+				}
+				switch instruction.Op {
+				case OpReturn:
+					block.Return = true
+				case OpJumpOffset:
+					block.ComputedJump = true
+					graph.ComputedJumps = append(graph.ComputedJumps, pc)
+				}
+				break
+			}
+			pc += 2
+		}
+		graph.Blocks = append(graph.Blocks, block)
+	}
+
+	for _, entry := range sortedAddresses(functions) {
+		if block, ok := graph.Block(entry); ok {
+			graph.Functions = append(graph.Functions, graph.function(block))
+		}
+	}
+	return graph
+}
+
+// function collects blocks reachable from entry without calls.
+func (g *ControlFlowGraph) function(entry *BasicBlock) Function {
+	name := fmt.Sprintf("sub_%03X", entry.Start)
+	if entry.Start == g.Entry {
+		name = "main"
+	}
+
+	blocks := map[uint16]bool{}
+	calls := map[uint16]bool{}
+	work := []*BasicBlock{entry}
+	for len(work) > 0 {
+		block := work[len(work)-1]
+		work = work[:len(work)-1]
+		if blocks[block.Start] {
+			continue
+		}
+		blocks[block.Start] = true
+
+		for _, edge := range block.Successors {
+			if edge.Kind == EdgeCall {
+				calls[edge.To] = true
+			} else if next, ok := g.Block(edge.To); ok {
+				work = append(work, next)
+			}
+		}
+	}
+	return Function{Name: name, Entry: entry.Start, Blocks: sortedAddresses(blocks), Calls: sortedAddresses(calls)}
+}
+
+// Block returns the basic block starting at address.
+func (g *ControlFlowGraph) Block(address uint16) (*BasicBlock, bool) {
+	i := sort.Search(len(g.Blocks), func(i int) bool { return g.Blocks[i].Start >= address })
+	if i < len(g.Blocks) && g.Blocks[i].Start == address {
+		return &g.Blocks[i], true
+	}
+	return nil, false
+}
+
+func sortedAddresses(set map[uint16]bool) []uint16 {
+	addresses := make([]uint16, 0, len(set))
+	for address := range set {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i] < addresses[j] })
+	return addresses
+}
+
+// WriteJSON writes the graph as indented JSON.
+func (g *ControlFlowGraph) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(g)
+}
+
+// WriteDOT writes basic blocks as a Graphviz graph, e.g. for "dot -Tsvg". Calls are dashed edges
+// and blocks ending with a computed jump are red.
+func (g *ControlFlowGraph) WriteDOT(w io.Writer) error {
+	fmt.Fprintln(w, "digraph cfg {")
+	fmt.Fprintln(w, "  node [shape=box fontname=monospace];")
+	for _, block := range g.Blocks {
+		var label strings.Builder
+		for _, instruction := range block.Instructions {
+			fmt.Fprintf(&label, "0x%03X  %s\\l", instruction.Address, instruction.Mnemonic)
+		}
+		attributes := ""
+		if block.ComputedJump {
+			attributes = " color=red"
+		}
+		fmt.Fprintf(w, "  \"0x%03X\" [label=\"%s\"%s];\n", block.Start, label.String(), attributes)
+
+		for _, edge := range block.Successors {
+			style := ""
+			if edge.Kind == EdgeCall {
+				style = " style=dashed"
+			}
+			fmt.Fprintf(w, "  \"0x%03X\" -> \"0x%03X\" [label=%s%s];\n", block.Start, edge.To, edge.Kind, style)
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// WriteCallGraphDOT writes functions and calls between them as a Graphviz graph.
+func (g *ControlFlowGraph) WriteCallGraphDOT(w io.Writer) error {
+	fmt.Fprintln(w, "digraph calls {")
+	fmt.Fprintln(w, "  node [shape=box fontname=monospace];")
+	for _, function := range g.Functions {
+		fmt.Fprintf(w, "  \"0x%03X\" [label=\"%s\"];\n", function.Entry, function.Name)
+		for _, call := range function.Calls {
+			fmt.Fprintf(w, "  \"0x%03X\" -> \"0x%03X\";\n", function.Entry, call)
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
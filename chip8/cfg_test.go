@@ -0,0 +1,102 @@
+package chip8
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildControlFlowGraph(t *testing.T) {
+	// 0x200: call 0x20a, 0x202: skip if V0 == 1, 0x204: jump to 0x200, 0x206: jump to V0 + 0x300
+	// 0x208: data, 0x20a: V0 += 1, 0x20c: return
+	program := []byte{0x22, 0x0a, 0x30, 0x01, 0x12, 0x00, 0xb3, 0x00, 0xff, 0xff, 0x70, 0x01, 0x00, 0xee}
+	memory := make([]byte, 0x1000)
+	copy(memory[0x200:], program)
+
+	graph := BuildControlFlowGraph(PlatformChip8, memory, 0x200)
+
+	t.Run("Blocks end with jumps, calls, returns and skips", func(t *testing.T) {
+		var starts []uint16
+		for _, block := range graph.Blocks {
+			starts = append(starts, block.Start)
+		}
+		want := []uint16{0x200, 0x202, 0x204, 0x206, 0x20a}
+		if !reflect.DeepEqual(starts, want) {
+			t.Errorf("got blocks at %X, want %X", starts, want)
+		}
+
+		block, _ := graph.Block(0x202)
+		wantEdges := []BlockEdge{{To: 0x204, Kind: EdgeNext}, {To: 0x206, Kind: EdgeSkip}}
+		if !reflect.DeepEqual(block.Successors, wantEdges) {
+			t.Errorf("got successors %+v, want %+v", block.Successors, wantEdges)
+		}
+
+		block, _ = graph.Block(0x20a)
+		if !block.Return || block.End != 0x20e || len(block.Instructions) != 2 {
+			t.Errorf("got block %+v, want two instructions ending with return", block)
+		}
+	})
+
+	t.Run("Computed jumps are flagged", func(t *testing.T) {
+		block, _ := graph.Block(0x206)
+		if !block.ComputedJump || len(block.Successors) != 0 {
+			t.Errorf("got block %+v, want computed jump without successors", block)
+		}
+		if !reflect.DeepEqual(graph.ComputedJumps, []uint16{0x206}) {
+			t.Errorf("got computed jumps %X, want [206]", graph.ComputedJumps)
+		}
+	})
+
+	t.Run("Call graph contains the entry and called functions", func(t *testing.T) {
+		want := []Function{
+			{Name: "main", Entry: 0x200, Blocks: []uint16{0x200, 0x202, 0x204, 0x206}, Calls: []uint16{0x20a}},
+			{Name: "sub_20A", Entry: 0x20a, Blocks: []uint16{0x20a}, Calls: []uint16{}},
+		}
+		if !reflect.DeepEqual(graph.Functions, want) {
+			t.Errorf("got %+v, want %+v", graph.Functions, want)
+		}
+	})
+
+	t.Run("Graphs are written in DOT", func(t *testing.T) {
+		var buffer bytes.Buffer
+		if err := graph.WriteDOT(&buffer); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		for _, want := range []string{`"0x200" -> "0x20A" [label=call style=dashed];`, `"0x206" [label="0x206  JP V0, 0x300\l" color=red];`} {
+			if !strings.Contains(buffer.String(), want) {
+				t.Errorf("got %q, want it to contain %q", buffer.String(), want)
+			}
+		}
+
+		buffer.Reset()
+		if err := graph.WriteCallGraphDOT(&buffer); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		if !strings.Contains(buffer.String(), `"0x200" -> "0x20A";`) {
+			t.Errorf("got %q, want call from main to 0x20A", buffer.String())
+		}
+	})
+}
+
+func TestBuildControlFlowGraphOfChip8X(t *testing.T) {
+	// 0x200: colour zones of V1 and V2 with V3, 0x202: colour 4 rows of V1 with V3,
+	// 0x204: cycle the background, 0x206: jump to 0x200
+	program := []byte{0xb1, 0x30, 0xb1, 0x34, 0x02, 0xa0, 0x12, 0x00}
+	memory := make([]byte, 0x1000)
+	copy(memory[0x200:], program)
+
+	graph := BuildControlFlowGraph(PlatformChip8X, memory, 0x200)
+
+	if len(graph.Blocks) != 1 || len(graph.ComputedJumps) != 0 {
+		t.Fatalf("got %d blocks and computed jumps %X, want one block without computed jumps", len(graph.Blocks), graph.ComputedJumps)
+	}
+	var mnemonics []string
+	for _, instruction := range graph.Blocks[0].Instructions {
+		mnemonics = append(mnemonics, instruction.Mnemonic)
+	}
+	want := []string{"COL V1, V3", "COL V1, V3, 4", "BGC", "JP 0x200"}
+	if !reflect.DeepEqual(mnemonics, want) {
+		t.Errorf("got %q, want %q", mnemonics, want)
+	}
+}
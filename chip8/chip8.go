@@ -1,12 +1,9 @@
 package chip8
 
 import (
-	"fmt"
 	"image/color"
 	"math/rand"
 
-	"os"
-
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
@@ -24,315 +21,472 @@ type Chip8 struct {
 	Texture        rl.Texture2D
 	PrimaryColor   color.RGBA
 	SecondaryColor color.RGBA
-}
-
+	Keypad         Keypad
+	// Random is used by CXNN, nil uses the global source of math/rand.
+	Random *rand.Rand
+	// Tracer receives the state after every instruction executed by Step, nil disables tracing.
+	Tracer Tracer
+	// MemoryObserver is notified about reads and writes of Memory made by instructions, it can be nil.
+	MemoryObserver MemoryObserver
+	// Watchpoints checks accesses of memory and registers made by Step, it can be nil.
+	Watchpoints *Watchpoints
+	// Undo records changes made by Step so they can be undone by StepBack, nil disables recording.
+	Undo *UndoLog
+	// Profiler counts instructions executed by Step, nil disables profiling.
+	Profiler *Profiler
+	// CodeTracker reports instructions overwritten by the program, it can be nil.
+	CodeTracker *CodeTracker
+	// Engine executes instructions in RunFrame, nil executes them by Step.
+	Engine Engine
+	// Quirks selects behaviour of instructions which differs between interpreters.
+	Quirks Quirks
+	// Timing is the speed of instructions executed by RunTick.
+	Timing Timing
+	// Platform selects the variant of CHIP-8 decoding instructions, it's changed by SetPlatform.
+	Platform Platform
+	// Colors are the colours of CHIP-8X, nil on other platforms.
+	Colors *ColorMap
+
+	// code is the cache of an engine which has to be invalidated when memory changes
+	code codeCache
+	// cycles left in the current tick, negative if the last instruction took cycles of the next one
+	cycles int
+	// cycleFraction is the remainder of cycles per tick multiplied by TimerFrequency
+	cycleFraction int
+	// waitingForDisplay is set by DXYN with Quirks.DisplayWait until the next tick
+	waitingForDisplay bool
+}
+
+// NewChip8 creates chip of the CHIP-8 platform, SetPlatform selects another one.
 func NewChip8() *Chip8 {
+	layout := PlatformChip8.Layout()
 	chip := &Chip8{
-		Memory:         make([]byte, 4096),
+		Memory:         make([]byte, layout.MemorySize),
 		Registers:      make([]byte, 16),
 		Timers:         make([]byte, 2),
 		Stack:          make([]uint16, 0, 48),
 		Screen:         make([]color.RGBA, 64*32),
 		Width:          64,
 		Height:         32,
-		Pc:             0x200,
+		Pc:             layout.Entry,
 		Sp:             0,
 		I:              0,
 		PrimaryColor:   rl.White,
 		SecondaryColor: rl.Black,
+		Keypad:         RaylibKeypad{},
+		Timing:         TickrateTiming(DefaultTickrate),
 	}
 
 	return chip
 }
 
+// Font contains sprites for hexadecimal digits 0-F, each sprite is 5 bytes long.
+var Font = []byte{
+	0xf0, 0x90, 0x90, 0x90, 0xf0, // 0
+	0x20, 0x60, 0x20, 0x20, 0x70, // 1
+	0xf0, 0x10, 0xf0, 0x80, 0xf0, // 2
+	0xf0, 0x10, 0xf0, 0x10, 0xf0, // 3
+	0x90, 0x90, 0xf0, 0x10, 0x10, // 4
+	0xf0, 0x80, 0xf0, 0x10, 0xf0, // 5
+	0xf0, 0x80, 0xf0, 0x90, 0xf0, // 6
+	0xf0, 0x10, 0x20, 0x40, 0x40, // 7
+	0xf0, 0x90, 0xf0, 0x90, 0xf0, // 8
+	0xf0, 0x90, 0xf0, 0x10, 0xf0, // 9
+	0xf0, 0x90, 0xf0, 0x90, 0x90, // A
+	0xe0, 0x90, 0xe0, 0x90, 0xe0, // B
+	0xf0, 0x80, 0x80, 0x80, 0xf0, // C
+	0xe0, 0x90, 0x90, 0x90, 0xe0, // D
+	0xf0, 0x80, 0xf0, 0x80, 0xf0, // E
+	0xf0, 0x80, 0xf0, 0x80, 0x80, // F
+}
+
+// LoadFont copies the font sprites to the font address of the platform.
+func (c *Chip8) LoadFont() {
+	copy(c.Memory[c.Platform.Layout().FontAddress:], Font)
+	if c.code != nil {
+		c.code.invalidateAll()
+	}
+}
+
+// LoadProgram copies program to Memory starting at the load address of the platform, e.g. 0x200.
+// Programs of HIRES CHIP-8 select the screen size and start address by their first instruction.
+func (c *Chip8) LoadProgram(program []byte) {
+	copy(c.Memory[c.Platform.Layout().LoadAddress:], program)
+	c.enterHires()
+	if c.code != nil {
+		c.code.invalidateAll()
+	}
+}
+
+// Step executes the instruction at pc and moves pc to the next instruction.
+// It returns both bytes of the executed instruction. Addresses wrap around the end of Memory,
+// so pc is always inside of it.
+func (c *Chip8) Step() (firstByte, secondByte byte) {
+	pc := c.memoryAddress(c.Pc)
+	c.Pc = pc
+	firstByte = c.Memory[pc]
+	secondByte = c.Memory[c.memoryAddress(pc+1)]
+	if c.CodeTracker != nil {
+		c.CodeTracker.executing(pc)
+	}
+	if c.Undo != nil {
+		c.Undo.begin(c)
+		defer c.Undo.end()
+	}
+	instruction := c.DecodeBytes(firstByte, secondByte)
+	if c.Undo != nil && c.Colors != nil && instruction.Op.changesColors() {
+		c.Undo.recordColors(c.Colors)
+	}
+	var reads, writes uint16
+	var registers [16]byte
+	if c.Watchpoints != nil {
+		c.Watchpoints.pc = pc
+		reads, writes = registerAccesses(instruction, c.Quirks)
+		copy(registers[:], c.Registers)
+	}
+
+	emulator := Emulator{EmulatorStore: c}
+	emulator.Execute(instruction)
+
+	if c.Watchpoints != nil {
+		// FX0A writes the register only when a key was pressed, otherwise it runs again
+		if instruction.Op == OpWaitKey && c.Pc == pc-2 {
+			writes = 0
+		}
+		c.Watchpoints.registersAccessed(reads, writes, registers[:], c.Registers)
+	}
+
+	if !instruction.Op.jumps() {
+		c.Pc += 2
+	}
+	c.Pc = c.memoryAddress(c.Pc)
+
+	if c.Profiler != nil {
+		c.Profiler.executed(pc, firstByte, secondByte)
+	}
+	if c.Tracer != nil {
+		c.Tracer.Trace(c.traceEntry(pc, firstByte, secondByte))
+	}
+
+	return firstByte, secondByte
+}
+
+// WaitingForDisplay reports whether DXYN stopped execution until the next tick of the timers.
+func (c *Chip8) WaitingForDisplay() bool {
+	return c.waitingForDisplay
+}
+
+// UpdateTimers decreases delay and sound timers by one, it should be called 60 times per second.
+// It's also the vertical blank which ends waiting for the display. It returns true if the sound
+// timer was active.
+func (c *Chip8) UpdateTimers() bool {
+	c.waitingForDisplay = false
+	sound := false
+	for t := range c.Timers {
+		if c.Timers[t] > 0 {
+			// c.Timers[1] is a sound timer
+			if t == 1 {
+				sound = true
+			}
+			c.Timers[t] -= 1
+		}
+	}
+
+	return sound
+}
+
+// RunFrame executes tickrate instructions followed by one update of the timers, the frame ends
+// early when an instruction waits for the display. It returns true if the buzzer should sound
+// during the frame.
+func (c *Chip8) RunFrame(tickrate int) bool {
+	if c.Engine != nil {
+		c.Engine.Run(tickrate)
+		return c.UpdateTimers()
+	}
+	for i := 0; i < tickrate && !c.waitingForDisplay; i++ {
+		c.Step()
+	}
+	return c.UpdateTimers()
+}
+
 type EmulatorStore interface {
 	ClearScreen()
-	LoadRegister(firstByte, secondByte byte)
-	LoadIndexRegister(firstByte, secondByte byte)
-	JumpToInstruction(firstByte, secondByte byte)
-	Draw(firstByte, secondByte byte)
-	AddValueToRegister(firstByte, secondByte byte)
-	SkipNextInstruction(firstByte, secondByte byte)
-	JumpPlusRegister(firstByte, secondByte byte)
-	CallAddress(firstByte, secondByte byte)
-	SkipIfNotEquals(firstByte, secondByte byte)
-	SkipEqualRegisters(firstByte, secondByte byte)
-	SkipNotEqualRegisters(firstByte, secondByte byte)
-	Return(firstByte, secondByte byte)
-	VxGetsVy(firstByte, secondByte byte)
-	LoadRegistersFromMemory(firstByte, secondByte byte)
-	LoadRegistersToMemory(firstByte, secondByte byte)
-	VxOrVy(firstByte, secondByte byte)
-	VxAndVy(firstByte, secondByte byte)
-	VxXorVy(firstByte, secondByte byte)
-	VxAddVy(firstByte, secondByte byte)
-	VxSubVy(firstByte, secondByte byte)
-	VySubVx(firstByte, secondByte byte)
-	VxRightShift(firstByte, secondByte byte)
-	VxLeftShift(firstByte, secondByte byte)
-	StoreBCDRepresentationInMemory(firstByte, secondByte byte)
-	StoreValueOfVxPlusIInI(firstByte, secondByte byte)
-	SetDelayTimer(firstByte byte)
-	SetSoundTimer(firstByte byte)
-	SkipKeyNotPressed(firstByte byte)
-	SkipKeyPressed(firstByte byte)
-	PutTimerInRegister(firstByte byte)
-	WaitForKeyPress(firstByte byte)
-	SetRandomNumber(firstByte, secondByte byte)
-	SetLocationOfSprite(firstByte byte)
+	LoadRegister(instruction Instruction)
+	LoadIndexRegister(instruction Instruction)
+	JumpToInstruction(instruction Instruction)
+	Draw(instruction Instruction)
+	AddValueToRegister(instruction Instruction)
+	SkipNextInstruction(instruction Instruction)
+	JumpPlusRegister(instruction Instruction)
+	CallAddress(instruction Instruction)
+	SkipIfNotEquals(instruction Instruction)
+	SkipEqualRegisters(instruction Instruction)
+	SkipNotEqualRegisters(instruction Instruction)
+	Return(instruction Instruction)
+	VxGetsVy(instruction Instruction)
+	LoadRegistersFromMemory(instruction Instruction)
+	LoadRegistersToMemory(instruction Instruction)
+	VxOrVy(instruction Instruction)
+	VxAndVy(instruction Instruction)
+	VxXorVy(instruction Instruction)
+	VxAddVy(instruction Instruction)
+	VxSubVy(instruction Instruction)
+	VySubVx(instruction Instruction)
+	VxRightShift(instruction Instruction)
+	VxLeftShift(instruction Instruction)
+	StoreBCDRepresentationInMemory(instruction Instruction)
+	StoreValueOfVxPlusIInI(instruction Instruction)
+	SetDelayTimer(instruction Instruction)
+	SetSoundTimer(instruction Instruction)
+	SkipKeyNotPressed(instruction Instruction)
+	SkipKeyPressed(instruction Instruction)
+	PutTimerInRegister(instruction Instruction)
+	WaitForKeyPress(instruction Instruction)
+	SetRandomNumber(instruction Instruction)
+	SetLocationOfSprite(instruction Instruction)
+	CycleBackground()
+	ColorZones(instruction Instruction)
+	ColorRows(instruction Instruction)
 }
 
 type Emulator struct {
 	EmulatorStore
 }
 
-var keymap = map[byte]int32{
-	0x1: rl.KeyOne,
-	0x2: rl.KeyTwo,
-	0x3: rl.KeyThree,
-	0xc: rl.KeyFour,
-	0x4: rl.KeyQ,
-	0x5: rl.KeyW,
-	0x6: rl.KeyE,
-	0xd: rl.KeyR,
-	0x7: rl.KeyA,
-	0x8: rl.KeyS,
-	0x9: rl.KeyD,
-	0xe: rl.KeyF,
-	0xa: rl.KeyZ,
-	0x0: rl.KeyX,
-	0xb: rl.KeyC,
-	0xf: rl.KeyV,
+func (c *Chip8) SetLocationOfSprite(instruction Instruction) {
+	// only the low nibble selects the digit
+	value := c.Registers[instruction.X] & 0xf
+	c.I = c.Platform.Layout().FontAddress + uint16(value)*5
 }
 
-func (c *Chip8) SetLocationOfSprite(firstByte byte) {
-	value := c.Registers[firstByte&0xf]
-	c.I = uint16(value * 5)
+func (c *Chip8) SetRandomNumber(instruction Instruction) {
+	var randNumber int
+	if c.Random != nil {
+		randNumber = c.Random.Intn(256)
+	} else {
+		randNumber = rand.Intn(256)
+	}
+	c.Registers[instruction.X] = byte(randNumber) & instruction.NN
 }
 
-func (c *Chip8) SetRandomNumber(firstByte, secondByte byte) {
-	randNumber := rand.Intn(256)
-	c.Registers[firstByte&0xf] = byte(randNumber) & secondByte
+// SetResolution changes the size of the screen, e.g. to 128x64 or 64x64, and clears it.
+func (c *Chip8) SetResolution(width, height byte) {
+	c.Width, c.Height = width, height
+	c.Screen = make([]color.RGBA, int(width)*int(height))
+	if c.Colors != nil {
+		c.Colors = newColorMap(int(width), int(height))
+	}
+	c.ClearScreen()
 }
 
 // ClearScreen clears the screen by setting all pixels to 0.
 func (c *Chip8) ClearScreen() {
 	for i := range c.Screen {
-		c.Screen[i] = c.SecondaryColor
+		c.setPixel(i, c.SecondaryColor)
 	}
 }
 
 // StoreBCDRepresentationInMemory stores decimal number in Vx in Memory (Memory[i] = hundreds digit, Memory[i+1] = tens digit, Memory[i+2] = ones digit).
-func (c *Chip8) StoreBCDRepresentationInMemory(firstByte, secondByte byte) {
-	registerX := firstByte & 0xf
-	valueRegisterX := c.Registers[registerX]
+func (c *Chip8) StoreBCDRepresentationInMemory(instruction Instruction) {
+	valueRegisterX := c.Registers[instruction.X]
 
-	var i int = 0
-	for i = int(c.I + 2); i >= int(c.I); i-- {
+	for i := 2; i >= 0; i-- {
 		// get last digit from valueRegisterX
-		c.Memory[i] = valueRegisterX % 10
+		c.writeMemory(c.I+uint16(i), valueRegisterX%10)
 		// divide to get the next digit from the right
 		valueRegisterX /= 10
 	}
 }
 
 // StoreValueOfVxPlusIInI adds values of index register and Vx and stores the result in index register I.
-func (c *Chip8) StoreValueOfVxPlusIInI(firstByte, secondByte byte) {
-	c.I = c.I + uint16(c.Registers[firstByte&0xf])
+func (c *Chip8) StoreValueOfVxPlusIInI(instruction Instruction) {
+	c.I = c.I + uint16(c.Registers[instruction.X])
 }
 
 // LoadRegistersFromMemory loads x registers from memory starting at index register (I).
-func (c *Chip8) LoadRegistersFromMemory(firstByte, secondByte byte) {
-	copy(c.Registers[0x0:firstByte&0xf+1], c.Memory[c.I:c.I+uint16(firstByte&0xf+1)])
-	c.I += uint16(c.Registers[firstByte&0xf]) + 1
+// I is moved after the loaded bytes unless Quirks.KeepIndex is set.
+func (c *Chip8) LoadRegistersFromMemory(instruction Instruction) {
+	for r := uint16(0); r <= uint16(instruction.X); r++ {
+		c.Registers[r] = c.readMemory(c.I + r)
+	}
+	if !c.Quirks.KeepIndex {
+		c.I += uint16(instruction.X) + 1
+	}
 }
 
 // LoadRegistersToMemory loads x registers to memory starting at index register I.
-func (c *Chip8) LoadRegistersToMemory(firstByte, secondByte byte) {
-	copy(c.Memory[c.I:c.I+uint16(firstByte&0xf+1)], c.Registers[0x0:firstByte&0xf+1])
-	c.I += uint16(c.Registers[firstByte&0xf]) + 1
+// I is moved after the stored bytes unless Quirks.KeepIndex is set.
+func (c *Chip8) LoadRegistersToMemory(instruction Instruction) {
+	for r := uint16(0); r <= uint16(instruction.X); r++ {
+		c.writeMemory(c.I+r, c.Registers[r])
+	}
+	if !c.Quirks.KeepIndex {
+		c.I += uint16(instruction.X) + 1
+	}
 }
 
-// Return pops address from the stack and puts it in the pc.
-func (c *Chip8) Return(firstByte, secondByte byte) {
+// Return pops address from the stack and puts it in the pc, it does nothing if the stack is empty.
+func (c *Chip8) Return(instruction Instruction) {
+	if len(c.Stack) == 0 {
+		return
+	}
 	address := c.Stack[len(c.Stack)-1]
 	c.Stack = c.Stack[:len(c.Stack)-1]
 	c.Pc = address
 }
 
-func (c *Chip8) CallAddress(firstByte, secondByte byte) {
+func (c *Chip8) CallAddress(instruction Instruction) {
 	c.Stack = append(c.Stack, c.Pc)
-	c.Pc = get12BitValue(firstByte, secondByte)
+	c.Pc = instruction.NNN
 }
 
 // SkipEqualRegisters compares values of two registers and increases pc if they're equal.
-func (c *Chip8) SkipEqualRegisters(firstByte, secondByte byte) {
-	firstRegister := firstByte & 0xf
-	secondRegister := secondByte >> 4
-
-	if c.Registers[firstRegister] == c.Registers[secondRegister] {
+func (c *Chip8) SkipEqualRegisters(instruction Instruction) {
+	if c.Registers[instruction.X] == c.Registers[instruction.Y] {
 		c.Pc += 2
 	}
 }
 
-// SkipIfNotEquals increases the program counter by 2 if value in given register is different than secondByte.
-func (c *Chip8) SkipIfNotEquals(firstByte, secondByte byte) {
-	register := firstByte & 0xf
-	registerValue := c.Registers[register]
-	value := secondByte
-
-	if registerValue != value {
+// SkipIfNotEquals increases the program counter by 2 if value in given register is different than NN.
+func (c *Chip8) SkipIfNotEquals(instruction Instruction) {
+	if c.Registers[instruction.X] != instruction.NN {
 		c.Pc += 2
 	}
 }
 
 // SkipNotEqualRegisters increases pc if values of registers are different.
-func (c *Chip8) SkipNotEqualRegisters(firstByte, secondByte byte) {
-	firstRegister := firstByte & 0xf
-	firstValue := c.Registers[firstRegister]
-	secondRegister := secondByte >> 4
-	secondValue := c.Registers[secondRegister]
-
-	if firstValue != secondValue {
+func (c *Chip8) SkipNotEqualRegisters(instruction Instruction) {
+	if c.Registers[instruction.X] != c.Registers[instruction.Y] {
 		c.Pc += 2
 	}
-
 }
 
-// LoadRegister loads secondByte into register.
-func (c *Chip8) LoadRegister(firstByte, secondByte byte) {
-	// register name is represented by the last 4 bits of the first byte.
-	register := firstByte & 0x0f
-	c.Registers[register] = secondByte
-}
-
-func get12BitValue(firstByte, secondByte byte) uint16 {
-	// value is the last 4 bits of the first byte and the last byte.
-	// shift first byte to fit the second byte and connect them together.
-	return uint16(firstByte&0xf)<<8 | uint16(secondByte)
+// LoadRegister loads NN into register.
+func (c *Chip8) LoadRegister(instruction Instruction) {
+	c.Registers[instruction.X] = instruction.NN
 }
 
 // LoadIndexRegister loads 12 bits into index register.
-func (c *Chip8) LoadIndexRegister(firstByte, secondByte byte) {
-	c.I = get12BitValue(firstByte, secondByte)
+func (c *Chip8) LoadIndexRegister(instruction Instruction) {
+	c.I = instruction.NNN
 }
 
 // VxGetsVy stores value of y register in x register.
-func (c *Chip8) VxGetsVy(firstByte, secondByte byte) {
-	registerX := firstByte & 0xf
-	registerY := secondByte >> 4
-	registerYValue := c.Registers[registerY]
-	c.Registers[registerX] = registerYValue
+func (c *Chip8) VxGetsVy(instruction Instruction) {
+	c.Registers[instruction.X] = c.Registers[instruction.Y]
 }
 
 // JumpToInstruction sets the program counter to the new value.
-func (c *Chip8) JumpToInstruction(firstByte, secondByte byte) {
-	c.Pc = get12BitValue(firstByte, secondByte)
+func (c *Chip8) JumpToInstruction(instruction Instruction) {
+	c.Pc = instruction.NNN
 }
 
-// SkipNextInstruction increases the program counter if value of the register is equal to secondByte.
-func (c *Chip8) SkipNextInstruction(firstByte, secondByte byte) {
-	registerValue := c.Registers[firstByte&0xf]
-	value := secondByte
-
-	if registerValue == value {
+// SkipNextInstruction increases the program counter if value of the register is equal to NN.
+func (c *Chip8) SkipNextInstruction(instruction Instruction) {
+	if c.Registers[instruction.X] == instruction.NN {
 		c.Pc += 2
 	}
 }
 
-func (c *Chip8) JumpPlusRegister(firstByte, secondByte byte) {
-	// works for chip8 quirks
-	register := c.Registers[0xf]
-	c.Pc = get12BitValue(firstByte, secondByte) + uint16(register)
+// JumpPlusRegister jumps to NNN plus V0, or to XNN plus VX with Quirks.JumpVx.
+func (c *Chip8) JumpPlusRegister(instruction Instruction) {
+	register := c.Registers[0x0]
+	if c.Quirks.JumpVx {
+		register = c.Registers[instruction.X]
+	}
+	c.Pc = instruction.NNN + uint16(register)
 }
 
-func (c *Chip8) Draw(firstByte, secondByte byte) {
-	bytesToRead := secondByte & 0xf
-	x := c.Registers[firstByte&0xf] % c.Width
-	y := c.Registers[secondByte>>4] % c.Height
+// Draw xors N rows of the sprite at I onto the screen at VX, VY, the start coordinates wrap
+// around the screen. Pixels past its edges are clipped, or wrapped with Quirks.WrapSprites.
+// VF is set to 1 if a pixel was turned off.
+func (c *Chip8) Draw(instruction Instruction) {
+	width, height := int(c.Width), int(c.Height)
+	startX := int(c.Registers[instruction.X]) % width
+	startY := int(c.Registers[instruction.Y]) % height
 	c.Registers[0xf] = 0
 
-	for i := c.I; i < (uint16(bytesToRead) + c.I); i++ {
-		var currentByte byte = c.Memory[i]
-		var color color.RGBA
-
-		// check each bit in the current byte
-		for j := 0; j < 8; j++ {
-			pixel := currentByte >> 7
-			// shift byte to access next bit from left
-			currentByte = currentByte << 1
-
-			// position in 1D array is based on x, y and width
-			var position int = int(x) + (int(y) * int(c.Width))
-
-			// pixels are xored (^) onto the screen but xor is not defined for color.RGBA
-			if (pixel == 1 && c.Screen[position] == c.SecondaryColor) || (pixel == 0 && c.Screen[position] == c.PrimaryColor) {
-				color = c.PrimaryColor
-			} else {
-				color = c.SecondaryColor
+	for i := 0; i < int(instruction.N); i++ {
+		y := startY + i
+		if y >= height {
+			if !c.Quirks.WrapSprites {
+				break
 			}
-
-			// set collision flag
-			if pixel == 1 && c.Screen[position] == c.PrimaryColor {
-				c.Registers[0xf] = 1
+			y %= height
+		}
+		currentByte := c.readMemory(c.I + uint16(i))
+
+		// check each bit in the current byte, starting with the leftmost one
+		for j := 0; j < 8; j, currentByte = j+1, currentByte<<1 {
+			x := startX + j
+			if x >= width {
+				if !c.Quirks.WrapSprites {
+					break
+				}
+				x %= width
+			}
+			if currentByte&0x80 == 0 {
+				continue
 			}
 
-			c.Screen[position] = color
+			// position in 1D array is based on x, y and width
+			position := x + y*width
 
-			// increase x to draw in the next x coordinate
-			x += 1
-			if x > 63 {
-				break
+			// pixels are xored onto the screen but xor is not defined for color.RGBA
+			if c.Screen[position] == c.PrimaryColor {
+				c.Registers[0xf] = 1
+				c.setPixel(position, c.SecondaryColor)
+			} else {
+				c.setPixel(position, c.PrimaryColor)
 			}
 		}
-		// reset x
-		x = c.Registers[firstByte&0xf] % c.Width
-		// increase y to move down
-		y += 1
-		if y > 31 {
-			break
-		}
+	}
 
+	if c.Quirks.DisplayWait {
+		c.waitingForDisplay = true
 	}
 }
 
-func (c *Chip8) AddValueToRegister(firstByte, secondByte byte) {
-	register := firstByte & 0xf
-	value := secondByte
-
-	c.Registers[register] += value
+func (c *Chip8) AddValueToRegister(instruction Instruction) {
+	c.Registers[instruction.X] += instruction.NN
 }
 
-func (c *Chip8) VxOrVy(firstByte, secondByte byte) {
-	registerX := firstByte & 0xf
+func (c *Chip8) VxOrVy(instruction Instruction) {
+	registerX := instruction.X
 
-	value := c.Registers[registerX] | c.Registers[secondByte>>4]
+	value := c.Registers[registerX] | c.Registers[instruction.Y]
 	c.Registers[registerX] = value
-	c.Registers[0xf] = 0
+	if !c.Quirks.KeepVF {
+		c.Registers[0xf] = 0
+	}
 }
 
 // VxAndVy calculates result of Vx&Vy and stores the result in Vx.
-func (c *Chip8) VxAndVy(firstByte, secondByte byte) {
-	registerX := firstByte & 0xf
+func (c *Chip8) VxAndVy(instruction Instruction) {
+	registerX := instruction.X
 
-	value := c.Registers[registerX] & c.Registers[secondByte>>4]
+	value := c.Registers[registerX] & c.Registers[instruction.Y]
 	c.Registers[registerX] = value
-	c.Registers[0xf] = 0
+	if !c.Quirks.KeepVF {
+		c.Registers[0xf] = 0
+	}
 }
 
 // VxXorVy calculates result of Vx^Vy and stores the result in Vx.
-func (c *Chip8) VxXorVy(firstByte, secondByte byte) {
-	registerX := firstByte & 0xf
+func (c *Chip8) VxXorVy(instruction Instruction) {
+	registerX := instruction.X
 
-	value := c.Registers[registerX] ^ c.Registers[secondByte>>4]
+	value := c.Registers[registerX] ^ c.Registers[instruction.Y]
 	c.Registers[registerX] = value
-	c.Registers[0xf] = 0
+	if !c.Quirks.KeepVF {
+		c.Registers[0xf] = 0
+	}
 }
 
 // VxAddVy adds value of Vx and Vy, stores the result in Vx and sets Vf to 1 on overflow.
-func (c *Chip8) VxAddVy(firstByte, secondByte byte) {
-	registerX := firstByte & 0xf
-	registerY := secondByte >> 4
+func (c *Chip8) VxAddVy(instruction Instruction) {
+	registerX := instruction.X
+	registerY := instruction.Y
 
 	var xOverflow int = int(c.Registers[registerX])
 	var yOverflow int = int(c.Registers[registerY])
@@ -347,9 +501,9 @@ func (c *Chip8) VxAddVy(firstByte, secondByte byte) {
 }
 
 // VxSubVy sets Vf to 1 if Vx > Vy, stores result of Vx - Vy in Vx.
-func (c *Chip8) VxSubVy(firstByte, secondByte byte) {
-	registerX := firstByte & 0xf
-	registerY := secondByte >> 4
+func (c *Chip8) VxSubVy(instruction Instruction) {
+	registerX := instruction.X
+	registerY := instruction.Y
 
 	if c.Registers[registerX] >= c.Registers[registerY] {
 		c.Registers[registerX] = c.Registers[registerX] - c.Registers[registerY]
@@ -361,25 +515,26 @@ func (c *Chip8) VxSubVy(firstByte, secondByte byte) {
 
 }
 
-// VySubVx sets Vf to 1 if Vy > Vx and sets Vx to Vy - Vx.
-func (c *Chip8) VySubVx(firstByte, secondByte byte) {
-	registerX := firstByte & 0xf
-	registerY := secondByte >> 4
+// VySubVx sets Vf to 1 if Vy >= Vx and sets Vx to Vy - Vx.
+func (c *Chip8) VySubVx(instruction Instruction) {
+	registerX := instruction.X
+	registerY := instruction.Y
 
+	noBorrow := c.Registers[registerY] >= c.Registers[registerX]
 	c.Registers[registerX] = c.Registers[registerY] - c.Registers[registerX]
-
-	if c.Registers[registerY] > c.Registers[registerX] {
+	if noBorrow {
 		c.Registers[0xf] = 1
 	} else {
 		c.Registers[0xf] = 0
 	}
 }
 
-// VxRightShift sets Vf to 1 if the least significant bit of Vx is 1 and divides Vx by 2.
-func (c *Chip8) VxRightShift(firstByte, secondByte byte) {
-	registerX := firstByte & 0xf
+// VxRightShift stores Vy divided by 2 in Vx and sets Vf to its least significant bit.
+// Quirks.ShiftVx shifts Vx instead of Vy.
+func (c *Chip8) VxRightShift(instruction Instruction) {
+	registerX := instruction.X
 
-	c.Registers[registerX] = c.Registers[secondByte>>4]
+	c.Registers[registerX] = c.Registers[c.shiftedRegister(instruction)]
 	// find least significant bit and check if it's 1
 	if c.Registers[registerX]&0x1 == 1 {
 		// right shift by 1 to divide by 2
@@ -391,11 +546,12 @@ func (c *Chip8) VxRightShift(firstByte, secondByte byte) {
 	}
 }
 
-// VxLeftShift sets Vf to 1 if the most significant bit of Vx is 1 and multiplies Vx by 2.
-func (c *Chip8) VxLeftShift(firstByte, secondByte byte) {
-	registerX := firstByte & 0xf
+// VxLeftShift stores Vy multiplied by 2 in Vx and sets Vf to its most significant bit.
+// Quirks.ShiftVx shifts Vx instead of Vy.
+func (c *Chip8) VxLeftShift(instruction Instruction) {
+	registerX := instruction.X
 
-	c.Registers[registerX] = c.Registers[secondByte>>4]
+	c.Registers[registerX] = c.Registers[c.shiftedRegister(instruction)]
 	// find most significant bit and check if it's 1
 	if c.Registers[registerX]>>7 == 1 {
 		// left shift by 1 to multiply by 2
@@ -409,135 +565,131 @@ func (c *Chip8) VxLeftShift(firstByte, secondByte byte) {
 
 }
 
+// shiftedRegister returns the register shifted by 8XY6 and 8XYE.
+func (c *Chip8) shiftedRegister(instruction Instruction) byte {
+	if c.Quirks.ShiftVx {
+		return instruction.X
+	}
+	return instruction.Y
+}
+
 // SetDelayTimer sets delay timer to value in Vx.
-func (c *Chip8) SetDelayTimer(firstByte byte) {
-	c.Timers[0] = c.Registers[firstByte&0xf]
+func (c *Chip8) SetDelayTimer(instruction Instruction) {
+	c.Timers[0] = c.Registers[instruction.X]
 }
 
 // SetSoundTimer sets sound timer to the value of Vx.
-func (c *Chip8) SetSoundTimer(firstByte byte) {
-    c.Timers[1] = c.Registers[firstByte&0xf]
+func (c *Chip8) SetSoundTimer(instruction Instruction) {
+	c.Timers[1] = c.Registers[instruction.X]
 }
 
-func (c *Chip8) PutTimerInRegister(firstByte byte) {
-	c.Registers[firstByte&0xf] = c.Timers[0]
+func (c *Chip8) PutTimerInRegister(instruction Instruction) {
+	c.Registers[instruction.X] = c.Timers[0]
 }
 
-func (c *Chip8) SkipKeyNotPressed(firstByte byte) {
-	targetKey := c.Registers[firstByte&0xf]
-	if rl.IsKeyUp(keymap[targetKey]) {
+func (c *Chip8) SkipKeyNotPressed(instruction Instruction) {
+	targetKey := c.Registers[instruction.X]
+	if !c.keypad().IsKeyDown(targetKey) {
 		c.Pc += 2
 	}
 }
 
-func (c *Chip8) SkipKeyPressed(firstByte byte) {
-	targetKey := c.Registers[firstByte&0xf]
-	if rl.IsKeyDown(keymap[targetKey]) {
+func (c *Chip8) SkipKeyPressed(instruction Instruction) {
+	targetKey := c.Registers[instruction.X]
+	if c.keypad().IsKeyDown(targetKey) {
 		c.Pc += 2
 	}
 }
 
-func (c *Chip8) WaitForKeyPress(firstByte byte) {
-	if rl.GetKeyPressed() != 0 {
-		value := rl.GetKeyPressed()
-		c.Registers[firstByte&0xf] = getKeyFromKeymap(value)
+func (c *Chip8) WaitForKeyPress(instruction Instruction) {
+	if key, ok := c.keypad().KeyPressed(); ok {
+		c.Registers[instruction.X] = key
 	} else {
 		c.Pc -= 2
 	}
 }
 
-func getKeyFromKeymap(value int32) byte {
-	for k, v := range keymap {
-		if v == value {
-			fmt.Fprintln(os.Stdout, []any{"v: %d", v}...)
-			return k
-		}
-	}
-	return 0
-}
-
+// Emulate decodes and executes the instruction stored in firstByte and secondByte.
 func (e *Emulator) Emulate(firstByte, secondByte byte) {
-	switch firstByte >> 4 {
-	case 0x0:
-		switch secondByte {
-		case 0xe0:
-			e.ClearScreen()
-		case 0xee:
-			e.Return(firstByte, secondByte)
-		}
-
-	case 0x1:
-		e.JumpToInstruction(firstByte, secondByte)
-	case 0x2:
-		e.CallAddress(firstByte, secondByte)
-	case 0x3:
-		e.SkipNextInstruction(firstByte, secondByte)
-	case 0x4:
-		e.SkipIfNotEquals(firstByte, secondByte)
-	case 0x5:
-		e.SkipEqualRegisters(firstByte, secondByte)
-	case 0x6:
-		e.LoadRegister(firstByte, secondByte)
-	case 0x7:
-		e.AddValueToRegister(firstByte, secondByte)
-	case 0x8:
-		switch secondByte & 0xf {
-		case 0x0:
-			e.VxGetsVy(firstByte, secondByte)
-		case 0x1:
-			e.VxOrVy(firstByte, secondByte)
-		case 0x2:
-			e.VxAndVy(firstByte, secondByte)
-		case 0x3:
-			e.VxXorVy(firstByte, secondByte)
-		case 0x4:
-			e.VxAddVy(firstByte, secondByte)
-		case 0x5:
-			e.VxSubVy(firstByte, secondByte)
-		case 0x6:
-			e.VxRightShift(firstByte, secondByte)
-		case 0x7:
-			e.VySubVx(firstByte, secondByte)
-		case 0xe:
-			e.VxLeftShift(firstByte, secondByte)
-		}
-	case 0x9:
-		e.SkipNotEqualRegisters(firstByte, secondByte)
-	case 0xa:
-		e.LoadIndexRegister(firstByte, secondByte)
-	case 0xb:
-		e.JumpPlusRegister(firstByte, secondByte)
-	case 0xc:
-		e.SetRandomNumber(firstByte, secondByte)
-	case 0xd:
-		e.Draw(firstByte, secondByte)
-	case 0xe:
-		switch secondByte {
-		case 0x9e:
-			e.SkipKeyPressed(firstByte)
-		case 0xa1:
-			e.SkipKeyNotPressed(firstByte)
-		}
-	case 0xf:
-		switch secondByte {
-		case 0x07:
-			e.PutTimerInRegister(firstByte)
-		case 0x0a:
-			e.WaitForKeyPress(firstByte)
-		case 0x15:
-			e.SetDelayTimer(firstByte)
-		case 0x18:
-			e.SetSoundTimer(firstByte)
-		case 0x1e:
-			e.StoreValueOfVxPlusIInI(firstByte, secondByte)
-		case 0x29:
-			e.SetLocationOfSprite(firstByte)
-		case 0x33:
-			e.StoreBCDRepresentationInMemory(firstByte, secondByte)
-		case 0x55:
-			e.LoadRegistersToMemory(firstByte, secondByte)
-		case 0x65:
-			e.LoadRegistersFromMemory(firstByte, secondByte)
-		}
+	e.Execute(DecodeBytes(firstByte, secondByte))
+}
+
+// Execute executes the decoded instruction, invalid instructions are ignored.
+func (e *Emulator) Execute(instruction Instruction) {
+	switch instruction.Op {
+	case OpClearScreen:
+		e.ClearScreen()
+	case OpReturn:
+		e.Return(instruction)
+	case OpJump:
+		e.JumpToInstruction(instruction)
+	case OpCall:
+		e.CallAddress(instruction)
+	case OpSkipEqual:
+		e.SkipNextInstruction(instruction)
+	case OpSkipNotEqual:
+		e.SkipIfNotEquals(instruction)
+	case OpSkipEqualRegisters:
+		e.SkipEqualRegisters(instruction)
+	case OpLoad:
+		e.LoadRegister(instruction)
+	case OpAdd:
+		e.AddValueToRegister(instruction)
+	case OpMove:
+		e.VxGetsVy(instruction)
+	case OpOr:
+		e.VxOrVy(instruction)
+	case OpAnd:
+		e.VxAndVy(instruction)
+	case OpXor:
+		e.VxXorVy(instruction)
+	case OpAddRegisters:
+		e.VxAddVy(instruction)
+	case OpSub:
+		e.VxSubVy(instruction)
+	case OpShiftRight:
+		e.VxRightShift(instruction)
+	case OpSubReverse:
+		e.VySubVx(instruction)
+	case OpShiftLeft:
+		e.VxLeftShift(instruction)
+	case OpSkipNotEqualRegisters:
+		e.SkipNotEqualRegisters(instruction)
+	case OpLoadIndex:
+		e.LoadIndexRegister(instruction)
+	case OpJumpOffset:
+		e.JumpPlusRegister(instruction)
+	case OpRandom:
+		e.SetRandomNumber(instruction)
+	case OpDraw:
+		e.Draw(instruction)
+	case OpSkipKeyPressed:
+		e.SkipKeyPressed(instruction)
+	case OpSkipKeyNotPressed:
+		e.SkipKeyNotPressed(instruction)
+	case OpLoadDelayTimer:
+		e.PutTimerInRegister(instruction)
+	case OpWaitKey:
+		e.WaitForKeyPress(instruction)
+	case OpSetDelayTimer:
+		e.SetDelayTimer(instruction)
+	case OpSetSoundTimer:
+		e.SetSoundTimer(instruction)
+	case OpAddIndex:
+		e.StoreValueOfVxPlusIInI(instruction)
+	case OpLoadFont:
+		e.SetLocationOfSprite(instruction)
+	case OpStoreBCD:
+		e.StoreBCDRepresentationInMemory(instruction)
+	case OpStoreRegisters:
+		e.LoadRegistersToMemory(instruction)
+	case OpLoadRegisters:
+		e.LoadRegistersFromMemory(instruction)
+	case OpCycleBackground:
+		e.CycleBackground()
+	case OpColorZones:
+		e.ColorZones(instruction)
+	case OpColorRows:
+		e.ColorRows(instruction)
 	}
 }
@@ -224,6 +224,66 @@ func TestDrawInstruction(t *testing.T) {
 		var wantedVf byte = 0x1
 		AssertBytes(t, gotVf, wantedVf)
 	})
+
+	// a 2x2 square is drawn over the edges, points are x, y of lit pixels
+	edgeTests := []struct {
+		name          string
+		width, height byte
+		x, y          byte
+		wrap          bool
+		want          [][2]int
+	}{
+		{"right edge is clipped", 64, 32, 63, 4, false, [][2]int{{63, 4}, {63, 5}}},
+		{"right edge wraps", 64, 32, 63, 4, true, [][2]int{{0, 4}, {63, 4}, {0, 5}, {63, 5}}},
+		{"bottom edge is clipped", 64, 32, 10, 31, false, [][2]int{{10, 31}, {11, 31}}},
+		{"bottom edge wraps", 64, 32, 10, 31, true, [][2]int{{10, 0}, {11, 0}, {10, 31}, {11, 31}}},
+		{"corner is clipped", 64, 32, 63, 31, false, [][2]int{{63, 31}}},
+		{"corner wraps", 64, 32, 63, 31, true, [][2]int{{0, 0}, {63, 0}, {0, 31}, {63, 31}}},
+		{"corner of 128x64 is clipped", 128, 64, 127, 63, false, [][2]int{{127, 63}}},
+		{"corner of 128x64 wraps", 128, 64, 127, 63, true, [][2]int{{0, 0}, {127, 0}, {0, 63}, {127, 63}}},
+		{"coordinates past 128x64 wrap before drawing", 128, 64, 255, 127, false, [][2]int{{127, 63}}},
+		{"corner of 64x64 is clipped", 64, 64, 63, 63, false, [][2]int{{63, 63}}},
+		{"corner of 64x64 wraps", 64, 64, 63, 63, true, [][2]int{{0, 0}, {63, 0}, {0, 63}, {63, 63}}},
+		{"bottom edge of 64x64 is below 32", 64, 64, 0, 31, false, [][2]int{{0, 31}, {1, 31}, {0, 32}, {1, 32}}},
+	}
+	for _, test := range edgeTests {
+		t.Run("Sprite at the "+test.name, func(t *testing.T) {
+			chip := NewChip8()
+			chip.SetResolution(test.width, test.height)
+			chip.Quirks.WrapSprites = test.wrap
+			chip.Memory[0x300], chip.Memory[0x301] = 0xc0, 0xc0
+			chip.I = 0x300
+			chip.Registers[0x0], chip.Registers[0x1] = test.x, test.y
+
+			chip.Draw(Decode(0xd012))
+
+			var got [][2]int
+			for position, pixel := range chip.Screen {
+				if pixel == chip.PrimaryColor {
+					got = append(got, [2]int{position % int(test.width), position / int(test.width)})
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	t.Run("Wrapped pixels set VF on collision", func(t *testing.T) {
+		chip := NewChip8()
+		chip.Quirks.WrapSprites = true
+		chip.Screen[0] = chip.PrimaryColor
+		chip.Memory[0x300] = 0xc0
+		chip.I = 0x300
+		chip.Registers[0x0] = 63
+
+		chip.Draw(Decode(0xd011))
+
+		AssertBytes(t, chip.Registers[0xf], 1)
+		if chip.Screen[0] != chip.SecondaryColor {
+			t.Errorf("got %v, want %v", chip.Screen[0], chip.SecondaryColor)
+		}
+	})
 }
 
 func TestAddToRegister(t *testing.T) {
@@ -285,9 +345,23 @@ func TestSkipNextInstruction(t *testing.T) {
 }
 
 func TestJumpToLocationPlusV0(t *testing.T) {
-	t.Run("instruction 0xb321 with Vf = 0x4 sets pc to 0x325", func(t *testing.T) {
+	t.Run("instruction 0xb321 with V0 = 0x4 sets pc to 0x325", func(t *testing.T) {
+		chip := NewChip8()
+		chip.Registers[0x0] = 0x4
+		emulator := Emulator{EmulatorStore: chip}
+
+		emulator.Emulate(0xb3, 0x21)
+
+		got := chip.Pc
+		var want uint16 = 0x325
+
+		AssertAddress(t, got, want)
+	})
+	t.Run("instruction 0xb321 with V3 = 0x4 and JumpVx quirk sets pc to 0x325", func(t *testing.T) {
 		chip := NewChip8()
-		chip.Registers[0xf] = 0x4
+		chip.Quirks.JumpVx = true
+		chip.Registers[0x0] = 0x8
+		chip.Registers[0x3] = 0x4
 		emulator := Emulator{EmulatorStore: chip}
 
 		emulator.Emulate(0xb3, 0x21)
@@ -297,6 +371,15 @@ func TestJumpToLocationPlusV0(t *testing.T) {
 
 		AssertAddress(t, got, want)
 	})
+	t.Run("Step doesn't move pc after the jump", func(t *testing.T) {
+		chip := NewChip8()
+		chip.Registers[0x0] = 0x4
+		chip.LoadProgram([]byte{0xb3, 0x21})
+
+		chip.Step()
+
+		AssertAddress(t, chip.Pc, 0x325)
+	})
 }
 
 func TestCallAddress(t *testing.T) {
@@ -405,6 +488,15 @@ func TestReturn(t *testing.T) {
 		AssertAddress(t, pc, uint16(wantedPc))
 
 	})
+
+	t.Run("instruction 00EE with empty stack doesn't change pc", func(t *testing.T) {
+		chip := NewChip8()
+		emulator := Emulator{EmulatorStore: chip}
+
+		emulator.Emulate(0x00, 0xee)
+
+		AssertAddress(t, chip.Pc, 0x200)
+	})
 }
 
 func TestVxGetsVy(t *testing.T) {
@@ -441,6 +533,44 @@ func TestLoadRegistersFromMemory(t *testing.T) {
 			t.Errorf("got %v, want %v", got, want)
 		}
 	})
+
+	t.Run("instruction 0xf165 increases I by 2", func(t *testing.T) {
+		chip := NewChip8()
+		chip.I = 0x300
+		chip.Memory[0x300] = 0xf0
+		emulator := Emulator{EmulatorStore: chip}
+
+		emulator.Emulate(0xf1, 0x65)
+
+		AssertAddress(t, chip.I, 0x302)
+	})
+	t.Run("instruction 0xf165 with KeepIndex quirk doesn't change I", func(t *testing.T) {
+		chip := NewChip8()
+		chip.Quirks.KeepIndex = true
+		chip.I = 0x300
+		emulator := Emulator{EmulatorStore: chip}
+
+		emulator.Emulate(0xf1, 0x65)
+
+		AssertAddress(t, chip.I, 0x300)
+	})
+	t.Run("instruction 0xf265 reads memory wrapped around its end", func(t *testing.T) {
+		chip := NewChip8()
+		chip.I = 0xfff
+		chip.Memory[0xfff] = 0x1
+		chip.Memory[0x0] = 0x2
+		chip.Memory[0x1] = 0x3
+		emulator := Emulator{EmulatorStore: chip}
+
+		emulator.Emulate(0xf2, 0x65)
+
+		got := chip.Registers[:3]
+		want := []byte{0x1, 0x2, 0x3}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
 }
 
 func TestVxOrVy(t *testing.T) {
@@ -459,6 +589,17 @@ func TestVxOrVy(t *testing.T) {
 			t.Errorf("got %x, want %x", got, want)
 		}
 	})
+
+	t.Run("instruction 0x8011 with KeepVF quirk doesn't change Vf", func(t *testing.T) {
+		chip := NewChip8()
+		chip.Quirks.KeepVF = true
+		chip.Registers[0xf] = 0x5
+
+		emulator := Emulator{EmulatorStore: chip}
+		emulator.Emulate(0x80, 0x11)
+
+		AssertBytes(t, chip.Registers[0xf], 0x5)
+	})
 }
 
 func TestVxAndVy(t *testing.T) {
@@ -622,6 +763,29 @@ func TestVySubVx(t *testing.T) {
 
 		AssertBytes(t, gotVf, wantedVf)
 	})
+
+	t.Run("instruction 0x8017 sets Vf to 1 if V1 = V0", func(t *testing.T) {
+		chip := NewChip8()
+		chip.Registers[0x0] = 0x10
+		chip.Registers[0x1] = 0x10
+
+		emulator := Emulator{EmulatorStore: chip}
+		emulator.Emulate(0x80, 0x17)
+
+		AssertBytes(t, chip.Registers[0x0], 0x0)
+		AssertBytes(t, chip.Registers[0xf], 0x1)
+	})
+	t.Run("instruction 0x8017 sets Vf to 0 if V1 < V0", func(t *testing.T) {
+		chip := NewChip8()
+		chip.Registers[0x0] = 0x20
+		chip.Registers[0x1] = 0x10
+
+		emulator := Emulator{EmulatorStore: chip}
+		emulator.Emulate(0x80, 0x17)
+
+		AssertBytes(t, chip.Registers[0x0], 0xf0)
+		AssertBytes(t, chip.Registers[0xf], 0x0)
+	})
 }
 
 func TestVxRightShift(t *testing.T) {
@@ -660,6 +824,19 @@ func TestVxRightShift(t *testing.T) {
 
 		AssertBytes(t, gotVf, wantedVf)
 	})
+
+	t.Run("instruction 0x8016 with ShiftVx quirk shifts Vx", func(t *testing.T) {
+		chip := NewChip8()
+		chip.Quirks.ShiftVx = true
+		chip.Registers[0x0] = 0x8
+		chip.Registers[0x1] = 0x3
+
+		emulator := Emulator{EmulatorStore: chip}
+		emulator.Emulate(0x80, 0x16)
+
+		AssertBytes(t, chip.Registers[0x0], 0x4)
+		AssertBytes(t, chip.Registers[0xf], 0x0)
+	})
 }
 
 func TestVxLeftShift(t *testing.T) {
@@ -698,6 +875,17 @@ func TestLoadRegistersToMemory(t *testing.T) {
 			t.Errorf("got %v, want %v", got, want)
 		}
 	})
+
+	t.Run("instruction 0xf155 increases I by 2", func(t *testing.T) {
+		chip := NewChip8()
+		chip.I = 0x300
+		chip.Registers[0x1] = 0xf0
+
+		emulator := Emulator{EmulatorStore: chip}
+		emulator.Emulate(0xf1, 0x55)
+
+		AssertAddress(t, chip.I, 0x302)
+	})
 }
 
 func TestStoreBCDRepresentationInMemory(t *testing.T) {
@@ -761,6 +949,15 @@ func TestSetLocationOfSprite(t *testing.T) {
 
 		AssertAddress(t, got, want)
 	})
+
+	t.Run("instruction 0xf029 for V0=0x1f uses the low nibble", func(t *testing.T) {
+		chip := NewChip8()
+		chip.Registers[0x0] = 0x1f
+		emulator := Emulator{EmulatorStore: chip}
+		emulator.Emulate(0xf0, 0x29)
+
+		AssertAddress(t, chip.I, 0x4b)
+	})
 }
 
 func TestSetSoundTimer(t *testing.T) {
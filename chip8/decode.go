@@ -0,0 +1,258 @@
+package chip8
+
+// Op is the operation of an instruction without its operands.
+type Op byte
+
+const (
+	// OpInvalid is returned for opcodes which are not instructions, e.g. data.
+	OpInvalid Op = iota
+	OpSys
+	OpClearScreen
+	OpReturn
+	OpJump
+	OpCall
+	OpSkipEqual
+	OpSkipNotEqual
+	OpSkipEqualRegisters
+	OpLoad
+	OpAdd
+	OpMove
+	OpOr
+	OpAnd
+	OpXor
+	OpAddRegisters
+	OpSub
+	OpShiftRight
+	OpSubReverse
+	OpShiftLeft
+	OpSkipNotEqualRegisters
+	OpLoadIndex
+	OpJumpOffset
+	OpRandom
+	OpDraw
+	OpSkipKeyPressed
+	OpSkipKeyNotPressed
+	OpLoadDelayTimer
+	OpWaitKey
+	OpSetDelayTimer
+	OpSetSoundTimer
+	OpAddIndex
+	OpLoadFont
+	OpStoreBCD
+	OpStoreRegisters
+	OpLoadRegisters
+	// OpCycleBackground, OpColorZones and OpColorRows are colour instructions of CHIP-8X.
+	OpCycleBackground
+	OpColorZones
+	OpColorRows
+)
+
+// Platform is the machine which introduced an instruction.
+type Platform byte
+
+const (
+	PlatformChip8 Platform = iota
+	// PlatformHires is CHIP-8 with 64x64 pixels, programs enter it by jumping to 0x260 first.
+	PlatformHires
+	// PlatformChip8X is CHIP-8 with the VP-590 colour board, BNNN is replaced by colour instructions.
+	PlatformChip8X
+	// PlatformETI660 is CHIP-8 of the ETI-660, programs are loaded at 0x600.
+	PlatformETI660
+	// PlatformChip8Small is CHIP-8 of a COSMAC VIP with 2 KB of RAM.
+	PlatformChip8Small
+)
+
+func (p Platform) String() string {
+	switch p {
+	case PlatformChip8:
+		return "CHIP-8"
+	case PlatformHires:
+		return "HIRES CHIP-8"
+	case PlatformChip8X:
+		return "CHIP-8X"
+	case PlatformETI660:
+		return "ETI-660"
+	case PlatformChip8Small:
+		return "CHIP-8 (2 KB)"
+	}
+	return "unknown"
+}
+
+// Platforms contains the selectable platforms by their name.
+var Platforms = map[string]Platform{
+	"chip8":    PlatformChip8,
+	"hires":    PlatformHires,
+	"chip8x":   PlatformChip8X,
+	"eti660":   PlatformETI660,
+	"chip8-2k": PlatformChip8Small,
+}
+
+// Instruction is a decoded opcode, operands which are not used by the operation are still filled.
+type Instruction struct {
+	Opcode uint16
+	Op     Op
+	// X and Y are registers in the second and third nibble.
+	X, Y byte
+	// N is the last nibble, NN the last byte and NNN the last 12 bits.
+	N        byte
+	NN       byte
+	NNN      uint16
+	Platform Platform
+}
+
+// opcodePattern describes the opcodes of an operation in the usual notation, hexadecimal digits
+// must match and letters are operands, e.g. "8XY4".
+type opcodePattern struct {
+	pattern  string
+	op       Op
+	platform Platform
+}
+
+// opcodePatterns are matched in order, so patterns with more fixed digits are before the general ones.
+// Patterns of other platforms than CHIP-8 are matched only when decoding for their platform.
+var opcodePatterns = []opcodePattern{
+	{"00E0", OpClearScreen, PlatformChip8},
+	{"00EE", OpReturn, PlatformChip8},
+	{"0230", OpClearScreen, PlatformHires},
+	{"02A0", OpCycleBackground, PlatformChip8X},
+	{"0NNN", OpSys, PlatformChip8},
+	{"1NNN", OpJump, PlatformChip8},
+	{"2NNN", OpCall, PlatformChip8},
+	{"3XNN", OpSkipEqual, PlatformChip8},
+	{"4XNN", OpSkipNotEqual, PlatformChip8},
+	{"5XY0", OpSkipEqualRegisters, PlatformChip8},
+	{"6XNN", OpLoad, PlatformChip8},
+	{"7XNN", OpAdd, PlatformChip8},
+	{"8XY0", OpMove, PlatformChip8},
+	{"8XY1", OpOr, PlatformChip8},
+	{"8XY2", OpAnd, PlatformChip8},
+	{"8XY3", OpXor, PlatformChip8},
+	{"8XY4", OpAddRegisters, PlatformChip8},
+	{"8XY5", OpSub, PlatformChip8},
+	{"8XY6", OpShiftRight, PlatformChip8},
+	{"8XY7", OpSubReverse, PlatformChip8},
+	{"8XYE", OpShiftLeft, PlatformChip8},
+	{"9XY0", OpSkipNotEqualRegisters, PlatformChip8},
+	{"ANNN", OpLoadIndex, PlatformChip8},
+	{"BXY0", OpColorZones, PlatformChip8X},
+	{"BXYN", OpColorRows, PlatformChip8X},
+	{"BNNN", OpJumpOffset, PlatformChip8},
+	{"CXNN", OpRandom, PlatformChip8},
+	{"DXYN", OpDraw, PlatformChip8},
+	{"EX9E", OpSkipKeyPressed, PlatformChip8},
+	{"EXA1", OpSkipKeyNotPressed, PlatformChip8},
+	{"FX07", OpLoadDelayTimer, PlatformChip8},
+	{"FX0A", OpWaitKey, PlatformChip8},
+	{"FX15", OpSetDelayTimer, PlatformChip8},
+	{"FX18", OpSetSoundTimer, PlatformChip8},
+	{"FX1E", OpAddIndex, PlatformChip8},
+	{"FX29", OpLoadFont, PlatformChip8},
+	{"FX33", OpStoreBCD, PlatformChip8},
+	{"FX55", OpStoreRegisters, PlatformChip8},
+	{"FX65", OpLoadRegisters, PlatformChip8},
+}
+
+// matches reports whether opcode matches the pattern.
+func (p opcodePattern) matches(opcode uint16) bool {
+	for i := 0; i < 4; i++ {
+		nibble := byte(opcode>>(12-4*i)) & 0xf
+		digit := p.pattern[i]
+		switch {
+		case digit >= '0' && digit <= '9':
+			if nibble != digit-'0' {
+				return false
+			}
+		case digit >= 'A' && digit <= 'F':
+			if nibble != digit-'A'+0xa {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// decodeTables contain the index of the matching pattern plus one for every opcode of every
+// platform, 0 is invalid.
+var decodeTables = func() map[Platform][]byte {
+	tables := map[Platform][]byte{}
+	for _, platform := range Platforms {
+		table := make([]byte, 0x10000)
+		for opcode := range table {
+			for i, pattern := range opcodePatterns {
+				if (pattern.platform == PlatformChip8 || pattern.platform == platform) && pattern.matches(uint16(opcode)) {
+					table[opcode] = byte(i + 1)
+					break
+				}
+			}
+		}
+		tables[platform] = table
+	}
+	return tables
+}()
+
+// Decode returns the CHIP-8 instruction of opcode.
+func Decode(opcode uint16) Instruction {
+	return DecodeFor(PlatformChip8, opcode)
+}
+
+// DecodeFor returns the instruction of opcode on platform.
+func DecodeFor(platform Platform, opcode uint16) Instruction {
+	instruction := Instruction{
+		Opcode: opcode,
+		X:      byte(opcode>>8) & 0xf,
+		Y:      byte(opcode>>4) & 0xf,
+		N:      byte(opcode) & 0xf,
+		NN:     byte(opcode),
+		NNN:    opcode & 0xfff,
+	}
+	if index := decodeTables[platform][opcode]; index > 0 {
+		pattern := opcodePatterns[index-1]
+		instruction.Op = pattern.op
+		instruction.Platform = pattern.platform
+	}
+	return instruction
+}
+
+// DecodeBytes returns the CHIP-8 instruction of an opcode stored in two bytes of memory.
+func DecodeBytes(firstByte, secondByte byte) Instruction {
+	return Decode(uint16(firstByte)<<8 | uint16(secondByte))
+}
+
+// DecodeBytes returns the instruction of an opcode stored in two bytes on the platform of chip.
+func (c *Chip8) DecodeBytes(firstByte, secondByte byte) Instruction {
+	return DecodeFor(c.Platform, uint16(firstByte)<<8|uint16(secondByte))
+}
+
+// InstructionAt decodes the instruction at address in memory.
+func (c *Chip8) InstructionAt(address uint16) Instruction {
+	return c.DecodeBytes(c.ReadMemory(address), c.ReadMemory(address+1))
+}
+
+// jumps reports whether the operation sets pc itself, so pc isn't moved to the next instruction.
+func (o Op) jumps() bool {
+	return o == OpJump || o == OpCall || o == OpJumpOffset
+}
+
+// ChangesScreen reports whether the operation changes pixels or colours of the screen.
+func (o Op) ChangesScreen() bool {
+	switch o {
+	case OpClearScreen, OpDraw, OpCycleBackground, OpColorZones, OpColorRows:
+		return true
+	}
+	return false
+}
+
+// changesColors reports whether the operation changes colours of CHIP-8X.
+func (o Op) changesColors() bool {
+	return o == OpCycleBackground || o == OpColorZones || o == OpColorRows
+}
+
+// String returns the pattern of the operation, e.g. "8XY4", invalid opcodes return "????".
+func (o Op) String() string {
+	for _, pattern := range opcodePatterns {
+		if pattern.op == o {
+			return pattern.pattern
+		}
+	}
+	return "????"
+}
@@ -0,0 +1,88 @@
+package chip8
+
+import "testing"
+
+func TestDecode(t *testing.T) {
+	t.Run("Operands are extracted from the opcode", func(t *testing.T) {
+		got := Decode(0xd12f)
+		want := Instruction{Opcode: 0xd12f, Op: OpDraw, X: 0x1, Y: 0x2, N: 0xf, NN: 0x2f, NNN: 0x12f, Platform: PlatformChip8}
+
+		if got != want {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("Specific patterns win over general ones", func(t *testing.T) {
+		cases := map[uint16]Op{
+			0x00e0: OpClearScreen,
+			0x00ee: OpReturn,
+			0x0123: OpSys,
+			0x8ab6: OpShiftRight,
+			0xf265: OpLoadRegisters,
+		}
+		for opcode, want := range cases {
+			if got := Decode(opcode).Op; got != want {
+				t.Errorf("got %v for %04X, want %v", got, opcode, want)
+			}
+		}
+	})
+
+	t.Run("Opcodes which are not instructions are invalid", func(t *testing.T) {
+		for _, opcode := range []uint16{0x5121, 0x8008, 0x9ab1, 0xe19f, 0xffff} {
+			if got := Decode(opcode).Op; got != OpInvalid {
+				t.Errorf("got %v for %04X, want invalid", got, opcode)
+			}
+		}
+	})
+
+	t.Run("Every operation has a pattern", func(t *testing.T) {
+		for op := OpSys; op <= OpColorRows; op++ {
+			if op.String() == "????" {
+				t.Errorf("got no pattern for operation %d", op)
+			}
+		}
+	})
+
+	t.Run("Instructions of other platforms are decoded only for them", func(t *testing.T) {
+		cases := []struct {
+			platform Platform
+			opcode   uint16
+			want     Op
+		}{
+			{PlatformChip8, 0x0230, OpSys},
+			{PlatformHires, 0x0230, OpClearScreen},
+			{PlatformChip8X, 0x0230, OpSys},
+			{PlatformChip8, 0x02a0, OpSys},
+			{PlatformChip8X, 0x02a0, OpCycleBackground},
+			{PlatformChip8, 0xb120, OpJumpOffset},
+			{PlatformChip8X, 0xb120, OpColorZones},
+			{PlatformChip8X, 0xb125, OpColorRows},
+			{PlatformChip8X, 0x00e0, OpClearScreen},
+		}
+		for _, test := range cases {
+			if got := DecodeFor(test.platform, test.opcode).Op; got != test.want {
+				t.Errorf("got %v for %04X on %v, want %v", got, test.opcode, test.platform, test.want)
+			}
+		}
+	})
+
+	t.Run("Instruction is decoded for the platform of the chip", func(t *testing.T) {
+		chip := NewChip8()
+		chip.SetPlatform(PlatformChip8X)
+
+		got := chip.DecodeBytes(0xb1, 0x20)
+
+		if got.Op != OpColorZones || got.Platform != PlatformChip8X {
+			t.Errorf("got %v of %v, want %v of %v", got.Op, got.Platform, OpColorZones, PlatformChip8X)
+		}
+	})
+
+	t.Run("Instruction is read from memory", func(t *testing.T) {
+		chip := NewChip8()
+		chip.LoadProgram([]byte{0x22, 0x34})
+
+		if got := chip.InstructionAt(0x200); got.Op != OpCall || got.NNN != 0x234 {
+			t.Errorf("got %+v, want call of 0x234", got)
+		}
+	})
+}
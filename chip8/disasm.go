@@ -0,0 +1,96 @@
+package chip8
+
+import "fmt"
+
+// Disassemble returns the mnemonic of the instruction, e.g. "LD VA, 0x02" for 6A02.
+// Bytes which are not a valid instruction are returned as "DW 0x1234".
+func Disassemble(firstByte, secondByte byte) string {
+	return DisassembleInstruction(DecodeBytes(firstByte, secondByte))
+}
+
+// DisassembleInstruction returns the mnemonic of a decoded instruction, so instructions of
+// other platforms than CHIP-8 can be disassembled too.
+func DisassembleInstruction(instruction Instruction) string {
+	x, y, nn, nnn := instruction.X, instruction.Y, instruction.NN, instruction.NNN
+
+	switch instruction.Op {
+	case OpClearScreen:
+		return "CLS"
+	case OpReturn:
+		return "RET"
+	case OpSys:
+		return fmt.Sprintf("SYS 0x%03X", nnn)
+	case OpJump:
+		return fmt.Sprintf("JP 0x%03X", nnn)
+	case OpCall:
+		return fmt.Sprintf("CALL 0x%03X", nnn)
+	case OpSkipEqual:
+		return fmt.Sprintf("SE V%X, 0x%02X", x, nn)
+	case OpSkipNotEqual:
+		return fmt.Sprintf("SNE V%X, 0x%02X", x, nn)
+	case OpSkipEqualRegisters:
+		return fmt.Sprintf("SE V%X, V%X", x, y)
+	case OpLoad:
+		return fmt.Sprintf("LD V%X, 0x%02X", x, nn)
+	case OpAdd:
+		return fmt.Sprintf("ADD V%X, 0x%02X", x, nn)
+	case OpMove:
+		return fmt.Sprintf("LD V%X, V%X", x, y)
+	case OpOr:
+		return fmt.Sprintf("OR V%X, V%X", x, y)
+	case OpAnd:
+		return fmt.Sprintf("AND V%X, V%X", x, y)
+	case OpXor:
+		return fmt.Sprintf("XOR V%X, V%X", x, y)
+	case OpAddRegisters:
+		return fmt.Sprintf("ADD V%X, V%X", x, y)
+	case OpSub:
+		return fmt.Sprintf("SUB V%X, V%X", x, y)
+	case OpShiftRight:
+		return fmt.Sprintf("SHR V%X, V%X", x, y)
+	case OpSubReverse:
+		return fmt.Sprintf("SUBN V%X, V%X", x, y)
+	case OpShiftLeft:
+		return fmt.Sprintf("SHL V%X, V%X", x, y)
+	case OpSkipNotEqualRegisters:
+		return fmt.Sprintf("SNE V%X, V%X", x, y)
+	case OpLoadIndex:
+		return fmt.Sprintf("LD I, 0x%03X", nnn)
+	case OpJumpOffset:
+		return fmt.Sprintf("JP V0, 0x%03X", nnn)
+	case OpRandom:
+		return fmt.Sprintf("RND V%X, 0x%02X", x, nn)
+	case OpDraw:
+		return fmt.Sprintf("DRW V%X, V%X, %d", x, y, instruction.N)
+	case OpSkipKeyPressed:
+		return fmt.Sprintf("SKP V%X", x)
+	case OpSkipKeyNotPressed:
+		return fmt.Sprintf("SKNP V%X", x)
+	case OpLoadDelayTimer:
+		return fmt.Sprintf("LD V%X, DT", x)
+	case OpWaitKey:
+		return fmt.Sprintf("LD V%X, K", x)
+	case OpSetDelayTimer:
+		return fmt.Sprintf("LD DT, V%X", x)
+	case OpSetSoundTimer:
+		return fmt.Sprintf("LD ST, V%X", x)
+	case OpAddIndex:
+		return fmt.Sprintf("ADD I, V%X", x)
+	case OpLoadFont:
+		return fmt.Sprintf("LD F, V%X", x)
+	case OpStoreBCD:
+		return fmt.Sprintf("LD B, V%X", x)
+	case OpStoreRegisters:
+		return fmt.Sprintf("LD [I], V%X", x)
+	case OpLoadRegisters:
+		return fmt.Sprintf("LD V%X, [I]", x)
+	case OpCycleBackground:
+		return "BGC"
+	case OpColorZones:
+		return fmt.Sprintf("COL V%X, V%X", x, y)
+	case OpColorRows:
+		return fmt.Sprintf("COL V%X, V%X, %d", x, y, instruction.N)
+	}
+
+	return fmt.Sprintf("DW 0x%04X", instruction.Opcode)
+}
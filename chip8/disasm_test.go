@@ -0,0 +1,46 @@
+package chip8
+
+import "testing"
+
+func TestDisassemble(t *testing.T) {
+	cases := map[[2]byte]string{
+		{0x00, 0xe0}: "CLS",
+		{0x00, 0xee}: "RET",
+		{0x13, 0x45}: "JP 0x345",
+		{0x23, 0x42}: "CALL 0x342",
+		{0x3c, 0x00}: "SE VC, 0x00",
+		{0x6a, 0x02}: "LD VA, 0x02",
+		{0x80, 0x14}: "ADD V0, V1",
+		{0x80, 0x1e}: "SHL V0, V1",
+		{0xa2, 0x31}: "LD I, 0x231",
+		{0xb3, 0x21}: "JP V0, 0x321",
+		{0xd0, 0x15}: "DRW V0, V1, 5",
+		{0xe2, 0xa1}: "SKNP V2",
+		{0xf1, 0x65}: "LD V1, [I]",
+		{0x51, 0x23}: "DW 0x5123",
+		{0xff, 0xff}: "DW 0xFFFF",
+	}
+
+	for bytes, want := range cases {
+		t.Run(want, func(t *testing.T) {
+			got := Disassemble(bytes[0], bytes[1])
+
+			if got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+
+	t.Run("Instructions of CHIP-8X are disassembled", func(t *testing.T) {
+		cases := map[uint16]string{
+			0x02a0: "BGC",
+			0xb120: "COL V1, V2",
+			0xb124: "COL V1, V2, 4",
+		}
+		for opcode, want := range cases {
+			if got := DisassembleInstruction(DecodeFor(PlatformChip8X, opcode)); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		}
+	})
+}
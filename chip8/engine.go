@@ -0,0 +1,161 @@
+package chip8
+
+// Engine executes instructions of a chip, engines differ only in speed. Instructions must have
+// the same effect as if they were executed by Step.
+type Engine interface {
+	// Run executes the given number of instructions, it stops early when an instruction waits
+	// for the display.
+	Run(instructions int)
+}
+
+// codeCache is a cache of an engine containing instructions decoded from memory.
+type codeCache interface {
+	// invalidate forgets instructions containing the byte at address.
+	invalidate(address uint16)
+	invalidateAll()
+}
+
+// handler executes a decoded instruction without moving pc to the next instruction.
+type handler func(c *Chip8, instruction Instruction)
+
+func ignoreInstruction(c *Chip8, instruction Instruction) {}
+
+// handlers contains the method executing every operation, it skips the interface dispatch of Emulate.
+var handlers = [...]handler{
+	OpInvalid:               ignoreInstruction,
+	OpSys:                   ignoreInstruction,
+	OpClearScreen:           func(c *Chip8, instruction Instruction) { c.ClearScreen() },
+	OpReturn:                (*Chip8).Return,
+	OpJump:                  (*Chip8).JumpToInstruction,
+	OpCall:                  (*Chip8).CallAddress,
+	OpSkipEqual:             (*Chip8).SkipNextInstruction,
+	OpSkipNotEqual:          (*Chip8).SkipIfNotEquals,
+	OpSkipEqualRegisters:    (*Chip8).SkipEqualRegisters,
+	OpLoad:                  (*Chip8).LoadRegister,
+	OpAdd:                   (*Chip8).AddValueToRegister,
+	OpMove:                  (*Chip8).VxGetsVy,
+	OpOr:                    (*Chip8).VxOrVy,
+	OpAnd:                   (*Chip8).VxAndVy,
+	OpXor:                   (*Chip8).VxXorVy,
+	OpAddRegisters:          (*Chip8).VxAddVy,
+	OpSub:                   (*Chip8).VxSubVy,
+	OpShiftRight:            (*Chip8).VxRightShift,
+	OpSubReverse:            (*Chip8).VySubVx,
+	OpShiftLeft:             (*Chip8).VxLeftShift,
+	OpSkipNotEqualRegisters: (*Chip8).SkipNotEqualRegisters,
+	OpLoadIndex:             (*Chip8).LoadIndexRegister,
+	OpJumpOffset:            (*Chip8).JumpPlusRegister,
+	OpRandom:                (*Chip8).SetRandomNumber,
+	OpDraw:                  (*Chip8).Draw,
+	OpSkipKeyPressed:        (*Chip8).SkipKeyPressed,
+	OpSkipKeyNotPressed:     (*Chip8).SkipKeyNotPressed,
+	OpLoadDelayTimer:        (*Chip8).PutTimerInRegister,
+	OpWaitKey:               (*Chip8).WaitForKeyPress,
+	OpSetDelayTimer:         (*Chip8).SetDelayTimer,
+	OpSetSoundTimer:         (*Chip8).SetSoundTimer,
+	OpAddIndex:              (*Chip8).StoreValueOfVxPlusIInI,
+	OpLoadFont:              (*Chip8).SetLocationOfSprite,
+	OpStoreBCD:              (*Chip8).StoreBCDRepresentationInMemory,
+	OpStoreRegisters:        (*Chip8).LoadRegistersToMemory,
+	OpLoadRegisters:         (*Chip8).LoadRegistersFromMemory,
+	OpCycleBackground:       func(c *Chip8, instruction Instruction) { c.CycleBackground() },
+	OpColorZones:            (*Chip8).ColorZones,
+	OpColorRows:             (*Chip8).ColorRows,
+}
+
+// decodedInstruction is an entry of the decode cache.
+type decodedInstruction struct {
+	instruction Instruction
+	handler     handler
+	// advance is false for instructions which set pc themselves
+	advance bool
+	valid   bool
+}
+
+// decodeCache contains decoded instructions at every address of memory. Entries are invalidated
+// when a byte of the instruction is written, so self-modifying programs run correctly.
+type decodeCache struct {
+	entries []decodedInstruction
+}
+
+func (d *decodeCache) invalidate(address uint16) {
+	if int(address) < len(d.entries) {
+		d.entries[address].valid = false
+	}
+	if address > 0 && int(address-1) < len(d.entries) {
+		d.entries[address-1].valid = false
+	}
+}
+
+func (d *decodeCache) invalidateAll() {
+	for i := range d.entries {
+		d.entries[i].valid = false
+	}
+}
+
+// CachedEngine decodes the instruction at every address only once and calls its handler directly.
+// Step is used instead while tracing, profiling, watching or recording undo, so all hooks work
+// the same with both.
+type CachedEngine struct {
+	chip  *Chip8
+	cache *decodeCache
+}
+
+// NewCachedEngine creates engine for chip, the chip invalidates its cache when memory is written.
+func NewCachedEngine(chip *Chip8) *CachedEngine {
+	cache := &decodeCache{entries: make([]decodedInstruction, len(chip.Memory))}
+	chip.code = cache
+	return &CachedEngine{chip: chip, cache: cache}
+}
+
+// Run executes the given number of instructions or fewer if one of them waits for the display.
+func (e *CachedEngine) Run(instructions int) {
+	c := e.chip
+	if c.instrumented() {
+		for i := 0; i < instructions && !c.waitingForDisplay; i++ {
+			c.Step()
+		}
+		return
+	}
+
+	for i := 0; i < instructions && !c.waitingForDisplay; i++ {
+		pc := c.Pc
+		if int(pc)+1 >= len(c.Memory) {
+			// the instruction wraps around the end of memory
+			c.Step()
+			continue
+		}
+		entry := &e.cache.entries[pc]
+		if !entry.valid {
+			instruction := c.DecodeBytes(c.Memory[pc], c.Memory[pc+1])
+			*entry = decodedInstruction{
+				instruction: instruction,
+				handler:     handlers[instruction.Op],
+				advance:     !instruction.Op.jumps(),
+				valid:       true,
+			}
+		}
+
+		entry.handler(c, entry.instruction)
+		if entry.advance {
+			c.Pc += 2
+		}
+		if int(c.Pc) >= len(c.Memory) {
+			c.Pc = c.memoryAddress(c.Pc)
+		}
+	}
+}
+
+// interpret executes instruction at pc like Step without calling its hooks.
+func interpret(c *Chip8, instruction Instruction) {
+	handlers[instruction.Op](c, instruction)
+	if !instruction.Op.jumps() {
+		c.Pc += 2
+	}
+	c.Pc = c.memoryAddress(c.Pc)
+}
+
+// instrumented reports whether any hook called by Step is set.
+func (c *Chip8) instrumented() bool {
+	return c.Tracer != nil || c.Profiler != nil || c.Watchpoints != nil || c.Undo != nil || c.CodeTracker != nil
+}
@@ -0,0 +1,218 @@
+package chip8
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// bundledROMs returns programs in the root of the repository by their file names.
+func bundledROMs(tb testing.TB) map[string][]byte {
+	paths, err := filepath.Glob("../*.ch8")
+	if err != nil || len(paths) == 0 {
+		tb.Fatalf("didn't find bundled ROMs, got %v", err)
+	}
+
+	roms := map[string][]byte{}
+	for _, path := range paths {
+		program, err := os.ReadFile(path)
+		if err != nil {
+			tb.Fatalf("didn't expect an error, got %v", err)
+		}
+		roms[filepath.Base(path)] = program
+	}
+	return roms
+}
+
+// newEngineChip creates chip with program loaded and deterministic random numbers.
+func newEngineChip(program []byte) *Chip8 {
+	chip := NewChip8()
+	chip.Keypad = NewMovieKeypad(nil)
+	chip.Random = rand.New(rand.NewSource(1))
+	chip.ClearScreen()
+	chip.LoadFont()
+	chip.LoadProgram(program)
+	return chip
+}
+
+func assertSameState(t *testing.T, got, want *Chip8) {
+	t.Helper()
+	if got.Pc != want.Pc || got.I != want.I || !reflect.DeepEqual(got.Registers, want.Registers) ||
+		!reflect.DeepEqual(got.Stack, want.Stack) || !reflect.DeepEqual(got.Timers, want.Timers) {
+		t.Fatalf("got pc 0x%X, I 0x%X, V %X, stack %X, want pc 0x%X, I 0x%X, V %X, stack %X",
+			got.Pc, got.I, got.Registers, got.Stack, want.Pc, want.I, want.Registers, want.Stack)
+	}
+	if !reflect.DeepEqual(got.Memory, want.Memory) || !reflect.DeepEqual(got.Screen, want.Screen) ||
+		!reflect.DeepEqual(got.Colors, want.Colors) {
+		t.Fatalf("got different memory, screen or colours at pc 0x%X", got.Pc)
+	}
+}
+
+// engines creates the engines which are compared with Step.
+var engines = map[string]func(chip *Chip8) Engine{
+	"cached":   func(chip *Chip8) Engine { return NewCachedEngine(chip) },
+	"compiled": func(chip *Chip8) Engine { return NewCompiledEngine(chip) },
+}
+
+func TestEngines(t *testing.T) {
+	bundled := bundledROMs(t)
+	// 0x200: jump to 0xFFD, 0xFFD: V0 += 0x10, 0xFFF: V0 += 1 with the second byte at 0x000,
+	// 0x001: jump to 0xFFD
+	wrapping := func(chip *Chip8) {
+		copy(chip.Memory[0xffd:], []byte{0x70, 0x10, 0x70})
+		copy(chip.Memory, []byte{0x01, 0x1f, 0xfd})
+	}
+	// 0x206: cycle the background, 0x208: colour zones V0 and V1 with V2, 0x20A: colour 4 rows
+	// from V4 of the column of V3, 0x20C: draw at V3, V4, 0x20E-0x216: change V0-V4, 0x218: jump to 0x206
+	colors := []byte{0x60, 0x00, 0x61, 0x00, 0x62, 0x00, 0x02, 0xa0, 0xb0, 0x20, 0xb3, 0x24, 0xd3, 0x45,
+		0x70, 0x01, 0x71, 0x01, 0x72, 0x03, 0x73, 0x07, 0x74, 0x05, 0x12, 0x06}
+
+	configurations := []struct {
+		name     string
+		programs map[string][]byte
+		platform Platform
+		quirks   Quirks
+		// patch changes memory after the program was loaded
+		patch func(chip *Chip8)
+		// odd tickrates stop compiled blocks in the middle
+		tickrate int
+		frames   int
+	}{
+		{name: "Bundled ROMs", programs: bundled, tickrate: 17, frames: 300},
+		{name: "Bundled ROMs waiting for the display", programs: bundled, quirks: QuirksVIP, tickrate: 17, frames: 300},
+		{name: "Bundled ROMs wrapping sprites", programs: bundled, quirks: QuirksXOChip, tickrate: 17, frames: 300},
+		{name: "Execution wrapping around the end of memory", programs: map[string][]byte{"wrap": {0x1f, 0xfd}},
+			patch: wrapping, tickrate: 7, frames: 5},
+		{name: "CHIP-8X colours", programs: map[string][]byte{"colors": colors}, platform: PlatformChip8X,
+			tickrate: 13, frames: 50},
+	}
+
+	for _, configuration := range configurations {
+		for engineName, newEngine := range engines {
+			for name, program := range configuration.programs {
+				t.Run(configuration.name+"/"+engineName+"/"+name, func(t *testing.T) {
+					newChip := func() *Chip8 {
+						chip := NewChip8()
+						chip.Keypad = NewMovieKeypad(nil)
+						chip.Random = rand.New(rand.NewSource(1))
+						chip.Quirks = configuration.quirks
+						chip.SetPlatform(configuration.platform)
+						chip.ClearScreen()
+						chip.LoadFont()
+						chip.LoadProgram(program)
+						if configuration.patch != nil {
+							configuration.patch(chip)
+						}
+						return chip
+					}
+					want := newChip()
+					got := newChip()
+					got.Engine = newEngine(got)
+
+					for frame := 0; frame < configuration.frames; frame++ {
+						want.RunFrame(configuration.tickrate)
+						got.RunFrame(configuration.tickrate)
+						assertSameState(t, got, want)
+					}
+				})
+			}
+		}
+	}
+
+	t.Run("Execution wraps around the end of memory", func(t *testing.T) {
+		chip := newEngineChip([]byte{0x1f, 0xfd})
+		wrapping(chip)
+		chip.Engine = NewCompiledEngine(chip)
+
+		chip.RunFrame(7)
+		if chip.Registers[0] == 0 || int(chip.Pc) >= len(chip.Memory) {
+			t.Errorf("got V0 0x%02X and pc 0x%X, want V0 increased and pc in memory", chip.Registers[0], chip.Pc)
+		}
+	})
+
+	t.Run("CHIP-8X colours change", func(t *testing.T) {
+		chip := NewChip8()
+		chip.SetPlatform(PlatformChip8X)
+		chip.LoadProgram(colors)
+		chip.Engine = NewCachedEngine(chip)
+
+		reset := NewChip8()
+		reset.SetPlatform(PlatformChip8X)
+
+		chip.RunFrame(50)
+		if chip.Colors.Background == reset.Colors.Background ||
+			reflect.DeepEqual(chip.Colors.Foreground, reset.Colors.Foreground) {
+			t.Errorf("got colours %+v, want them changed by the program", chip.Colors)
+		}
+	})
+}
+
+func TestCachedEngine(t *testing.T) {
+	t.Run("Writes into cached code are executed", func(t *testing.T) {
+		// 0x200: V0 = 0x61, 0x202: V1 = 0x07, 0x204: call 0x20c, 0x206: I = 0x20c,
+		// 0x208: store V0-V1 over 0x20c, 0x20a: jump to 0x204, 0x20c: V1 += 1, 0x20e: return
+		program := []byte{0x60, 0x61, 0x61, 0x07, 0x22, 0x0c, 0xa2, 0x0c, 0xf1, 0x55, 0x12, 0x04, 0x71, 0x01, 0x00, 0xee}
+		chip := newEngineChip(program)
+		engine := NewCachedEngine(chip)
+
+		// the first call executes and caches V1 += 1, then it's replaced by V1 = 0x08
+		engine.Run(9)
+		chip.Registers[1] = 0
+		engine.Run(1)
+
+		if chip.Registers[1] != 0x08 || chip.Memory[0x20c] != 0x61 {
+			t.Errorf("got V1 0x%02X and opcode 0x%02X%02X, want 0x08 and 0x6108", chip.Registers[1], chip.Memory[0x20c], chip.Memory[0x20d])
+		}
+	})
+
+	t.Run("Memory written by a debugger invalidates the cache", func(t *testing.T) {
+		chip := newEngineChip([]byte{0x60, 0x01, 0x12, 0x00})
+		engine := NewCachedEngine(chip)
+		engine.Run(2)
+
+		chip.WriteMemory(0x201, 0x02)
+		engine.Run(1)
+
+		if chip.Registers[0] != 0x02 {
+			t.Errorf("got V0 0x%02X, want 0x02", chip.Registers[0])
+		}
+	})
+
+	t.Run("Hooks of Step are called", func(t *testing.T) {
+		chip := newEngineChip([]byte{0x60, 0x01, 0x12, 0x00})
+		chip.Profiler = NewProfiler(len(chip.Memory))
+		chip.Engine = NewCachedEngine(chip)
+
+		chip.RunFrame(10)
+
+		if chip.Profiler.Instructions() != 10 {
+			t.Errorf("got %d profiled instructions, want 10", chip.Profiler.Instructions())
+		}
+	})
+}
+
+func BenchmarkEngines(b *testing.B) {
+	const tickrate = 1000
+	benchmarked := map[string]func(chip *Chip8) Engine{"interpreter": func(chip *Chip8) Engine { return nil }}
+	for name, newEngine := range engines {
+		benchmarked[name] = newEngine
+	}
+
+	for name, program := range bundledROMs(b) {
+		for engineName, newEngine := range benchmarked {
+			b.Run(name+"/"+engineName, func(b *testing.B) {
+				chip := newEngineChip(program)
+				chip.Engine = newEngine(chip)
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					chip.RunFrame(tickrate)
+				}
+				b.ReportMetric(float64(b.N*tickrate)/b.Elapsed().Seconds(), "instructions/s")
+			})
+		}
+	}
+}
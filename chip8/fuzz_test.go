@@ -0,0 +1,204 @@
+package chip8
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// fuzzKeypad holds down keys set in the bit mask, the pressed key is the lowest one held down.
+type fuzzKeypad uint16
+
+func (k fuzzKeypad) IsKeyDown(key byte) bool {
+	return key < 16 && k&(1<<key) != 0
+}
+
+func (k fuzzKeypad) KeyPressed() (byte, bool) {
+	for key := byte(0); key < 16; key++ {
+		if k.IsKeyDown(key) {
+			return key, true
+		}
+	}
+	return 0, false
+}
+
+// fuzzInput is the state before the fuzzed instruction.
+type fuzzInput struct {
+	opcode    uint16
+	registers []byte
+	index     uint16
+	pc        uint16
+	// stack contains big endian addresses
+	stack  []byte
+	timers uint16
+	keys   uint16
+	// memory is copied to memory at I, so sprites, BCD and stored registers overlap it
+	memory []byte
+	// screen contains pixels in bits, starting with the most significant bit of the first byte
+	screen []byte
+	seed   int64
+}
+
+// addFuzzSeeds adds an input with every instruction, registers and addresses are chosen so
+// they reach the end of memory and the edges of the screen.
+func addFuzzSeeds(f *testing.F) {
+	opcodes := []uint16{
+		0x00e0, 0x00ee, 0x0123, 0x1234, 0x2345, 0x3a12, 0x4a12, 0x5ab0, 0x5ab1, 0x6a12, 0x7aff,
+		0x8ab0, 0x8ab1, 0x8ab2, 0x8ab3, 0x8ab4, 0x8ab5, 0x8ab6, 0x8ab7, 0x8abe, 0x8ff4, 0x8f0e, 0x8af7,
+		0x9ab0, 0xa123, 0xb2ff, 0xc0ff, 0xdab5, 0xdabf, 0xdff0, 0xe19e, 0xe1a1, 0xe1ff, 0xf107, 0xf10a,
+		0xf115, 0xf118, 0xf11e, 0xfa29, 0xfa33, 0xff55, 0xff65, 0xf3ff, 0xffff,
+	}
+	registers := []byte{0x3f, 0x1f, 0xff, 0x80, 0x00, 0x01, 0x7f, 0xfe, 0x10, 0x20, 0xc8, 0x3c, 0x99, 0x0a, 0x05, 0x01}
+	memory := []byte{0xff, 0x81, 0xaa, 0x55, 0x00, 0xf0, 0x0f, 0x18, 0x3c, 0x7e, 0xc3, 0x66, 0x24, 0x99, 0xe7}
+	screen := []byte{0xf0, 0x0f, 0xaa, 0x55, 0x00, 0xff, 0x81}
+	for _, opcode := range opcodes {
+		f.Add(opcode, registers, uint16(0x300), uint16(0x200), []byte{0x02, 0x40}, uint16(0x0302), uint16(0x0020), memory, screen, int64(1))
+		f.Add(opcode, registers, uint16(0xffe), uint16(0xffe), []byte{0x0f, 0xfe}, uint16(0), uint16(0), memory, screen, int64(2))
+	}
+}
+
+// newFuzzChips creates Chip8 and the reference model in the same state.
+func newFuzzChips(input fuzzInput, quirks Quirks) (*Chip8, *referenceChip) {
+	reference := &referenceChip{
+		i:      input.index,
+		pc:     input.pc % 4096,
+		delay:  byte(input.timers >> 8),
+		sound:  byte(input.timers),
+		keys:   input.keys,
+		random: rand.New(rand.NewSource(input.seed)),
+		quirks: quirks,
+	}
+	copy(reference.v[:], input.registers)
+	for k, value := range input.memory {
+		reference.write(input.index+uint16(k), value)
+	}
+	reference.write(reference.pc, byte(input.opcode>>8))
+	reference.write(reference.pc+1, byte(input.opcode))
+	for k := 0; k+1 < len(input.stack) && k < 32; k += 2 {
+		reference.stack = append(reference.stack, (uint16(input.stack[k])<<8|uint16(input.stack[k+1]))%4096)
+	}
+	for k := range reference.screen {
+		if k/8 < len(input.screen) {
+			reference.screen[k] = input.screen[k/8]&(0x80>>(k%8)) != 0
+		}
+	}
+
+	chip := NewChip8()
+	copy(chip.Memory, reference.memory[:])
+	copy(chip.Registers, reference.v[:])
+	chip.I = reference.i
+	chip.Pc = reference.pc
+	chip.Stack = append(chip.Stack, reference.stack...)
+	chip.Timers[0], chip.Timers[1] = reference.delay, reference.sound
+	for k, on := range reference.screen {
+		chip.Screen[k] = chip.SecondaryColor
+		if on {
+			chip.Screen[k] = chip.PrimaryColor
+		}
+	}
+	chip.Keypad = fuzzKeypad(input.keys)
+	chip.Random = rand.New(rand.NewSource(input.seed))
+	chip.Quirks = quirks
+	return chip, reference
+}
+
+// assertMatchesReference compares everything except pc.
+func assertMatchesReference(t *testing.T, profile string, chip *Chip8, reference *referenceChip) {
+	t.Helper()
+	if string(chip.Registers) != string(reference.v[:]) || chip.I != reference.i {
+		t.Fatalf("%s: got V %X, I 0x%X, want V %X, I 0x%X", profile, chip.Registers, chip.I, reference.v, reference.i)
+	}
+	if len(chip.Stack) != len(reference.stack) {
+		t.Fatalf("%s: got stack %X, want %X", profile, chip.Stack, reference.stack)
+	}
+	for k := range chip.Stack {
+		if chip.Stack[k] != reference.stack[k] {
+			t.Fatalf("%s: got stack %X, want %X", profile, chip.Stack, reference.stack)
+		}
+	}
+	if chip.Timers[0] != reference.delay || chip.Timers[1] != reference.sound {
+		t.Fatalf("%s: got timers %v, want %d and %d", profile, chip.Timers, reference.delay, reference.sound)
+	}
+	for address := range chip.Memory {
+		if chip.Memory[address] != reference.memory[address] {
+			t.Fatalf("%s: got 0x%02X at 0x%03X, want 0x%02X", profile, chip.Memory[address], address, reference.memory[address])
+		}
+	}
+	for k, on := range reference.screen {
+		if (chip.Screen[k] == chip.PrimaryColor) != on {
+			t.Fatalf("%s: got pixel %d,%d %v, want %v", profile, k%64, k/64, !on, on)
+		}
+	}
+}
+
+// assertFlagRules checks VF after instructions which set it as a flag.
+func assertFlagRules(t *testing.T, profile string, chip *Chip8, instruction Instruction) {
+	t.Helper()
+	vf := chip.Registers[0xf]
+	switch instruction.Op {
+	case OpAddRegisters, OpSub, OpSubReverse, OpShiftRight, OpShiftLeft, OpDraw:
+		if vf > 1 {
+			t.Fatalf("%s: got VF 0x%02X after %04X, want 0 or 1", profile, vf, instruction.Opcode)
+		}
+	case OpOr, OpAnd, OpXor:
+		if !chip.Quirks.KeepVF && vf != 0 {
+			t.Fatalf("%s: got VF 0x%02X after %04X, want 0", profile, vf, instruction.Opcode)
+		}
+	}
+}
+
+// runFuzzed runs execute for every quirk profile and fails if it panics.
+func runFuzzed(t *testing.T, input fuzzInput, execute func(t *testing.T, profile string, chip *Chip8, reference *referenceChip)) {
+	for _, profile := range QuirkProfileNames() {
+		chip, reference := newFuzzChips(input, QuirkProfiles[profile])
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					t.Fatalf("%s: didn't expect a panic executing %04X, got %v", profile, input.opcode, err)
+				}
+			}()
+			execute(t, profile, chip, reference)
+		}()
+	}
+}
+
+func FuzzEmulate(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, opcode uint16, registers []byte, index, pc uint16, stack []byte, timers, keys uint16, memory, screen []byte, seed int64) {
+		input := fuzzInput{opcode, registers, index, pc, stack, timers, keys, memory, screen, seed}
+		runFuzzed(t, input, func(t *testing.T, profile string, chip *Chip8, reference *referenceChip) {
+			high, low := byte(opcode>>8), byte(opcode)
+			emulator := Emulator{EmulatorStore: chip}
+			emulator.Emulate(high, low)
+			reference.execute(high, low, reference.pc)
+
+			assertMatchesReference(t, profile, chip, reference)
+			assertFlagRules(t, profile, chip, Decode(opcode))
+
+			// Emulate leaves moving to the next instruction to Step unless the instruction jumps
+			got := chip.Pc
+			if kind := high >> 4; kind != 0x1 && kind != 0x2 && kind != 0xb {
+				got += 2
+			}
+			if got%4096 != reference.pc%4096 {
+				t.Fatalf("%s: got pc 0x%03X after %04X, want 0x%03X", profile, got%4096, opcode, reference.pc%4096)
+			}
+		})
+	})
+}
+
+func FuzzStep(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, opcode uint16, registers []byte, index, pc uint16, stack []byte, timers, keys uint16, memory, screen []byte, seed int64) {
+		input := fuzzInput{opcode, registers, index, pc, stack, timers, keys, memory, screen, seed}
+		runFuzzed(t, input, func(t *testing.T, profile string, chip *Chip8, reference *referenceChip) {
+			first, second := chip.Step()
+			reference.step()
+
+			assertMatchesReference(t, profile, chip, reference)
+			assertFlagRules(t, profile, chip, DecodeBytes(first, second))
+			if int(chip.Pc) >= len(chip.Memory) || chip.Pc != reference.pc {
+				t.Fatalf("%s: got pc 0x%X after %02X%02X, want 0x%03X", profile, chip.Pc, first, second, reference.pc)
+			}
+		})
+	})
+}
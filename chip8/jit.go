@@ -0,0 +1,250 @@
+package chip8
+
+// maxBlockLength limits the number of instructions compiled into one block.
+const maxBlockLength = 32
+
+// compiledStep executes one instruction of a block with operands bound when it was compiled.
+type compiledStep func(c *Chip8)
+
+// compiledBlock is a sequence of instructions executed one after another. Only the last instruction
+// can change pc or write memory, the other ones expect pc to be moved to end after the block.
+// Unconditional jumps don't end blocks, the block continues at their target.
+type compiledBlock struct {
+	steps []compiledStep
+	// addresses of the instructions, pc is set from them if the block is stopped in the middle
+	addresses []uint16
+	// end is the address where execution continues after a block which isn't terminated
+	end uint16
+	// terminated is true if the last instruction sets pc itself
+	terminated bool
+}
+
+// CompiledEngine compiles basic blocks of the program into chains of closures, so instructions
+// are neither fetched nor decoded while running. Addresses written by the program after they
+// were compiled are executed by Step from then on. Like CachedEngine it uses Step while any
+// hook of Step is set.
+type CompiledEngine struct {
+	chip   *Chip8
+	blocks []*compiledBlock
+	// compiled marks addresses which are a part of some block
+	compiled []bool
+	// selfModifying marks code written by the program, it's never compiled again
+	selfModifying []bool
+}
+
+// NewCompiledEngine creates engine for chip, the chip tells it when memory is written.
+func NewCompiledEngine(chip *Chip8) *CompiledEngine {
+	e := &CompiledEngine{
+		chip:          chip,
+		blocks:        make([]*compiledBlock, len(chip.Memory)),
+		compiled:      make([]bool, len(chip.Memory)),
+		selfModifying: make([]bool, len(chip.Memory)),
+	}
+	chip.code = e
+	return e
+}
+
+func (e *CompiledEngine) invalidate(address uint16) {
+	if int(address) >= len(e.compiled) || !e.compiled[address] {
+		return
+	}
+	// blocks overlap, so it's simpler to compile everything again without the written address
+	e.selfModifying[address] = true
+	clear(e.blocks)
+	clear(e.compiled)
+}
+
+func (e *CompiledEngine) invalidateAll() {
+	clear(e.blocks)
+	clear(e.compiled)
+	clear(e.selfModifying)
+}
+
+// Run executes the given number of instructions or fewer if one of them waits for the display.
+func (e *CompiledEngine) Run(instructions int) {
+	c := e.chip
+	if c.instrumented() {
+		for i := 0; i < instructions && !c.waitingForDisplay; i++ {
+			c.Step()
+		}
+		return
+	}
+
+	for executed := 0; executed < instructions && !c.waitingForDisplay; {
+		pc := c.Pc
+		if int(pc)+1 >= len(c.Memory) {
+			// the instruction wraps around the end of memory
+			c.Step()
+			executed++
+			continue
+		}
+		block := e.blocks[pc]
+		if block == nil {
+			// blocks never contain self-modifying code, so it's checked only before compiling
+			if e.selfModifying[pc] || e.selfModifying[pc+1] {
+				interpret(c, c.DecodeBytes(c.Memory[pc], c.Memory[pc+1]))
+				executed++
+				continue
+			}
+			block = e.compile(pc)
+			e.blocks[pc] = block
+		}
+
+		steps := block.steps
+		if left := instructions - executed; len(steps) > left {
+			// the block continues in the next call
+			for _, step := range steps[:left] {
+				step(c)
+			}
+			c.Pc = block.addresses[left]
+			return
+		}
+
+		for _, step := range steps {
+			step(c)
+		}
+		if !block.terminated {
+			c.Pc = block.end
+		}
+		if int(c.Pc) >= len(c.Memory) {
+			c.Pc = c.memoryAddress(c.Pc)
+		}
+		executed += len(steps)
+	}
+}
+
+// compile compiles instructions starting at pc until an instruction which sets pc or writes memory.
+func (e *CompiledEngine) compile(pc uint16) *compiledBlock {
+	memory := e.chip.Memory
+	block := &compiledBlock{}
+	address := pc
+	for len(block.steps) < maxBlockLength && int(address)+1 < len(memory) {
+		if e.selfModifying[address] || e.selfModifying[address+1] {
+			break
+		}
+		instruction := e.chip.DecodeBytes(memory[address], memory[address+1])
+		step, terminates := compileInstruction(address, instruction)
+		block.steps = append(block.steps, step)
+		block.addresses = append(block.addresses, address)
+		e.compiled[address] = true
+		e.compiled[address+1] = true
+
+		switch {
+		case instruction.Op == OpJump:
+			address = instruction.NNN
+		case terminates:
+			block.terminated = true
+			return block
+		default:
+			address += 2
+		}
+	}
+	block.end = address
+
+	// memory ends at pc, Step crashes the same way as without the engine
+	if len(block.steps) == 0 {
+		block.steps = append(block.steps, func(c *Chip8) { c.Step() })
+		block.addresses = append(block.addresses, pc)
+		block.terminated = true
+	}
+	return block
+}
+
+// compileInstruction returns closure executing instruction at address. terminates is true if
+// the instruction has to end the block because it sets pc or writes memory.
+func compileInstruction(address uint16, instruction Instruction) (step compiledStep, terminates bool) {
+	x, y, nn, nnn := instruction.X, instruction.Y, instruction.NN, instruction.NNN
+	switch instruction.Op {
+	case OpInvalid, OpSys:
+		return func(c *Chip8) {}, false
+	case OpLoad:
+		return func(c *Chip8) { c.Registers[x] = nn }, false
+	case OpAdd:
+		return func(c *Chip8) { c.Registers[x] += nn }, false
+	case OpMove:
+		return func(c *Chip8) { c.Registers[x] = c.Registers[y] }, false
+	case OpLoadIndex:
+		return func(c *Chip8) { c.I = nnn }, false
+	case OpAddIndex:
+		return func(c *Chip8) { c.I += uint16(c.Registers[x]) }, false
+	case OpLoadDelayTimer:
+		return func(c *Chip8) { c.Registers[x] = c.Timers[0] }, false
+	case OpSetDelayTimer:
+		return func(c *Chip8) { c.Timers[0] = c.Registers[x] }, false
+	case OpSetSoundTimer:
+		return func(c *Chip8) { c.Timers[1] = c.Registers[x] }, false
+	case OpJump:
+		// compile continues at the target, the jump sets pc only if it ends the block
+		return func(c *Chip8) { c.Pc = nnn }, false
+	case OpCall:
+		return func(c *Chip8) {
+			c.Stack = append(c.Stack, address)
+			c.Pc = nnn
+		}, true
+	case OpSkipEqual:
+		return func(c *Chip8) {
+			c.Pc = address + 2
+			if c.Registers[x] == nn {
+				c.Pc += 2
+			}
+		}, true
+	case OpSkipNotEqual:
+		return func(c *Chip8) {
+			c.Pc = address + 2
+			if c.Registers[x] != nn {
+				c.Pc += 2
+			}
+		}, true
+	case OpSkipEqualRegisters:
+		return func(c *Chip8) {
+			c.Pc = address + 2
+			if c.Registers[x] == c.Registers[y] {
+				c.Pc += 2
+			}
+		}, true
+	case OpSkipKeyPressed:
+		return func(c *Chip8) {
+			c.Pc = address + 2
+			if c.keypad().IsKeyDown(c.Registers[x]) {
+				c.Pc += 2
+			}
+		}, true
+	case OpSkipKeyNotPressed:
+		return func(c *Chip8) {
+			c.Pc = address + 2
+			if !c.keypad().IsKeyDown(c.Registers[x]) {
+				c.Pc += 2
+			}
+		}, true
+	case OpWaitKey:
+		// pc stays at the instruction until a key is pressed
+		return func(c *Chip8) {
+			c.Pc = address
+			if key, ok := c.keypad().KeyPressed(); ok {
+				c.Registers[x] = key
+				c.Pc += 2
+			}
+		}, true
+	case OpSkipNotEqualRegisters:
+		return func(c *Chip8) {
+			c.Pc = address + 2
+			if c.Registers[x] != c.Registers[y] {
+				c.Pc += 2
+			}
+		}, true
+	}
+
+	handler := handlers[instruction.Op]
+	switch instruction.Op {
+	case OpReturn, OpStoreBCD, OpStoreRegisters, OpDraw:
+		// drawing ends the block because it can wait for the display
+		return func(c *Chip8) {
+			c.Pc = address
+			handler(c, instruction)
+			c.Pc += 2
+		}, true
+	case OpJumpOffset:
+		return func(c *Chip8) { handler(c, instruction) }, true
+	}
+	return func(c *Chip8) { handler(c, instruction) }, false
+}
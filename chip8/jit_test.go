@@ -0,0 +1,72 @@
+package chip8
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// runFrameRecovered runs a frame and reports whether the program crashed, e.g. by reading
+// outside of memory.
+func runFrameRecovered(chip *Chip8, tickrate int) (crashed bool) {
+	defer func() {
+		if recover() != nil {
+			crashed = true
+		}
+	}()
+	chip.RunFrame(tickrate)
+	return false
+}
+
+func TestCompiledEngine(t *testing.T) {
+	t.Run("Random programs run the same as with Step", func(t *testing.T) {
+		random := rand.New(rand.NewSource(42))
+		for i := 0; i < 200; i++ {
+			program := make([]byte, 128)
+			random.Read(program)
+			want := newEngineChip(program)
+			got := newEngineChip(program)
+			got.Engine = NewCompiledEngine(got)
+
+			for frame := 0; frame < 20; frame++ {
+				wantCrashed := runFrameRecovered(want, 13)
+				gotCrashed := runFrameRecovered(got, 13)
+				if gotCrashed != wantCrashed {
+					t.Fatalf("got crash %v, want %v in frame %d of program %X", gotCrashed, wantCrashed, frame, program)
+				}
+				if wantCrashed {
+					break
+				}
+				assertSameState(t, got, want)
+			}
+		}
+	})
+
+	t.Run("Self-modifying code is executed by Step", func(t *testing.T) {
+		// 0x200: V0 = 0x61, 0x202: V1 = 0x07, 0x204: call 0x20c, 0x206: I = 0x20c,
+		// 0x208: store V0-V1 over 0x20c, 0x20a: jump to 0x204, 0x20c: V1 += 1, 0x20e: return
+		program := []byte{0x60, 0x61, 0x61, 0x07, 0x22, 0x0c, 0xa2, 0x0c, 0xf1, 0x55, 0x12, 0x04, 0x71, 0x01, 0x00, 0xee}
+		chip := newEngineChip(program)
+		engine := NewCompiledEngine(chip)
+
+		engine.Run(9)
+		chip.Registers[1] = 0
+		engine.Run(1)
+
+		if chip.Registers[1] != 0x08 || !engine.selfModifying[0x20c] || engine.selfModifying[0x206] {
+			t.Errorf("got V1 0x%02X, want 0x08 and only 0x20c marked as self-modifying", chip.Registers[1])
+		}
+	})
+
+	t.Run("Loading a program forgets compiled blocks", func(t *testing.T) {
+		chip := newEngineChip([]byte{0x60, 0x01, 0x12, 0x00})
+		engine := NewCompiledEngine(chip)
+		engine.Run(2)
+
+		chip.LoadProgram([]byte{0x60, 0x02, 0x12, 0x00})
+		engine.Run(1)
+
+		if chip.Registers[0] != 0x02 {
+			t.Errorf("got V0 0x%02X, want 0x02", chip.Registers[0])
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package chip8
+
+import rl "github.com/gen2brain/raylib-go/raylib"
+
+// Keypad reports the state of the 16 keys of the hexadecimal keypad.
+type Keypad interface {
+	// IsKeyDown reports whether key is held down.
+	IsKeyDown(key byte) bool
+	// KeyPressed returns key that was pressed since the last call, ok is false if no key was pressed.
+	KeyPressed() (key byte, ok bool)
+}
+
+var keymap = map[byte]int32{
+	0x1: rl.KeyOne,
+	0x2: rl.KeyTwo,
+	0x3: rl.KeyThree,
+	0xc: rl.KeyFour,
+	0x4: rl.KeyQ,
+	0x5: rl.KeyW,
+	0x6: rl.KeyE,
+	0xd: rl.KeyR,
+	0x7: rl.KeyA,
+	0x8: rl.KeyS,
+	0x9: rl.KeyD,
+	0xe: rl.KeyF,
+	0xa: rl.KeyZ,
+	0x0: rl.KeyX,
+	0xb: rl.KeyC,
+	0xf: rl.KeyV,
+}
+
+// RaylibKeypad reads keys from the raylib window using the left side of the keyboard (1-4, Q-R, A-F, Z-V).
+type RaylibKeypad struct{}
+
+func (RaylibKeypad) IsKeyDown(key byte) bool {
+	return rl.IsKeyDown(keymap[key])
+}
+
+func (RaylibKeypad) KeyPressed() (byte, bool) {
+	// skip keys which are not on the keypad
+	for value := rl.GetKeyPressed(); value != 0; value = rl.GetKeyPressed() {
+		if key, ok := getKeyFromKeymap(value); ok {
+			return key, true
+		}
+	}
+	return 0, false
+}
+
+func getKeyFromKeymap(value int32) (byte, bool) {
+	for k, v := range keymap {
+		if v == value {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// keypad returns Keypad of the chip, chips created without NewChip8 use the keyboard of the window.
+func (c *Chip8) keypad() Keypad {
+	if c.Keypad == nil {
+		return RaylibKeypad{}
+	}
+	return c.Keypad
+}
@@ -0,0 +1,116 @@
+package chip8
+
+// MemoryAccess describes a read or write of Memory made by an instruction.
+type MemoryAccess struct {
+	Address uint16
+	// Value is the byte which was read or written.
+	Value byte
+	// Old is the value overwritten by a write.
+	Old   byte
+	Write bool
+}
+
+// MemoryObserver is notified about every access of Memory made by instructions, e.g. to show
+// recently written bytes or to stop when a byte changes.
+type MemoryObserver interface {
+	MemoryAccessed(access MemoryAccess)
+}
+
+// ReadMemory returns the byte at address, addresses outside of Memory read as 0.
+// Unlike instructions it doesn't notify the MemoryObserver.
+func (c *Chip8) ReadMemory(address uint16) byte {
+	if int(address) >= len(c.Memory) {
+		return 0
+	}
+	return c.Memory[address]
+}
+
+// WriteMemory changes the byte at address, e.g. from a memory editor. It returns false if
+// address is outside of Memory. Unlike instructions it doesn't notify the MemoryObserver.
+func (c *Chip8) WriteMemory(address uint16, value byte) bool {
+	if int(address) >= len(c.Memory) {
+		return false
+	}
+	c.Memory[address] = value
+	if c.code != nil {
+		c.code.invalidate(address)
+	}
+	return true
+}
+
+// memoryAddress wraps address around the end of Memory.
+func (c *Chip8) memoryAddress(address uint16) uint16 {
+	if int(address) < len(c.Memory) {
+		return address
+	}
+	return uint16(int(address) % len(c.Memory))
+}
+
+// readMemory is used by instructions reading from Memory, addresses wrap around its end.
+func (c *Chip8) readMemory(address uint16) byte {
+	address = c.memoryAddress(address)
+	value := c.Memory[address]
+	if c.MemoryObserver != nil {
+		c.MemoryObserver.MemoryAccessed(MemoryAccess{Address: address, Value: value})
+	}
+	if c.Watchpoints != nil {
+		c.Watchpoints.check(WatchMemory, address, false, value, value)
+	}
+	if c.Profiler != nil {
+		c.Profiler.accessed(address)
+	}
+	return value
+}
+
+// writeMemory is used by instructions writing to Memory, addresses wrap around its end.
+func (c *Chip8) writeMemory(address uint16, value byte) {
+	address = c.memoryAddress(address)
+	old := c.Memory[address]
+	c.Memory[address] = value
+	if c.code != nil {
+		c.code.invalidate(address)
+	}
+	if c.Undo != nil {
+		c.Undo.recordMemory(address, old)
+	}
+	if c.MemoryObserver != nil {
+		c.MemoryObserver.MemoryAccessed(MemoryAccess{Address: address, Value: value, Old: old, Write: true})
+	}
+	if c.Watchpoints != nil {
+		c.Watchpoints.check(WatchMemory, address, true, old, value)
+	}
+	if c.CodeTracker != nil {
+		c.CodeTracker.written(address, old, value)
+	}
+	if c.Profiler != nil {
+		c.Profiler.accessed(address)
+	}
+}
+
+// MemoryWriteTracker remembers in which frame every byte of memory was last written.
+type MemoryWriteTracker struct {
+	frame   int
+	written map[uint16]int
+}
+
+func NewMemoryWriteTracker() *MemoryWriteTracker {
+	return &MemoryWriteTracker{written: map[uint16]int{}}
+}
+
+func (t *MemoryWriteTracker) MemoryAccessed(access MemoryAccess) {
+	if access.Write {
+		t.written[access.Address] = t.frame
+	}
+}
+
+// NextFrame should be called after every frame, ages of written bytes are counted in frames.
+func (t *MemoryWriteTracker) NextFrame() {
+	t.frame++
+}
+
+// Age returns how many frames ago the byte at address was written.
+// It returns false if the byte wasn't written since the tracker was created.
+func (t *MemoryWriteTracker) Age(address uint16) (int, bool) {
+	frame, ok := t.written[address]
+	return t.frame - frame, ok
+}
@@ -0,0 +1,75 @@
+package chip8
+
+import (
+	"reflect"
+	"testing"
+)
+
+// accessLog records every access of memory.
+type accessLog []MemoryAccess
+
+func (l *accessLog) MemoryAccessed(access MemoryAccess) {
+	*l = append(*l, access)
+}
+
+func TestMemoryAccess(t *testing.T) {
+	t.Run("ReadMemory and WriteMemory outside of memory", func(t *testing.T) {
+		chip := &Chip8{Memory: make([]byte, 16)}
+
+		if chip.WriteMemory(0x10, 1) {
+			t.Errorf("didn't expect write outside of memory to succeed")
+		}
+		if got := chip.ReadMemory(0x10); got != 0 {
+			t.Errorf("got %v, want 0", got)
+		}
+		if !chip.WriteMemory(0xf, 7) || chip.ReadMemory(0xf) != 7 {
+			t.Errorf("expected byte at 0xf to be written")
+		}
+	})
+
+	t.Run("Instructions notify the observer", func(t *testing.T) {
+		chip := &Chip8{Memory: make([]byte, 4096), Registers: make([]byte, 16), I: 0x300}
+		chip.Registers[0x0] = 123
+		chip.Memory[0x300] = 9
+		var log accessLog
+		chip.MemoryObserver = &log
+		emulator := Emulator{EmulatorStore: chip}
+
+		emulator.Emulate(0xf0, 0x33)
+
+		want := accessLog{
+			{Address: 0x302, Value: 3, Write: true},
+			{Address: 0x301, Value: 2, Write: true},
+			{Address: 0x300, Value: 1, Old: 9, Write: true},
+		}
+		if !reflect.DeepEqual(log, want) {
+			t.Errorf("got %v, want %v", log, want)
+		}
+
+		log = nil
+		emulator.Emulate(0xf1, 0x65)
+
+		want = accessLog{{Address: 0x300, Value: 1}, {Address: 0x301, Value: 2}}
+		if !reflect.DeepEqual(log, want) {
+			t.Errorf("got %v, want %v", log, want)
+		}
+	})
+
+	t.Run("Tracker counts frames since the last write", func(t *testing.T) {
+		chip := &Chip8{Memory: make([]byte, 4096), Registers: make([]byte, 16), I: 0x300}
+		tracker := NewMemoryWriteTracker()
+		chip.MemoryObserver = tracker
+		emulator := Emulator{EmulatorStore: chip}
+
+		emulator.Emulate(0xf1, 0x55)
+		tracker.NextFrame()
+		tracker.NextFrame()
+
+		if age, ok := tracker.Age(0x301); !ok || age != 2 {
+			t.Errorf("got age %v %v, want 2", age, ok)
+		}
+		if _, ok := tracker.Age(0x302); ok {
+			t.Errorf("didn't expect 0x302 to be written")
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package chip8
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Movie is a recording of the keypad state for every frame.
+//
+// It's stored as text, each line contains the frame number and keys held
+// from that frame on written as hexadecimal digits, "." means no keys:
+//
+//	# start the game and move right for half a second
+//	30 5
+//	32 .
+//	60 6
+//	90 .
+type Movie struct {
+	changes []movieChange
+}
+
+type movieChange struct {
+	frame int
+	keys  uint16
+}
+
+// ParseMovie reads a movie from r.
+func ParseMovie(r io.Reader) (*Movie, error) {
+	movie := &Movie{}
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("movie line %d: expected frame and keys, got %q", line, text)
+		}
+
+		frame, err := strconv.Atoi(fields[0])
+		if err != nil || frame < 0 {
+			return nil, fmt.Errorf("movie line %d: invalid frame %q", line, fields[0])
+		}
+
+		var keys uint16
+		if fields[1] != "." {
+			for _, r := range fields[1] {
+				key, err := strconv.ParseUint(string(r), 16, 8)
+				if err != nil {
+					return nil, fmt.Errorf("movie line %d: invalid key %q", line, r)
+				}
+				keys |= 1 << key
+			}
+		}
+
+		movie.changes = append(movie.changes, movieChange{frame: frame, keys: keys})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(movie.changes, func(i, j int) bool {
+		return movie.changes[i].frame < movie.changes[j].frame
+	})
+
+	return movie, nil
+}
+
+// LoadMovie reads a movie from the file at path.
+func LoadMovie(path string) (*Movie, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseMovie(file)
+}
+
+// Keys returns keys held during frame, bit n is set if key n is held.
+func (m *Movie) Keys(frame int) uint16 {
+	var keys uint16
+	for _, change := range m.changes {
+		if change.frame > frame {
+			break
+		}
+		keys = change.keys
+	}
+	return keys
+}
+
+// MovieKeypad is a Keypad which plays back a Movie.
+type MovieKeypad struct {
+	movie   *Movie
+	keys    uint16
+	pressed uint16
+}
+
+// NewMovieKeypad creates keypad playing movie, nil movie never presses any keys.
+func NewMovieKeypad(movie *Movie) *MovieKeypad {
+	if movie == nil {
+		movie = &Movie{}
+	}
+	return &MovieKeypad{movie: movie}
+}
+
+// SetFrame updates the held keys to the state of the movie at frame.
+func (k *MovieKeypad) SetFrame(frame int) {
+	keys := k.movie.Keys(frame)
+	k.pressed |= keys &^ k.keys
+	k.keys = keys
+}
+
+func (k *MovieKeypad) IsKeyDown(key byte) bool {
+	return k.keys&(1<<(key&0xf)) != 0
+}
+
+func (k *MovieKeypad) KeyPressed() (byte, bool) {
+	for key := byte(0); key < 16; key++ {
+		if k.pressed&(1<<key) != 0 {
+			k.pressed &^= 1 << key
+			return key, true
+		}
+	}
+	return 0, false
+}
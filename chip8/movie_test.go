@@ -0,0 +1,70 @@
+package chip8
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMovie(t *testing.T) {
+	t.Run("Keys are held from their frame until the next change", func(t *testing.T) {
+		movie, err := ParseMovie(strings.NewReader("# comment\n10 5\n\n20 4a\n30 .\n"))
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		cases := map[int]uint16{0: 0, 10: 1 << 5, 19: 1 << 5, 20: 1<<4 | 1<<0xa, 30: 0, 100: 0}
+		for frame, want := range cases {
+			if got := movie.Keys(frame); got != want {
+				t.Errorf("frame %d: got %016b, want %016b", frame, got, want)
+			}
+		}
+	})
+
+	t.Run("Return error for invalid key", func(t *testing.T) {
+		_, err := ParseMovie(strings.NewReader("10 x\n"))
+
+		if err == nil {
+			t.Fatalf("expected an error")
+		}
+	})
+}
+
+func TestMovieKeypad(t *testing.T) {
+	t.Run("Key is reported as pressed once when it goes down", func(t *testing.T) {
+		movie, _ := ParseMovie(strings.NewReader("1 7\n"))
+		keypad := NewMovieKeypad(movie)
+
+		keypad.SetFrame(0)
+		if _, ok := keypad.KeyPressed(); ok {
+			t.Fatalf("didn't expect a key press at frame 0")
+		}
+
+		keypad.SetFrame(1)
+		key, ok := keypad.KeyPressed()
+		if !ok || key != 7 {
+			t.Errorf("got %x %v, want 7 true", key, ok)
+		}
+		if !keypad.IsKeyDown(7) {
+			t.Errorf("expected key 7 to be down")
+		}
+
+		keypad.SetFrame(2)
+		if _, ok := keypad.KeyPressed(); ok {
+			t.Errorf("didn't expect a second key press")
+		}
+	})
+
+	t.Run("Instruction 0xe09e skips when movie holds the key", func(t *testing.T) {
+		movie, _ := ParseMovie(strings.NewReader("0 3\n"))
+		keypad := NewMovieKeypad(movie)
+		keypad.SetFrame(0)
+		chip := NewChip8()
+		chip.Keypad = keypad
+		chip.Registers[0x0] = 3
+		emulator := Emulator{EmulatorStore: chip}
+
+		emulator.Emulate(0xe0, 0x9e)
+
+		AssertAddress(t, chip.Pc, 0x202)
+	})
+}
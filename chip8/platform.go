@@ -0,0 +1,207 @@
+package chip8
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strings"
+)
+
+// hiresEntry is the address where the HIRES CHIP-8 interpreter continues after the 0x1260
+// jump at the start of programs.
+const hiresEntry = 0x2C0
+
+// Layout is where a platform keeps the program and the font in memory.
+type Layout struct {
+	// LoadAddress is where LoadProgram copies programs.
+	LoadAddress uint16
+	// Entry is the address of the first instruction.
+	Entry uint16
+	// MemorySize is the number of bytes of Memory.
+	MemorySize int
+	// FontAddress is where LoadFont copies Font, FX29 points I at digits there.
+	FontAddress uint16
+}
+
+// Layout returns the memory layout of the platform. Platforms based on the COSMAC VIP load
+// programs at 0x200 after the interpreter, the ETI-660 at 0x600 with the font at 0x050,
+// where most later interpreters keep it.
+func (p Platform) Layout() Layout {
+	switch p {
+	case PlatformETI660:
+		return Layout{LoadAddress: 0x600, Entry: 0x600, MemorySize: 4096, FontAddress: 0x050}
+	case PlatformChip8Small:
+		return Layout{LoadAddress: 0x200, Entry: 0x200, MemorySize: 2048}
+	}
+	return Layout{LoadAddress: 0x200, Entry: 0x200, MemorySize: 4096}
+}
+
+// ColorForeground contains the foreground colours of the VP-590 colour board of CHIP-8X,
+// selected by the low 3 bits of VY of BXY0 and BXYN.
+var ColorForeground = []color.RGBA{
+	{0x00, 0x00, 0x00, 0xff}, // black
+	{0xff, 0x00, 0x00, 0xff}, // red
+	{0x00, 0x00, 0xff, 0xff}, // blue
+	{0xff, 0x00, 0xff, 0xff}, // violet
+	{0x00, 0xff, 0x00, 0xff}, // green
+	{0xff, 0xff, 0x00, 0xff}, // yellow
+	{0x00, 0xff, 0xff, 0xff}, // aqua
+	{0xff, 0xff, 0xff, 0xff}, // white
+}
+
+// ColorBackground contains the background colours of the VP-590 in the order 02A0 cycles them.
+var ColorBackground = []color.RGBA{
+	{0x00, 0x00, 0x80, 0xff}, // blue
+	{0x00, 0x00, 0x00, 0xff}, // black
+	{0x00, 0x80, 0x00, 0xff}, // green
+	{0x80, 0x00, 0x00, 0xff}, // red
+}
+
+// defaultForeground is the colour of all zones after reset, red.
+const defaultForeground = 1
+
+// ColorMap is the colour memory of CHIP-8X. Every zone is 8 pixels wide and 1 pixel high.
+type ColorMap struct {
+	// Background is the index of the background colour in ColorBackground.
+	Background int
+	// Foreground contains the index in ColorForeground of every zone, row by row.
+	Foreground []byte
+	// columns is the number of zones in a row
+	columns int
+}
+
+// newColorMap creates colours for a screen of width x height pixels.
+func newColorMap(width, height int) *ColorMap {
+	columns := (width + 7) / 8
+	colors := &ColorMap{Foreground: make([]byte, columns*height), columns: columns}
+	for i := range colors.Foreground {
+		colors.Foreground[i] = defaultForeground
+	}
+	return colors
+}
+
+// ParsePlatform returns the platform called name, e.g. "hires".
+func ParsePlatform(name string) (Platform, error) {
+	platform, ok := Platforms[name]
+	if !ok {
+		return PlatformChip8, fmt.Errorf("unknown platform %q, known are %s", name, strings.Join(PlatformNames(), ", "))
+	}
+	return platform, nil
+}
+
+// PlatformNames returns sorted names of Platforms.
+func PlatformNames() []string {
+	names := make([]string, 0, len(Platforms))
+	for name := range Platforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetPlatform selects the platform used to decode instructions and moves pc to its entry point.
+// Memory is reallocated if the platform has a different size, then the font and the program
+// have to be loaded after it. CHIP-8X gets colours, HIRES CHIP-8 switches to 64x64 pixels if
+// the loaded program starts with the jump to 0x260.
+func (c *Chip8) SetPlatform(platform Platform) {
+	c.Platform = platform
+	layout := platform.Layout()
+	if len(c.Memory) != layout.MemorySize {
+		c.Memory = make([]byte, layout.MemorySize)
+	}
+	c.Pc = layout.Entry
+	c.Colors = nil
+	if platform == PlatformChip8X {
+		c.Colors = newColorMap(int(c.Width), int(c.Height))
+	}
+	c.enterHires()
+	if c.code != nil {
+		c.code.invalidateAll()
+	}
+}
+
+// enterHires starts programs of HIRES CHIP-8. They begin with 0x1260, which jumps into the
+// part of the interpreter loaded at 0x260 on the original platform. It's emulated by switching
+// to 64x64 pixels and continuing at 0x2C0, where the program itself starts.
+func (c *Chip8) enterHires() {
+	if c.Platform != PlatformHires {
+		return
+	}
+	entry := c.Platform.Layout().Entry
+	if c.Memory[entry] == 0x12 && c.Memory[entry+1] == 0x60 {
+		if c.Width != 64 || c.Height != 64 {
+			c.SetResolution(64, 64)
+		}
+		if c.Pc == entry {
+			c.Pc = hiresEntry
+		}
+	} else if c.Width != 64 || c.Height != 32 {
+		c.SetResolution(64, 32)
+	}
+}
+
+// ScreenColors returns the screen as it's displayed. CHIP-8X colours on pixels by the colour of
+// their zone and off pixels by the background, other platforms return Screen.
+func (c *Chip8) ScreenColors() []color.RGBA {
+	if c.Colors == nil {
+		return c.Screen
+	}
+	width := int(c.Width)
+	pixels := make([]color.RGBA, len(c.Screen))
+	for i, pixel := range c.Screen {
+		if pixel == c.PrimaryColor {
+			pixels[i] = ColorForeground[c.Colors.Foreground[i/width*c.Colors.columns+i%width/8]]
+		} else {
+			pixels[i] = ColorBackground[c.Colors.Background]
+		}
+	}
+	return pixels
+}
+
+// CycleBackground changes the background to the next colour.
+func (c *Chip8) CycleBackground() {
+	if c.Colors == nil {
+		return
+	}
+	c.Colors.Background = (c.Colors.Background + 1) % len(ColorBackground)
+}
+
+// ColorZones sets the colour VY of zones 8 pixels wide and 4 pixels high. The low nibble of VX
+// is the left zone and the high nibble the number of zones right of it, V(X+1) selects rows of
+// zones the same way.
+func (c *Chip8) ColorZones(instruction Instruction) {
+	if c.Colors == nil {
+		return
+	}
+	horizontal := c.Registers[instruction.X]
+	vertical := c.Registers[(instruction.X+1)&0xf]
+	color := c.Registers[instruction.Y] & 7
+
+	for column := int(horizontal & 0xf); column <= int(horizontal&0xf+horizontal>>4); column++ {
+		for row := int(vertical&0xf) * 4; row < int(vertical&0xf+vertical>>4+1)*4; row++ {
+			c.setZoneColor(column, row, color)
+		}
+	}
+}
+
+// ColorRows sets the colour VY of N rows from V(X+1) in the column of zones containing VX.
+func (c *Chip8) ColorRows(instruction Instruction) {
+	if c.Colors == nil {
+		return
+	}
+	column := int(c.Registers[instruction.X]) / 8
+	top := int(c.Registers[(instruction.X+1)&0xf])
+	color := c.Registers[instruction.Y] & 7
+
+	for row := top; row < top+int(instruction.N); row++ {
+		c.setZoneColor(column, row, color)
+	}
+}
+
+// setZoneColor sets the colour of a zone, zones outside of the screen are ignored.
+func (c *Chip8) setZoneColor(column, row int, color byte) {
+	if column >= c.Colors.columns || row >= int(c.Height) {
+		return
+	}
+	c.Colors.Foreground[row*c.Colors.columns+column] = color
+}
@@ -0,0 +1,213 @@
+package chip8
+
+import "testing"
+
+func TestPlatform(t *testing.T) {
+	t.Run("Return platform of a known name", func(t *testing.T) {
+		got, err := ParsePlatform("chip8x")
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		if got != PlatformChip8X {
+			t.Errorf("got %v, want %v", got, PlatformChip8X)
+		}
+	})
+
+	t.Run("Return error for unknown platform", func(t *testing.T) {
+		_, err := ParsePlatform("schip")
+
+		if err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+
+	t.Run("ETI-660 programs are loaded and start at 0x600", func(t *testing.T) {
+		chip := NewChip8()
+		chip.SetPlatform(PlatformETI660)
+
+		chip.LoadProgram([]byte{0x61, 0x05})
+		chip.Step()
+
+		if chip.Memory[0x600] != 0x61 || chip.Registers[1] != 5 || chip.Pc != 0x602 {
+			t.Errorf("got 0x%02X at 0x600, V1 %d, pc 0x%X, want 0x61, 5, 0x602", chip.Memory[0x600], chip.Registers[1], chip.Pc)
+		}
+	})
+
+	t.Run("Memory of 2 KB systems is reallocated", func(t *testing.T) {
+		chip := NewChip8()
+
+		chip.SetPlatform(PlatformChip8Small)
+
+		if len(chip.Memory) != 2048 || chip.Pc != 0x200 {
+			t.Errorf("got %d bytes with pc 0x%X, want 2048 with pc 0x200", len(chip.Memory), chip.Pc)
+		}
+	})
+
+	t.Run("FX29 points I at the digit loaded by LoadFont", func(t *testing.T) {
+		chip := NewChip8()
+		chip.SetPlatform(PlatformETI660)
+		chip.LoadFont()
+		chip.Registers[2] = 0xa
+
+		chip.SetLocationOfSprite(Decode(0xf229))
+
+		if chip.I != 0x050+50 || chip.Memory[0] != 0 {
+			t.Errorf("got I 0x%X and 0x%02X at 0x000, want the font at 0x050", chip.I, chip.Memory[0])
+		}
+		if got := chip.Memory[chip.I : chip.I+5]; string(got) != string(Font[50:55]) {
+			t.Errorf("got %X, want %X", got, Font[50:55])
+		}
+	})
+
+	t.Run("HIRES program starting with the jump to 0x260 runs at 0x2C0 on 64x64 pixels", func(t *testing.T) {
+		chip := NewChip8()
+		chip.SetPlatform(PlatformHires)
+
+		chip.LoadProgram([]byte{0x12, 0x60})
+
+		if chip.Width != 64 || chip.Height != 64 || len(chip.Screen) != 64*64 || chip.Pc != 0x2c0 {
+			t.Errorf("got %dx%d with pc 0x%X, want 64x64 with pc 0x2C0", chip.Width, chip.Height, chip.Pc)
+		}
+	})
+
+	t.Run("HIRES platform can be selected after loading the program", func(t *testing.T) {
+		chip := NewChip8()
+		chip.LoadProgram([]byte{0x12, 0x60})
+
+		chip.SetPlatform(PlatformHires)
+
+		if chip.Height != 64 || chip.Pc != 0x2c0 {
+			t.Errorf("got height %d with pc 0x%X, want 64 with pc 0x2C0", chip.Height, chip.Pc)
+		}
+	})
+
+	t.Run("Other programs of the HIRES platform keep 64x32 pixels", func(t *testing.T) {
+		chip := NewChip8()
+		chip.SetPlatform(PlatformHires)
+		chip.LoadProgram([]byte{0x12, 0x60})
+
+		chip.Pc = 0x200
+		chip.LoadProgram([]byte{0x12, 0x00})
+
+		if chip.Height != 32 || chip.Pc != 0x200 {
+			t.Errorf("got height %d with pc 0x%X, want 32 with pc 0x200", chip.Height, chip.Pc)
+		}
+	})
+
+	t.Run("Jump to 0x260 is an ordinary jump on CHIP-8", func(t *testing.T) {
+		chip := NewChip8()
+
+		chip.LoadProgram([]byte{0x12, 0x60})
+
+		if chip.Height != 32 || chip.Pc != 0x200 {
+			t.Errorf("got height %d with pc 0x%X, want 32 with pc 0x200", chip.Height, chip.Pc)
+		}
+	})
+
+	t.Run("0230 clears the screen of HIRES CHIP-8", func(t *testing.T) {
+		chip := NewChip8()
+		chip.SetPlatform(PlatformHires)
+		chip.LoadProgram([]byte{0x12, 0x60})
+		chip.Memory[0x2c0], chip.Memory[0x2c1] = 0x02, 0x30
+		chip.Screen[64*63] = chip.PrimaryColor
+
+		chip.Step()
+
+		if chip.Screen[64*63] != chip.SecondaryColor {
+			t.Errorf("expected the last row to be cleared")
+		}
+	})
+}
+
+func TestColors(t *testing.T) {
+	// newColorChip creates CHIP-8X with one pixel on in every zone
+	newColorChip := func() *Chip8 {
+		chip := NewChip8()
+		chip.SetPlatform(PlatformChip8X)
+		for i := 0; i < len(chip.Screen); i += 8 {
+			chip.Screen[i] = chip.PrimaryColor
+		}
+		return chip
+	}
+	// execute executes opcode decoded for CHIP-8X
+	execute := func(chip *Chip8, opcode uint16) {
+		emulator := Emulator{EmulatorStore: chip}
+		emulator.Execute(DecodeFor(PlatformChip8X, opcode))
+	}
+	// zoneColor returns the displayed colour of the zone
+	zoneColor := func(chip *Chip8, column, row int) int {
+		got := chip.ScreenColors()[column*8+row*64]
+		for i, color := range ColorForeground {
+			if got == color {
+				return i
+			}
+		}
+		return -1
+	}
+
+	t.Run("Zones are red on a blue background after reset", func(t *testing.T) {
+		chip := newColorChip()
+
+		colors := chip.ScreenColors()
+
+		if colors[0] != ColorForeground[1] || colors[1] != ColorBackground[0] {
+			t.Errorf("got %v and %v, want red and blue", colors[0], colors[1])
+		}
+	})
+
+	t.Run("02A0 cycles the background colours", func(t *testing.T) {
+		chip := newColorChip()
+
+		for i := 0; i < 5; i++ {
+			execute(chip, 0x02a0)
+		}
+
+		if got := chip.ScreenColors()[1]; got != ColorBackground[1] {
+			t.Errorf("got %v, want %v", got, ColorBackground[1])
+		}
+	})
+
+	t.Run("BXY0 colours zones of 8x4 pixels", func(t *testing.T) {
+		chip := newColorChip()
+		// 2 zones from column 1 and 1 zone from row 3 in yellow
+		chip.Registers[0], chip.Registers[1], chip.Registers[2] = 0x11, 0x03, 5
+
+		execute(chip, 0xb020)
+
+		for _, zone := range [][2]int{{1, 12}, {2, 15}} {
+			if got := zoneColor(chip, zone[0], zone[1]); got != 5 {
+				t.Errorf("got colour %d of zone %v, want 5", got, zone)
+			}
+		}
+		for _, zone := range [][2]int{{0, 12}, {3, 12}, {1, 11}, {1, 16}} {
+			if got := zoneColor(chip, zone[0], zone[1]); got != defaultForeground {
+				t.Errorf("got colour %d of zone %v, want %d", got, zone, defaultForeground)
+			}
+		}
+	})
+
+	t.Run("BXYN colours N rows of a column", func(t *testing.T) {
+		chip := newColorChip()
+		// column of x 20, rows 30 and 31 are visible, the rest is clipped
+		chip.Registers[4], chip.Registers[5], chip.Registers[6] = 20, 30, 6
+
+		execute(chip, 0xb465)
+
+		if got := zoneColor(chip, 2, 30); got != 6 {
+			t.Errorf("got colour %d, want 6", got)
+		}
+		if got := zoneColor(chip, 2, 29); got != defaultForeground {
+			t.Errorf("got colour %d, want %d", got, defaultForeground)
+		}
+	})
+
+	t.Run("Screen of other platforms is displayed as it is", func(t *testing.T) {
+		chip := NewChip8()
+		chip.ClearScreen()
+
+		if got := chip.ScreenColors(); &got[0] != &chip.Screen[0] {
+			t.Errorf("expected Screen to be returned")
+		}
+	})
+}
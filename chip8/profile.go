@@ -0,0 +1,264 @@
+package chip8
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// MemoryKind tells how a byte of memory was used by the program, kinds can be combined.
+type MemoryKind byte
+
+const (
+	// KindCode bytes were executed as a part of an instruction.
+	KindCode MemoryKind = 1 << iota
+	// KindData bytes were read or written by instructions, e.g. sprites drawn by DXYN.
+	KindData
+)
+
+// Profiler counts executions of instructions at every address and of every opcode.
+type Profiler struct {
+	hits         []uint64
+	kinds        []MemoryKind
+	opcodes      []uint64
+	instructions uint64
+}
+
+// NewProfiler creates profiler for memory of memorySize bytes.
+func NewProfiler(memorySize int) *Profiler {
+	return &Profiler{
+		hits:    make([]uint64, memorySize),
+		kinds:   make([]MemoryKind, memorySize),
+		opcodes: make([]uint64, 0x10000),
+	}
+}
+
+// Reset forgets everything counted so far, e.g. when another program is loaded.
+func (p *Profiler) Reset() {
+	clear(p.hits)
+	clear(p.kinds)
+	clear(p.opcodes)
+	p.instructions = 0
+}
+
+// executed counts instruction at pc, it's called by Step.
+func (p *Profiler) executed(pc uint16, firstByte, secondByte byte) {
+	p.instructions++
+	p.opcodes[uint16(firstByte)<<8|uint16(secondByte)]++
+	if int(pc)+1 < len(p.hits) {
+		p.hits[pc]++
+		p.kinds[pc] |= KindCode
+		p.kinds[pc+1] |= KindCode
+	}
+}
+
+// accessed marks byte read or written by an instruction as data.
+func (p *Profiler) accessed(address uint16) {
+	if int(address) < len(p.kinds) {
+		p.kinds[address] |= KindData
+	}
+}
+
+// Instructions returns the number of executed instructions.
+func (p *Profiler) Instructions() uint64 {
+	return p.instructions
+}
+
+// Hits returns how many times the instruction at address was executed.
+func (p *Profiler) Hits(address uint16) uint64 {
+	if int(address) >= len(p.hits) {
+		return 0
+	}
+	return p.hits[address]
+}
+
+// MaxHits returns the number of executions of the hottest address.
+func (p *Profiler) MaxHits() uint64 {
+	var max uint64
+	for _, hits := range p.hits {
+		if hits > max {
+			max = hits
+		}
+	}
+	return max
+}
+
+// Kind returns how the byte at address was used, 0 means it wasn't touched by the program.
+func (p *Profiler) Kind(address uint16) MemoryKind {
+	if int(address) >= len(p.kinds) {
+		return 0
+	}
+	return p.kinds[address]
+}
+
+// AddressProfile is the number of executions of the instruction at an address.
+type AddressProfile struct {
+	Address  uint16 `json:"address"`
+	Opcode   uint16 `json:"opcode"`
+	Mnemonic string `json:"mnemonic"`
+	Hits     uint64 `json:"hits"`
+}
+
+// ClassProfile is the number of executions of instructions of one class, e.g. "8XY4".
+type ClassProfile struct {
+	Class string `json:"class"`
+	Hits  uint64 `json:"hits"`
+}
+
+// ProfileReport summarizes a profile, addresses and classes are sorted from the most executed.
+type ProfileReport struct {
+	Instructions uint64           `json:"instructions"`
+	CodeBytes    int              `json:"codeBytes"`
+	DataBytes    int              `json:"dataBytes"`
+	Addresses    []AddressProfile `json:"addresses"`
+	Classes      []ClassProfile   `json:"classes"`
+	// SelfModifications are writes into executed code, they're filled from a CodeTracker.
+	SelfModifications []CodeModification `json:"selfModifications,omitempty"`
+}
+
+// Report creates report of the profile of a program of platform, memory is used to find opcodes
+// at executed addresses.
+func (p *Profiler) Report(platform Platform, memory []byte) ProfileReport {
+	report := ProfileReport{Instructions: p.instructions, Addresses: []AddressProfile{}, Classes: []ClassProfile{}}
+
+	for address, hits := range p.hits {
+		if p.kinds[address]&KindCode != 0 {
+			report.CodeBytes++
+		}
+		if p.kinds[address]&KindData != 0 {
+			report.DataBytes++
+		}
+		if hits == 0 || address+1 >= len(memory) {
+			continue
+		}
+		opcode := uint16(memory[address])<<8 | uint16(memory[address+1])
+		report.Addresses = append(report.Addresses, AddressProfile{
+			Address:  uint16(address),
+			Opcode:   opcode,
+			Mnemonic: DisassembleInstruction(DecodeFor(platform, opcode)),
+			Hits:     hits,
+		})
+	}
+
+	classes := map[string]uint64{}
+	for opcode, hits := range p.opcodes {
+		if hits > 0 {
+			classes[OpcodeClass(platform, byte(opcode>>8), byte(opcode))] += hits
+		}
+	}
+	for class, hits := range classes {
+		report.Classes = append(report.Classes, ClassProfile{Class: class, Hits: hits})
+	}
+
+	sort.SliceStable(report.Addresses, func(i, j int) bool {
+		return report.Addresses[i].Hits > report.Addresses[j].Hits
+	})
+	sort.Slice(report.Classes, func(i, j int) bool {
+		if report.Classes[i].Hits != report.Classes[j].Hits {
+			return report.Classes[i].Hits > report.Classes[j].Hits
+		}
+		return report.Classes[i].Class < report.Classes[j].Class
+	})
+	return report
+}
+
+// WriteText writes the report as a table, only the top hottest addresses are listed (0 lists all).
+func (r ProfileReport) WriteText(w io.Writer, top int) error {
+	fmt.Fprintf(w, "instructions: %d\ncode bytes:   %d\ndata bytes:   %d\n\n", r.Instructions, r.CodeBytes, r.DataBytes)
+
+	fmt.Fprintln(w, "address  opcode  hits        %      instruction")
+	addresses := r.Addresses
+	if top > 0 && len(addresses) > top {
+		addresses = addresses[:top]
+	}
+	for _, address := range addresses {
+		fmt.Fprintf(w, "0x%03X    %04X    %-10d  %5.1f  %s\n",
+			address.Address, address.Opcode, address.Hits, r.percent(address.Hits), address.Mnemonic)
+	}
+
+	fmt.Fprintln(w, "\nclass  hits        %")
+	for _, class := range r.Classes {
+		if _, err := fmt.Fprintf(w, "%-5s  %-10d  %5.1f\n", class.Class, class.Hits, r.percent(class.Hits)); err != nil {
+			return err
+		}
+	}
+
+	if len(r.SelfModifications) > 0 {
+		fmt.Fprintln(w, "\nself-modifying code:")
+		return WriteModifications(w, r.SelfModifications)
+	}
+	return nil
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r ProfileReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+func (r ProfileReport) percent(hits uint64) float64 {
+	if r.Instructions == 0 {
+		return 0
+	}
+	return float64(hits) * 100 / float64(r.Instructions)
+}
+
+// WriteAnnotatedDisassembly disassembles memory of a program of platform from start to end with
+// the number of executions of every instruction. Code is followed by instructions, bytes used only as data are written as
+// DB and untouched bytes are skipped. Instructions overwritten by the program are marked if
+// tracker isn't nil, the disassembly shows only their current bytes.
+func (p *Profiler) WriteAnnotatedDisassembly(w io.Writer, platform Platform, memory []byte, start, end int, tracker *CodeTracker) error {
+	if end > len(memory) {
+		end = len(memory)
+	}
+
+	skipped := false
+	for address := start; address < end; {
+		var err error
+		kind := p.Kind(uint16(address))
+		switch {
+		case kind&KindCode != 0 && address+1 < end:
+			marker := ""
+			if tracker != nil && (tracker.Modified(uint16(address)) || tracker.Modified(uint16(address+1))) {
+				marker = " ; modified by the program"
+			} else if kind&KindData != 0 {
+				marker = " ; also used as data"
+			}
+			opcode := uint16(memory[address])<<8 | uint16(memory[address+1])
+			_, err = fmt.Fprintf(w, "0x%03X  %04X  %10d  %s%s\n", address, opcode,
+				p.hits[address], DisassembleInstruction(DecodeFor(platform, opcode)), marker)
+			address += 2
+			skipped = false
+		case kind&KindData != 0:
+			_, err = fmt.Fprintf(w, "0x%03X  %02X    %10s  DB 0x%02X\n", address, memory[address], "", memory[address])
+			address++
+			skipped = false
+		default:
+			// a run of untouched bytes is written as one line
+			if !skipped {
+				_, err = fmt.Fprintln(w, "...")
+			}
+			skipped = true
+			address++
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OpcodeClass returns the pattern of the instruction of platform in the usual notation, e.g. "8XY4"
+// or "FX33". Bytes which are not a valid instruction return "????".
+func OpcodeClass(platform Platform, firstByte, secondByte byte) string {
+	instruction := DecodeFor(platform, uint16(firstByte)<<8|uint16(secondByte))
+	// an operation can have a different pattern on other platforms, e.g. 0230 clears HIRES screen
+	for _, pattern := range opcodePatterns {
+		if pattern.op == instruction.Op && pattern.platform == instruction.Platform {
+			return pattern.pattern
+		}
+	}
+	return instruction.Op.String()
+}
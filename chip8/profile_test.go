@@ -0,0 +1,113 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestProfiler(t *testing.T) {
+	// 0x200: I = 0x20a, 0x202: V0 += 1, 0x204: draw sprite at 0x20a, 0x206: jump to 0x202
+	// 0x20a: sprite
+	program := []byte{0xa2, 0x0a, 0x70, 0x01, 0xd0, 0x01, 0x12, 0x02, 0x00, 0x00, 0xff}
+
+	newProfiledChip := func(steps int) *Chip8 {
+		chip := NewChip8()
+		chip.Keypad = NewMovieKeypad(nil)
+		chip.LoadProgram(program)
+		chip.Profiler = NewProfiler(len(chip.Memory))
+		for i := 0; i < steps; i++ {
+			chip.Step()
+		}
+		return chip
+	}
+
+	t.Run("Hits and kinds of addresses", func(t *testing.T) {
+		chip := newProfiledChip(10)
+		profiler := chip.Profiler
+
+		if profiler.Hits(0x200) != 1 || profiler.Hits(0x202) != 3 || profiler.MaxHits() != 3 {
+			t.Errorf("got hits %d and %d with max %d, want 1 and 3 with max 3",
+				profiler.Hits(0x200), profiler.Hits(0x202), profiler.MaxHits())
+		}
+		if profiler.Kind(0x203) != KindCode || profiler.Kind(0x20a) != KindData || profiler.Kind(0x208) != 0 {
+			t.Errorf("got kinds %d, %d and %d, want code, data and nothing",
+				profiler.Kind(0x203), profiler.Kind(0x20a), profiler.Kind(0x208))
+		}
+	})
+
+	t.Run("Report is sorted by hits", func(t *testing.T) {
+		chip := newProfiledChip(10)
+
+		report := chip.Profiler.Report(PlatformChip8, chip.Memory)
+
+		if report.Instructions != 10 || report.CodeBytes != 8 || report.DataBytes != 1 {
+			t.Errorf("got %d instructions, %d code and %d data bytes, want 10, 8 and 1",
+				report.Instructions, report.CodeBytes, report.DataBytes)
+		}
+		if report.Addresses[0].Address != 0x202 || report.Addresses[0].Mnemonic != "ADD V0, 0x01" {
+			t.Errorf("got hottest address %+v, want ADD V0, 0x01 at 0x202", report.Addresses[0])
+		}
+		// classes with the same number of hits are sorted by name
+		if report.Classes[0].Class != "1NNN" || report.Classes[0].Hits != 3 {
+			t.Errorf("got hottest class %+v, want 1NNN with 3 hits", report.Classes[0])
+		}
+
+		var buffer bytes.Buffer
+		if err := report.WriteJSON(&buffer); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		var decoded ProfileReport
+		if err := json.Unmarshal(buffer.Bytes(), &decoded); err != nil || decoded.Instructions != 10 {
+			t.Errorf("got %+v %v, want the same report", decoded, err)
+		}
+	})
+
+	t.Run("Annotated disassembly", func(t *testing.T) {
+		chip := newProfiledChip(10)
+
+		var buffer bytes.Buffer
+		if err := chip.Profiler.WriteAnnotatedDisassembly(&buffer, PlatformChip8, chip.Memory, 0x200, 0x20c, nil); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		want := strings.Join([]string{
+			"0x200  A20A           1  LD I, 0x20A",
+			"0x202  7001           3  ADD V0, 0x01",
+			"0x204  D001           3  DRW V0, V0, 1",
+			"0x206  1202           3  JP 0x202",
+			"...",
+			"0x20A  FF                DB 0xFF",
+			"...",
+		}, "\n") + "\n"
+		if got := buffer.String(); got != want {
+			t.Errorf("got\n%s\nwant\n%s", got, want)
+		}
+	})
+}
+
+func TestOpcodeClass(t *testing.T) {
+	cases := map[uint16]string{
+		0x00e0: "00E0", 0x0123: "0NNN", 0x8124: "8XY4", 0x812e: "8XYE", 0x8128: "????",
+		0xf333: "FX33", 0xe19e: "EX9E", 0x5121: "????", 0xd125: "DXYN",
+	}
+	for opcode, want := range cases {
+		if got := OpcodeClass(PlatformChip8, byte(opcode>>8), byte(opcode)); got != want {
+			t.Errorf("%04X: got %s, want %s", opcode, got, want)
+		}
+	}
+
+	// colour instructions of CHIP-8X and the clear screen of HIRES have their own patterns
+	platformCases := map[Platform]map[uint16]string{
+		PlatformChip8X: {0xb120: "BXY0", 0xb123: "BXYN", 0x02a0: "02A0", 0xb300: "BXY0"},
+		PlatformHires:  {0x0230: "0230", 0xb300: "BNNN"},
+	}
+	for platform, cases := range platformCases {
+		for opcode, want := range cases {
+			if got := OpcodeClass(platform, byte(opcode>>8), byte(opcode)); got != want {
+				t.Errorf("%04X on %s: got %s, want %s", opcode, platform, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package chip8
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Quirks selects behaviour of instructions which differs between interpreters. The zero value is
+// the original CHIP-8 interpreter of the COSMAC VIP without waiting for the display.
+type Quirks struct {
+	// KeepVF leaves VF unchanged by 8XY1, 8XY2 and 8XY3 instead of resetting it to 0.
+	KeepVF bool
+	// KeepIndex leaves I unchanged by FX55 and FX65 instead of increasing it by X+1.
+	KeepIndex bool
+	// ShiftVx makes 8XY6 and 8XYE shift Vx in place instead of shifting Vy into Vx.
+	ShiftVx bool
+	// JumpVx makes BXNN jump to XNN plus VX instead of NNN plus V0.
+	JumpVx bool
+	// WrapSprites makes DXYN wrap pixels past the edges of the screen to the opposite side
+	// instead of clipping them.
+	WrapSprites bool
+	// DisplayWait makes DXYN wait for the vertical blank like the COSMAC VIP, no instruction is
+	// executed after it until the next tick, so at most one sprite is drawn per tick.
+	DisplayWait bool
+}
+
+var (
+	QuirksChip8     = Quirks{}
+	QuirksVIP       = Quirks{DisplayWait: true}
+	QuirksSuperChip = Quirks{KeepVF: true, KeepIndex: true, ShiftVx: true, JumpVx: true}
+	QuirksXOChip    = Quirks{KeepVF: true, WrapSprites: true}
+)
+
+// QuirkProfiles contains quirks of known interpreters by their name.
+var QuirkProfiles = map[string]Quirks{
+	"chip8":  QuirksChip8,
+	"vip":    QuirksVIP,
+	"schip":  QuirksSuperChip,
+	"xochip": QuirksXOChip,
+}
+
+// QuirkProfile returns quirks of the interpreter called name, e.g. "schip".
+func QuirkProfile(name string) (Quirks, error) {
+	quirks, ok := QuirkProfiles[name]
+	if !ok {
+		return Quirks{}, fmt.Errorf("unknown quirk profile %q, known are %s", name, strings.Join(QuirkProfileNames(), ", "))
+	}
+	return quirks, nil
+}
+
+// QuirkProfileNames returns sorted names of QuirkProfiles.
+func QuirkProfileNames() []string {
+	names := make([]string, 0, len(QuirkProfiles))
+	for name := range QuirkProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,36 @@
+package chip8
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuirkProfile(t *testing.T) {
+	t.Run("Return quirks of a known profile", func(t *testing.T) {
+		got, err := QuirkProfile("schip")
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		if got != QuirksSuperChip {
+			t.Errorf("got %+v, want %+v", got, QuirksSuperChip)
+		}
+	})
+
+	t.Run("Return error for unknown profile", func(t *testing.T) {
+		_, err := QuirkProfile("vip2")
+
+		if err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+
+	t.Run("Names are sorted", func(t *testing.T) {
+		got := QuirkProfileNames()
+		want := []string{"chip8", "schip", "vip", "xochip"}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+}
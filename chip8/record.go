@@ -0,0 +1,153 @@
+package chip8
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Recorder collects frames of the screen and encodes them as an animated GIF.
+type Recorder struct {
+	// MaxFrames limits the length of the recording, 0 means no limit.
+	MaxFrames int
+	Scale     int
+	Tint      color.RGBA
+	images    []*image.Paletted
+	delays    []int
+	frames    int
+}
+
+// NewRecorder creates recorder which stops after maxFrames frames (60 frames is one second).
+func NewRecorder(scale int, tint color.RGBA, maxFrames int) *Recorder {
+	if scale < 1 {
+		scale = 1
+	}
+	return &Recorder{MaxFrames: maxFrames, Scale: scale, Tint: tint}
+}
+
+// AddFrame appends the current screen of the chip to the recording.
+// It returns false if the recording reached MaxFrames and the frame was not added.
+func (r *Recorder) AddFrame(c *Chip8) bool {
+	if r.Full() {
+		return false
+	}
+
+	frame := r.paletted(c)
+	delay := gifDelay(r.frames)
+	r.frames++
+
+	// screen often doesn't change between frames so extend the previous one instead of adding a copy
+	last := len(r.images) - 1
+	if last >= 0 && r.images[last].Bounds() == frame.Bounds() &&
+		r.images[last].Palette[0] == frame.Palette[0] && string(r.images[last].Pix) == string(frame.Pix) {
+		r.delays[last] += delay
+		return true
+	}
+	// viewers can't show the previous frame as short as it is, so it's replaced by this one
+	if last >= 0 && r.delays[last] < minGIFDelay {
+		r.images[last] = frame
+		r.delays[last] += delay
+		return true
+	}
+
+	r.images = append(r.images, frame)
+	r.delays = append(r.delays, delay)
+	return true
+}
+
+// Full reports whether the recording reached MaxFrames.
+func (r *Recorder) Full() bool {
+	return r.MaxFrames > 0 && r.frames >= r.MaxFrames
+}
+
+// Frames returns the number of recorded frames.
+func (r *Recorder) Frames() int {
+	return r.frames
+}
+
+// WriteGIF encodes the recording as an animated GIF and writes it to w.
+func (r *Recorder) WriteGIF(w io.Writer) error {
+	delays := append([]int(nil), r.delays...)
+	if last := len(delays) - 1; last >= 0 && delays[last] < minGIFDelay {
+		delays[last] = minGIFDelay
+	}
+	animation := &gif.GIF{Image: r.images, Delay: delays}
+
+	// frames can have different sizes if the resolution changed during the recording
+	for _, img := range r.images {
+		animation.Config.Width = max(animation.Config.Width, img.Bounds().Dx())
+		animation.Config.Height = max(animation.Config.Height, img.Bounds().Dy())
+	}
+
+	return gif.EncodeAll(w, animation)
+}
+
+// SaveGIF writes the recording to a GIF file in dir named after rom and the current time.
+// It returns path of the created file.
+func (r *Recorder) SaveGIF(dir, rom string) (string, error) {
+	path := filepath.Join(dir, RecordingFilename(rom, time.Now()))
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err := r.WriteGIF(file); err != nil {
+		return "", err
+	}
+
+	return path, file.Close()
+}
+
+// RecordingFilename returns name of the recording of rom started at time t, e.g. snake_20240131-154502.gif.
+func RecordingFilename(rom string, t time.Time) string {
+	return captureFilename(rom, t, ".gif")
+}
+
+// paletted converts the screen to an image with two colors, or the colors of CHIP-8X.
+func (r *Recorder) paletted(c *Chip8) *image.Paletted {
+	palette := color.Palette{tintColor(c.SecondaryColor, r.Tint), tintColor(c.PrimaryColor, r.Tint)}
+	if c.Colors != nil {
+		// the background is followed by all foreground colours of CHIP-8X
+		palette = color.Palette{tintColor(ColorBackground[c.Colors.Background], r.Tint)}
+		for _, foreground := range ColorForeground {
+			palette = append(palette, tintColor(foreground, r.Tint))
+		}
+	}
+	img := image.NewPaletted(image.Rect(0, 0, int(c.Width)*r.Scale, int(c.Height)*r.Scale), palette)
+
+	for y := 0; y < int(c.Height); y++ {
+		for x := 0; x < int(c.Width); x++ {
+			if c.Screen[x+y*int(c.Width)] != c.PrimaryColor {
+				continue
+			}
+			index := uint8(1)
+			if c.Colors != nil {
+				index += c.Colors.Foreground[y*c.Colors.columns+x/8]
+			}
+			for sy := 0; sy < r.Scale; sy++ {
+				for sx := 0; sx < r.Scale; sx++ {
+					img.SetColorIndex(x*r.Scale+sx, y*r.Scale+sy, index)
+				}
+			}
+		}
+	}
+
+	return img
+}
+
+// minGIFDelay is the shortest delay in hundredths of a second played as it is, browsers and most
+// viewers play shorter delays as 10.
+const minGIFDelay = 2
+
+// gifDelay returns delay of the frame in hundredths of a second. GIF can't store 1/60 s exactly
+// so delays alternate between 1 and 2 to keep the animation in sync with 60 Hz, AddFrame merges
+// frames of 1 with the next one.
+func gifDelay(frame int) int {
+	return ((frame+1)*100+30)/60 - (frame*100+30)/60
+}
@@ -0,0 +1,116 @@
+package chip8
+
+import (
+	"bytes"
+	"image/gif"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Run("Delays of 60 frames add up to one second", func(t *testing.T) {
+		chip := NewChip8()
+		recorder := NewRecorder(1, rl.White, 0)
+
+		for i := 0; i < 60; i++ {
+			chip.ClearScreen()
+			chip.Screen[i] = chip.PrimaryColor
+			recorder.AddFrame(chip)
+		}
+
+		total := 0
+		for _, delay := range recorder.delays {
+			total += delay
+		}
+
+		if total != 100 {
+			t.Errorf("got %d hundredths of a second, want 100", total)
+		}
+	})
+
+	t.Run("Every frame lasts at least 2 hundredths of a second", func(t *testing.T) {
+		chip := NewChip8()
+		recorder := NewRecorder(1, rl.White, 0)
+
+		for i := 0; i < 61; i++ {
+			chip.ClearScreen()
+			chip.Screen[i] = chip.PrimaryColor
+			recorder.AddFrame(chip)
+		}
+		var buf bytes.Buffer
+		if err := recorder.WriteGIF(&buf); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		animation, err := gif.DecodeAll(&buf)
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		for i, delay := range animation.Delay {
+			if delay < 2 {
+				t.Errorf("got delay %d of frame %d, want at least 2", delay, i)
+			}
+		}
+	})
+
+	t.Run("Identical frames are merged into one", func(t *testing.T) {
+		chip := NewChip8()
+		chip.ClearScreen()
+		recorder := NewRecorder(1, rl.White, 0)
+
+		for i := 0; i < 3; i++ {
+			recorder.AddFrame(chip)
+		}
+
+		if len(recorder.images) != 1 || recorder.Frames() != 3 {
+			t.Errorf("got %d images and %d frames, want 1 and 3", len(recorder.images), recorder.Frames())
+		}
+	})
+
+	t.Run("Recording stops at MaxFrames", func(t *testing.T) {
+		chip := NewChip8()
+		chip.ClearScreen()
+		recorder := NewRecorder(1, rl.White, 2)
+
+		recorder.AddFrame(chip)
+		recorder.AddFrame(chip)
+
+		if recorder.AddFrame(chip) {
+			t.Errorf("expected frame over the limit to be rejected")
+		}
+		if !recorder.Full() {
+			t.Errorf("expected recorder to be full")
+		}
+	})
+
+	t.Run("Encoded GIF has scaled frames", func(t *testing.T) {
+		chip := NewChip8()
+		chip.ClearScreen()
+		recorder := NewRecorder(2, rl.Red, 0)
+		recorder.AddFrame(chip)
+		chip.Screen[0] = chip.PrimaryColor
+		recorder.AddFrame(chip)
+
+		var buf bytes.Buffer
+		if err := recorder.WriteGIF(&buf); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		animation, err := gif.DecodeAll(&buf)
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		if len(animation.Image) != 2 {
+			t.Fatalf("got %d frames, want 2", len(animation.Image))
+		}
+		if animation.Config.Width != 128 || animation.Config.Height != 64 {
+			t.Errorf("got %dx%d, want 128x64", animation.Config.Width, animation.Config.Height)
+		}
+		r, g, b, _ := animation.Image[1].At(1, 1).RGBA()
+		if r>>8 != 0xe6 || g>>8 != 0x29 || b>>8 != 0x37 {
+			t.Errorf("got %x %x %x, want red", r>>8, g>>8, b>>8)
+		}
+	})
+}
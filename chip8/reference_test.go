@@ -0,0 +1,230 @@
+package chip8
+
+import "math/rand"
+
+// referenceChip is a second implementation of CHIP-8 used to check Chip8 by fuzzing. It's written
+// directly from the specification, instructions are selected by nibbles without the decoder and
+// the screen contains booleans, so it shares no code with Chip8.
+type referenceChip struct {
+	memory [4096]byte
+	v      [16]byte
+	i      uint16
+	pc     uint16
+	stack  []uint16
+	delay  byte
+	sound  byte
+	screen [64 * 32]bool
+	keys   uint16
+	random *rand.Rand
+	quirks Quirks
+}
+
+func (r *referenceChip) read(address uint16) byte {
+	return r.memory[address%uint16(len(r.memory))]
+}
+
+func (r *referenceChip) write(address uint16, value byte) {
+	r.memory[address%uint16(len(r.memory))] = value
+}
+
+func (r *referenceChip) keyDown(key byte) bool {
+	return key < 16 && r.keys&(1<<key) != 0
+}
+
+// step executes the instruction at pc.
+func (r *referenceChip) step() {
+	pc := r.pc % uint16(len(r.memory))
+	r.execute(r.read(pc), r.read(pc+1), pc)
+	r.pc %= uint16(len(r.memory))
+}
+
+// execute executes the instruction in two bytes as if it was at pc, pc is moved to the next
+// instruction unless the instruction jumps.
+func (r *referenceChip) execute(high, low byte, pc uint16) {
+	x, y, n := high&0xf, low>>4, low&0xf
+	nnn := uint16(x)<<8 | uint16(low)
+	vx, vy := r.v[x], r.v[y]
+	r.pc = pc + 2
+
+	switch high >> 4 {
+	case 0x0:
+		if x == 0 && low == 0xe0 {
+			r.screen = [64 * 32]bool{}
+		}
+		if x == 0 && low == 0xee && len(r.stack) > 0 {
+			// the stack contains the address of the call
+			r.pc = r.stack[len(r.stack)-1] + 2
+			r.stack = r.stack[:len(r.stack)-1]
+		}
+	case 0x1:
+		r.pc = nnn
+	case 0x2:
+		r.stack = append(r.stack, pc)
+		r.pc = nnn
+	case 0x3:
+		if vx == low {
+			r.pc += 2
+		}
+	case 0x4:
+		if vx != low {
+			r.pc += 2
+		}
+	case 0x5:
+		if n == 0 && vx == vy {
+			r.pc += 2
+		}
+	case 0x6:
+		r.v[x] = low
+	case 0x7:
+		r.v[x] += low
+	case 0x8:
+		r.arithmetic(x, y, n)
+	case 0x9:
+		if n == 0 && vx != vy {
+			r.pc += 2
+		}
+	case 0xa:
+		r.i = nnn
+	case 0xb:
+		if r.quirks.JumpVx {
+			r.pc = nnn + uint16(vx)
+		} else {
+			r.pc = nnn + uint16(r.v[0])
+		}
+	case 0xc:
+		r.v[x] = byte(r.random.Intn(256)) & low
+	case 0xd:
+		r.draw(vx, vy, n)
+	case 0xe:
+		if low == 0x9e && r.keyDown(vx) || low == 0xa1 && !r.keyDown(vx) {
+			r.pc += 2
+		}
+	case 0xf:
+		r.misc(x, low, pc)
+	}
+}
+
+func (r *referenceChip) arithmetic(x, y, n byte) {
+	vx, vy := r.v[x], r.v[y]
+	shifted := vy
+	if r.quirks.ShiftVx {
+		shifted = vx
+	}
+
+	// the flag is written after the result, so it wins when x is F
+	var flag byte
+	switch n {
+	case 0x0:
+		r.v[x] = vy
+		return
+	case 0x1, 0x2, 0x3:
+		switch n {
+		case 0x1:
+			r.v[x] = vx | vy
+		case 0x2:
+			r.v[x] = vx & vy
+		case 0x3:
+			r.v[x] = vx ^ vy
+		}
+		if !r.quirks.KeepVF {
+			r.v[0xf] = 0
+		}
+		return
+	case 0x4:
+		sum := int(vx) + int(vy)
+		r.v[x] = byte(sum)
+		if sum > 0xff {
+			flag = 1
+		}
+	case 0x5:
+		r.v[x] = vx - vy
+		if vx >= vy {
+			flag = 1
+		}
+	case 0x6:
+		r.v[x] = shifted >> 1
+		flag = shifted & 1
+	case 0x7:
+		r.v[x] = vy - vx
+		if vy >= vx {
+			flag = 1
+		}
+	case 0xe:
+		r.v[x] = shifted << 1
+		flag = shifted >> 7
+	default:
+		return
+	}
+	r.v[0xf] = flag
+}
+
+// draw xors n rows of the sprite at I onto the screen, pixels outside of the screen are clipped
+// or wrapped around it with WrapSprites.
+func (r *referenceChip) draw(vx, vy, n byte) {
+	left, top := int(vx)%64, int(vy)%32
+	collision := false
+	for row := 0; row < int(n); row++ {
+		sprite := r.read(r.i + uint16(row))
+		for column := 0; column < 8; column++ {
+			x, y := left+column, top+row
+			if r.quirks.WrapSprites {
+				x, y = x%64, y%32
+			}
+			if x >= 64 || y >= 32 || sprite&(0x80>>column) == 0 {
+				continue
+			}
+			if r.screen[y*64+x] {
+				collision = true
+			}
+			r.screen[y*64+x] = !r.screen[y*64+x]
+		}
+	}
+	r.v[0xf] = 0
+	if collision {
+		r.v[0xf] = 1
+	}
+}
+
+func (r *referenceChip) misc(x, low byte, pc uint16) {
+	vx := r.v[x]
+	switch low {
+	case 0x07:
+		r.v[x] = r.delay
+	case 0x0a:
+		key := byte(0)
+		for key < 16 && !r.keyDown(key) {
+			key++
+		}
+		if key < 16 {
+			r.v[x] = key
+		} else {
+			r.pc = pc
+		}
+	case 0x15:
+		r.delay = vx
+	case 0x18:
+		r.sound = vx
+	case 0x1e:
+		r.i += uint16(vx)
+	case 0x29:
+		r.i = uint16(vx%16) * 5
+	case 0x33:
+		r.write(r.i, vx/100)
+		r.write(r.i+1, vx/10%10)
+		r.write(r.i+2, vx%10)
+	case 0x55:
+		for k := byte(0); k <= x; k++ {
+			r.write(r.i+uint16(k), r.v[k])
+		}
+		if !r.quirks.KeepIndex {
+			r.i += uint16(x) + 1
+		}
+	case 0x65:
+		for k := byte(0); k <= x; k++ {
+			r.v[k] = r.read(r.i + uint16(k))
+		}
+		if !r.quirks.KeepIndex {
+			r.i += uint16(x) + 1
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package chip8
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Image returns the screen as an image enlarged by scale. Every pixel is multiplied by tint
+// the same way the texture is tinted when it's drawn in the window.
+func (c *Chip8) Image(scale int, tint color.RGBA) *image.RGBA {
+	if scale < 1 {
+		scale = 1
+	}
+
+	screen := c.ScreenColors()
+	img := image.NewRGBA(image.Rect(0, 0, int(c.Width)*scale, int(c.Height)*scale))
+	for y := 0; y < int(c.Height); y++ {
+		for x := 0; x < int(c.Width); x++ {
+			pixel := tintColor(screen[x+y*int(c.Width)], tint)
+
+			// fill scale x scale square for every pixel of the screen
+			for sy := 0; sy < scale; sy++ {
+				for sx := 0; sx < scale; sx++ {
+					img.SetRGBA(x*scale+sx, y*scale+sy, pixel)
+				}
+			}
+		}
+	}
+
+	return img
+}
+
+// WritePNG encodes the screen as PNG image and writes it to w.
+func (c *Chip8) WritePNG(w io.Writer, scale int, tint color.RGBA) error {
+	return png.Encode(w, c.Image(scale, tint))
+}
+
+// SaveScreenshot writes the screen to a PNG file in dir named after rom and the current time.
+// It returns path of the created file.
+func (c *Chip8) SaveScreenshot(dir, rom string, scale int, tint color.RGBA) (string, error) {
+	path := filepath.Join(dir, ScreenshotFilename(rom, time.Now()))
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err := c.WritePNG(file, scale, tint); err != nil {
+		return "", err
+	}
+
+	return path, file.Close()
+}
+
+// ScreenshotFilename returns name of the screenshot file for rom taken at time t,
+// e.g. snake_20240131-154502.png.
+func ScreenshotFilename(rom string, t time.Time) string {
+	return captureFilename(rom, t, ".png")
+}
+
+// captureFilename returns name of the file for a capture of rom made at time t with extension ext.
+func captureFilename(rom string, t time.Time, ext string) string {
+	return fmt.Sprintf("%s_%s%s", romBaseName(rom), t.Format("20060102-150405"), ext)
+}
+
+// romBaseName returns name of the rom without directories and extension.
+func romBaseName(rom string) string {
+	name := strings.TrimSpace(filepath.Base(filepath.ToSlash(rom)))
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if name == "" || name == "." {
+		return "chip8"
+	}
+	return name
+}
+
+// tintColor multiplies each channel of c by the corresponding channel of tint.
+func tintColor(c, tint color.RGBA) color.RGBA {
+	return color.RGBA{
+		R: uint8(uint16(c.R) * uint16(tint.R) / 255),
+		G: uint8(uint16(c.G) * uint16(tint.G) / 255),
+		B: uint8(uint16(c.B) * uint16(tint.B) / 255),
+		A: uint8(uint16(c.A) * uint16(tint.A) / 255),
+	}
+}
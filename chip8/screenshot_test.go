@@ -0,0 +1,91 @@
+package chip8
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestImage(t *testing.T) {
+	t.Run("Image at scale 1 has the size of the screen and tinted pixels", func(t *testing.T) {
+		chip := NewChip8()
+		chip.ClearScreen()
+		chip.Screen[1] = chip.PrimaryColor
+
+		img := chip.Image(1, rl.Red)
+
+		if img.Bounds().Dx() != 64 || img.Bounds().Dy() != 32 {
+			t.Fatalf("got size %v, want 64x32", img.Bounds())
+		}
+
+		if got := img.RGBAAt(1, 0); got != rl.Red {
+			t.Errorf("got %v, want %v", got, rl.Red)
+		}
+		if got := img.RGBAAt(0, 0); got != rl.Black {
+			t.Errorf("got %v, want %v", got, rl.Black)
+		}
+	})
+
+	t.Run("Image at scale 3 fills 3x3 square for every pixel", func(t *testing.T) {
+		chip := NewChip8()
+		chip.ClearScreen()
+		chip.Screen[65] = chip.PrimaryColor
+
+		img := chip.Image(3, rl.White)
+
+		if img.Bounds().Dx() != 64*3 || img.Bounds().Dy() != 32*3 {
+			t.Fatalf("got size %v, want 192x96", img.Bounds())
+		}
+
+		for y := 3; y < 6; y++ {
+			for x := 3; x < 6; x++ {
+				if got := img.RGBAAt(x, y); got != rl.White {
+					t.Errorf("pixel (%d, %d): got %v, want %v", x, y, got, rl.White)
+				}
+			}
+		}
+		if got := img.RGBAAt(6, 3); got != rl.Black {
+			t.Errorf("got %v, want %v", got, rl.Black)
+		}
+	})
+}
+
+func TestWritePNG(t *testing.T) {
+	t.Run("Encoded PNG decodes to the same pixels", func(t *testing.T) {
+		chip := NewChip8()
+		chip.ClearScreen()
+		chip.Screen[0] = chip.PrimaryColor
+
+		var buf bytes.Buffer
+		if err := chip.WritePNG(&buf, 2, rl.Gold); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		img, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		got := color.RGBAModel.Convert(img.At(1, 1)).(color.RGBA)
+		if got != rl.Gold {
+			t.Errorf("got %v, want %v", got, rl.Gold)
+		}
+	})
+}
+
+func TestScreenshotFilename(t *testing.T) {
+	t.Run("Filename contains name of the rom and time", func(t *testing.T) {
+		date := time.Date(2024, 1, 31, 15, 45, 2, 0, time.UTC)
+
+		got := ScreenshotFilename("games/snake.ch8", date)
+		want := "snake_20240131-154502.png"
+
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package chip8
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// CodeModification is a write of an instruction into an address which was executed before.
+// Such programs modify their own code, so they can't be disassembled statically and decoded
+// instructions can't be cached without checking for writes.
+type CodeModification struct {
+	// Pc is the address of the instruction which made the write.
+	Pc      uint16 `json:"pc"`
+	Address uint16 `json:"address"`
+	// Count is the number of writes made by the instruction at Pc to Address.
+	Count int `json:"count"`
+	// Old and Value are the bytes before and after the last write.
+	Old   byte `json:"old"`
+	Value byte `json:"value"`
+}
+
+// CodeTracker remembers executed addresses and reports writes into them.
+type CodeTracker struct {
+	executed      []bool
+	modified      []bool
+	modifications map[uint32]*CodeModification
+	// pc of the instruction being executed
+	pc uint16
+}
+
+// NewCodeTracker creates tracker for memory of memorySize bytes.
+func NewCodeTracker(memorySize int) *CodeTracker {
+	return &CodeTracker{
+		executed:      make([]bool, memorySize),
+		modified:      make([]bool, memorySize),
+		modifications: map[uint32]*CodeModification{},
+	}
+}
+
+// executing marks both bytes of the instruction at pc as code, it's called by Step.
+func (t *CodeTracker) executing(pc uint16) {
+	t.pc = pc
+	if int(pc)+1 < len(t.executed) {
+		t.executed[pc] = true
+		t.executed[pc+1] = true
+	}
+}
+
+// written records write of value into address if it contains code.
+func (t *CodeTracker) written(address uint16, old, value byte) {
+	if int(address) >= len(t.executed) || !t.executed[address] {
+		return
+	}
+	t.modified[address] = true
+
+	key := uint32(t.pc)<<16 | uint32(address)
+	modification, ok := t.modifications[key]
+	if !ok {
+		modification = &CodeModification{Pc: t.pc, Address: address}
+		t.modifications[key] = modification
+	}
+	modification.Count++
+	modification.Old = old
+	modification.Value = value
+}
+
+// Executed reports whether the byte at address was executed as a part of an instruction.
+func (t *CodeTracker) Executed(address uint16) bool {
+	return int(address) < len(t.executed) && t.executed[address]
+}
+
+// Modified reports whether the byte at address was overwritten after it was executed.
+// Disassemblers and caches of decoded instructions should not trust such addresses.
+func (t *CodeTracker) Modified(address uint16) bool {
+	return int(address) < len(t.modified) && t.modified[address]
+}
+
+// Modifications returns all writes into code sorted by the address of the writing instruction
+// and the written address.
+func (t *CodeTracker) Modifications() []CodeModification {
+	modifications := make([]CodeModification, 0, len(t.modifications))
+	for _, modification := range t.modifications {
+		modifications = append(modifications, *modification)
+	}
+	sort.Slice(modifications, func(i, j int) bool {
+		if modifications[i].Pc != modifications[j].Pc {
+			return modifications[i].Pc < modifications[j].Pc
+		}
+		return modifications[i].Address < modifications[j].Address
+	})
+	return modifications
+}
+
+// WriteModifications writes one line per instruction and modified address, e.g.
+//
+//	0x2A4 wrote 0x312 2 times, last 0x12 -> 0x34
+func WriteModifications(w io.Writer, modifications []CodeModification) error {
+	for _, m := range modifications {
+		_, err := fmt.Fprintf(w, "0x%03X wrote 0x%03X %d times, last 0x%02X -> 0x%02X\n", m.Pc, m.Address, m.Count, m.Old, m.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package chip8
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCodeTracker(t *testing.T) {
+	// 0x200: I = 0x200, 0x202: V0 = 0x12, 0x204: V1 = 0x34, 0x206: store V0-V1 over the first instruction,
+	// 0x208: I = 0x300, 0x20a: store V0-V1 as data, 0x20c: jump to 0x20c
+	program := []byte{0xa2, 0x00, 0x60, 0x12, 0x61, 0x34, 0xf1, 0x55, 0xa3, 0x00, 0xf1, 0x55, 0x12, 0x0c}
+
+	chip := NewChip8()
+	chip.Keypad = NewMovieKeypad(nil)
+	chip.LoadProgram(program)
+	tracker := NewCodeTracker(len(chip.Memory))
+	chip.CodeTracker = tracker
+	for i := 0; i < 8; i++ {
+		chip.Step()
+	}
+
+	t.Run("Writes into executed code are reported", func(t *testing.T) {
+		want := []CodeModification{
+			{Pc: 0x206, Address: 0x200, Count: 1, Old: 0xa2, Value: 0x12},
+			{Pc: 0x206, Address: 0x201, Count: 1, Old: 0x00, Value: 0x34},
+		}
+		got := tracker.Modifications()
+		if len(got) != len(want) {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("got %+v, want %+v", got[i], want[i])
+			}
+		}
+	})
+
+	t.Run("Only executed addresses are modified", func(t *testing.T) {
+		if !tracker.Modified(0x200) || !tracker.Modified(0x201) {
+			t.Errorf("expected 0x200 and 0x201 to be modified")
+		}
+		if tracker.Modified(0x300) || tracker.Executed(0x300) || tracker.Modified(0x206) {
+			t.Errorf("didn't expect 0x300 or 0x206 to be modified")
+		}
+		if !tracker.Executed(0x20d) || tracker.Modified(0xffff) {
+			t.Errorf("expected 0x20d to be executed and 0xffff to be ignored")
+		}
+	})
+
+	t.Run("Modifications are written as text", func(t *testing.T) {
+		var buffer bytes.Buffer
+		if err := WriteModifications(&buffer, tracker.Modifications()); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		want := "0x206 wrote 0x200 1 times, last 0xA2 -> 0x12\n0x206 wrote 0x201 1 times, last 0x00 -> 0x34\n"
+		if buffer.String() != want {
+			t.Errorf("got %q, want %q", buffer.String(), want)
+		}
+	})
+}
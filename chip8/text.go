@@ -0,0 +1,23 @@
+package chip8
+
+import "strings"
+
+// ScreenText returns the screen as text art with '#' for lit pixels and '.' for the rest,
+// every row of the screen ends with a new line.
+func (c *Chip8) ScreenText() string {
+	var b strings.Builder
+	b.Grow((int(c.Width) + 1) * int(c.Height))
+
+	for y := 0; y < int(c.Height); y++ {
+		for x := 0; x < int(c.Width); x++ {
+			if c.Screen[x+y*int(c.Width)] == c.PrimaryColor {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
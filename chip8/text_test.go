@@ -0,0 +1,25 @@
+package chip8
+
+import (
+	"image/color"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestScreenText(t *testing.T) {
+	t.Run("Lit pixels are drawn as # and the rest as .", func(t *testing.T) {
+		chip := &Chip8{Width: 3, Height: 2, PrimaryColor: rl.White, SecondaryColor: rl.Black}
+		chip.Screen = []color.RGBA{
+			rl.White, rl.Black, rl.Black,
+			rl.Black, rl.Black, rl.White,
+		}
+
+		got := chip.ScreenText()
+		want := "#..\n..#\n"
+
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package chip8
+
+import "time"
+
+// TimerFrequency is the number of ticks of delay and sound timers per second.
+const TimerFrequency = 60
+
+// TickDuration is the time between two ticks of the timers.
+const TickDuration = time.Second / TimerFrequency
+
+// DefaultTickrate is the number of instructions per tick used by NewChip8.
+const DefaultTickrate = 10
+
+// CycleTable contains the number of cycles taken by every operation.
+type CycleTable [OpColorRows + 1]int
+
+// VIPCycles approximates how long instructions of the original interpreter take on the
+// COSMAC VIP in microseconds. Drawing doesn't include waiting for the display.
+var VIPCycles = CycleTable{
+	OpInvalid:               100,
+	OpSys:                   100,
+	OpClearScreen:           109,
+	OpReturn:                105,
+	OpJump:                  105,
+	OpCall:                  105,
+	OpSkipEqual:             55,
+	OpSkipNotEqual:          55,
+	OpSkipEqualRegisters:    73,
+	OpLoad:                  27,
+	OpAdd:                   45,
+	OpMove:                  200,
+	OpOr:                    200,
+	OpAnd:                   200,
+	OpXor:                   200,
+	OpAddRegisters:          200,
+	OpSub:                   200,
+	OpShiftRight:            200,
+	OpSubReverse:            200,
+	OpShiftLeft:             200,
+	OpSkipNotEqualRegisters: 73,
+	OpLoadIndex:             55,
+	OpJumpOffset:            105,
+	OpRandom:                164,
+	OpDraw:                  3812,
+	OpSkipKeyPressed:        73,
+	OpSkipKeyNotPressed:     73,
+	OpLoadDelayTimer:        45,
+	OpWaitKey:               45,
+	OpSetDelayTimer:         45,
+	OpSetSoundTimer:         45,
+	OpAddIndex:              86,
+	OpLoadFont:              91,
+	OpStoreBCD:              927,
+	OpStoreRegisters:        605,
+	OpLoadRegisters:         605,
+	OpCycleBackground:       109,
+	OpColorZones:            200,
+	OpColorRows:             200,
+}
+
+// Timing is the speed of the emulated CPU. Time is counted in cycles of executed instructions,
+// so the timers tick at 60 Hz of emulated time however fast the host runs.
+type Timing struct {
+	// CyclesPerSecond is the number of cycles in one second of emulated time.
+	CyclesPerSecond int
+	// Cycles contains cycles of every operation, nil costs one cycle per instruction.
+	Cycles *CycleTable
+}
+
+// VIPTiming runs instructions about as fast as the COSMAC VIP, a cycle is a microsecond.
+var VIPTiming = Timing{CyclesPerSecond: 1000000, Cycles: &VIPCycles}
+
+// InstructionsPerSecond returns timing executing n instructions per second whatever they are.
+func InstructionsPerSecond(n int) Timing {
+	return Timing{CyclesPerSecond: n}
+}
+
+// TickrateTiming returns timing executing tickrate instructions per tick like RunFrame.
+func TickrateTiming(tickrate int) Timing {
+	return InstructionsPerSecond(tickrate * TimerFrequency)
+}
+
+// cost returns cycles of the instruction.
+func (t Timing) cost(instruction Instruction) int {
+	if t.Cycles == nil {
+		return 1
+	}
+	return t.Cycles[instruction.Op]
+}
+
+// RunTick executes instructions for one tick of emulated time according to Timing, followed by
+// one update of the timers. Instructions run while the tick has cycles left, the last one can
+// take cycles of the next tick, waiting for the display skips the rest of the tick. Engine is
+// used only if every instruction costs one cycle and onStep is nil. onStep is called with both
+// bytes of every instruction executed by Step. It returns true if the buzzer should sound
+// during the tick.
+func (c *Chip8) RunTick(onStep func(firstByte, secondByte byte)) bool {
+	// cycles of a tick aren't whole if CyclesPerSecond isn't divisible by 60
+	c.cycleFraction += c.Timing.CyclesPerSecond
+	c.cycles += c.cycleFraction / TimerFrequency
+	c.cycleFraction %= TimerFrequency
+
+	if c.Engine != nil && c.Timing.Cycles == nil && onStep == nil {
+		if c.cycles > 0 {
+			c.Engine.Run(c.cycles)
+			c.cycles = 0
+		}
+		return c.UpdateTimers()
+	}
+
+	for c.cycles > 0 && !c.waitingForDisplay {
+		firstByte, secondByte := c.Step()
+		c.cycles -= c.Timing.cost(c.DecodeBytes(firstByte, secondByte))
+		if onStep != nil {
+			onStep(firstByte, secondByte)
+		}
+	}
+	if c.waitingForDisplay && c.cycles > 0 {
+		c.cycles = 0
+	}
+	return c.UpdateTimers()
+}
+
+// Pacer converts time of the host to ticks of emulated time, so the emulation keeps its speed
+// when the window renders slower or faster than 60 frames per second.
+type Pacer struct {
+	// MaxTicks limits ticks returned at once, e.g. after the window was dragged, so the program
+	// doesn't run fast to catch up. 0 doesn't limit them.
+	MaxTicks int
+	elapsed  time.Duration
+}
+
+// Advance adds elapsed time of the host and returns the number of ticks which should be run.
+func (p *Pacer) Advance(elapsed time.Duration) int {
+	p.elapsed += elapsed
+	ticks := int(p.elapsed / TickDuration)
+	p.elapsed -= time.Duration(ticks) * TickDuration
+	if p.MaxTicks > 0 && ticks > p.MaxTicks {
+		ticks = p.MaxTicks
+	}
+	return ticks
+}
@@ -0,0 +1,154 @@
+package chip8
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// countTick runs one tick and returns the number of executed instructions.
+func countTick(chip *Chip8) int {
+	count := 0
+	chip.RunTick(func(firstByte, secondByte byte) { count++ })
+	return count
+}
+
+func TestRunTick(t *testing.T) {
+	t.Run("Tickrate timing runs the same instructions as RunFrame", func(t *testing.T) {
+		for name, program := range bundledROMs(t) {
+			t.Run(name, func(t *testing.T) {
+				want := newEngineChip(program)
+				got := newEngineChip(program)
+				got.Timing = TickrateTiming(15)
+
+				for frame := 0; frame < 100; frame++ {
+					want.RunFrame(15)
+					got.RunTick(nil)
+					assertSameState(t, got, want)
+				}
+			})
+		}
+	})
+
+	t.Run("Engine runs the same instructions as Step", func(t *testing.T) {
+		program := bundledROMs(t)["snake.ch8"]
+		want := newEngineChip(program)
+		want.Timing = InstructionsPerSecond(700)
+		got := newEngineChip(program)
+		got.Timing = InstructionsPerSecond(700)
+		got.Engine = NewCachedEngine(got)
+
+		for frame := 0; frame < 100; frame++ {
+			want.RunTick(func(firstByte, secondByte byte) {})
+			got.RunTick(nil)
+			assertSameState(t, got, want)
+		}
+	})
+
+	t.Run("Instructions per second are kept when a tick isn't a whole number of them", func(t *testing.T) {
+		// V0 += 1 in all memory after the program start
+		chip := newEngineChip(bytes.Repeat([]byte{0x70, 0x01}, 0x700))
+		chip.Timing = InstructionsPerSecond(100)
+
+		counts := map[int]int{}
+		for tick := 0; tick < TimerFrequency; tick++ {
+			counts[countTick(chip)]++
+		}
+
+		if chip.Registers[0] != 100 || counts[1]+counts[2] != TimerFrequency {
+			t.Errorf("got %d instructions in ticks of %v instructions, want 100 in ticks of 1 or 2", chip.Registers[0], counts)
+		}
+	})
+
+	t.Run("VIP timing runs fewer expensive instructions per tick", func(t *testing.T) {
+		loads := newEngineChip(bytes.Repeat([]byte{0x60, 0x01}, 0x700))
+		loads.Timing = VIPTiming
+		draws := newEngineChip(bytes.Repeat([]byte{0xd0, 0x00}, 0x700))
+		draws.Timing = VIPTiming
+
+		// a tick has 16666 cycles, the last instruction can take cycles of the next tick
+		if got := countTick(loads); got != 618 {
+			t.Errorf("got %d loads, want 618", got)
+		}
+		if got := countTick(draws); got != 5 {
+			t.Errorf("got %d draws, want 5", got)
+		}
+	})
+
+	t.Run("Waiting for the display draws at most one sprite per tick", func(t *testing.T) {
+		chip := newEngineChip(bytes.Repeat([]byte{0xd0, 0x01}, 0x700))
+		chip.Timing = InstructionsPerSecond(6000)
+		chip.Quirks = QuirksVIP
+
+		for tick := 0; tick < 3; tick++ {
+			if got := countTick(chip); got != 1 {
+				t.Errorf("got %d draws in tick %d, want 1", got, tick)
+			}
+		}
+		AssertAddress(t, chip.Pc, 0x206)
+	})
+
+	t.Run("Engines wait for the display like Step", func(t *testing.T) {
+		for name, program := range bundledROMs(t) {
+			t.Run(name, func(t *testing.T) {
+				want := newEngineChip(program)
+				want.Quirks = QuirksVIP
+				cached := newEngineChip(program)
+				cached.Quirks = QuirksVIP
+				cached.Engine = NewCachedEngine(cached)
+				compiled := newEngineChip(program)
+				compiled.Quirks = QuirksVIP
+				compiled.Engine = NewCompiledEngine(compiled)
+
+				for frame := 0; frame < 100; frame++ {
+					want.RunTick(func(firstByte, secondByte byte) {})
+					cached.RunTick(nil)
+					compiled.RunTick(nil)
+					assertSameState(t, cached, want)
+					assertSameState(t, compiled, want)
+				}
+			})
+		}
+	})
+
+	t.Run("Timers tick once per tick whatever the speed", func(t *testing.T) {
+		chip := newEngineChip([]byte{0x12, 0x00})
+		chip.Timing = InstructionsPerSecond(100000)
+		chip.Timers[0] = 60
+
+		for tick := 0; tick < 30; tick++ {
+			chip.RunTick(nil)
+		}
+
+		AssertBytes(t, chip.Timers[0], 30)
+	})
+}
+
+func TestPacer(t *testing.T) {
+	t.Run("Return whole ticks and keep the rest", func(t *testing.T) {
+		pacer := Pacer{}
+
+		got := []int{
+			pacer.Advance(2*TickDuration + TickDuration/2),
+			pacer.Advance(TickDuration / 4),
+			pacer.Advance(TickDuration - TickDuration/4),
+		}
+		want := []int{2, 0, 1}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Limit ticks after a long pause", func(t *testing.T) {
+		pacer := Pacer{MaxTicks: 3}
+
+		if got := pacer.Advance(time.Second); got != 3 {
+			t.Errorf("got %d, want 3", got)
+		}
+		if got := pacer.Advance(0); got != 0 {
+			t.Errorf("got %d, want 0", got)
+		}
+	})
+}
@@ -0,0 +1,278 @@
+package chip8
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TraceEntry is the state of the chip after executing one instruction.
+type TraceEntry struct {
+	// Pc is the address of the executed instruction.
+	Pc        uint16
+	Opcode    uint16
+	Registers [16]byte
+	I         uint16
+	Sp        uint8
+	Delay     byte
+	Sound     byte
+}
+
+// Tracer receives an entry after every instruction executed by Step.
+type Tracer interface {
+	Trace(entry TraceEntry)
+}
+
+// traceEntry returns state of the chip after executing opcode stored at pc.
+func (c *Chip8) traceEntry(pc uint16, firstByte, secondByte byte) TraceEntry {
+	entry := TraceEntry{
+		Pc:     pc,
+		Opcode: uint16(firstByte)<<8 | uint16(secondByte),
+		I:      c.I,
+		Sp:     uint8(len(c.Stack)),
+	}
+	copy(entry.Registers[:], c.Registers)
+	if len(c.Timers) == 2 {
+		entry.Delay = c.Timers[0]
+		entry.Sound = c.Timers[1]
+	}
+	return entry
+}
+
+// Mnemonic returns the disassembled instruction of the entry executed on platform.
+func (e TraceEntry) Mnemonic(platform Platform) string {
+	return DisassembleInstruction(DecodeFor(platform, e.Opcode))
+}
+
+// String formats the entry of a CHIP-8 program as a line of the text trace, see Format.
+func (e TraceEntry) String() string {
+	return e.Format(PlatformChip8)
+}
+
+// Format formats the entry as a line of the text trace with the mnemonic of platform, e.g.
+//
+//	PC=0200 OP=6A02 V=0A000000000000000000000000000000 I=0000 SP=00 DT=00 ST=00 ; LD VA, 0x02
+func (e TraceEntry) Format(platform Platform) string {
+	return fmt.Sprintf("PC=%04X OP=%04X V=%s I=%04X SP=%02X DT=%02X ST=%02X ; %s",
+		e.Pc, e.Opcode, strings.ToUpper(hex.EncodeToString(e.Registers[:])), e.I, e.Sp, e.Delay, e.Sound, e.Mnemonic(platform))
+}
+
+// ParseTraceEntry parses a line of the text trace. Text after ';' is ignored.
+func ParseTraceEntry(line string) (TraceEntry, error) {
+	var entry TraceEntry
+	if i := strings.Index(line, ";"); i >= 0 {
+		line = line[:i]
+	}
+
+	for _, field := range strings.Fields(line) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return entry, fmt.Errorf("trace field %q is not in KEY=VALUE format", field)
+		}
+
+		if key == "V" {
+			registers, err := hex.DecodeString(value)
+			if err != nil || len(registers) != 16 {
+				return entry, fmt.Errorf("trace registers %q should have 32 hexadecimal digits", value)
+			}
+			copy(entry.Registers[:], registers)
+			continue
+		}
+
+		number, err := strconv.ParseUint(value, 16, 16)
+		if err != nil {
+			return entry, fmt.Errorf("trace field %q: %w", field, err)
+		}
+		switch key {
+		case "PC":
+			entry.Pc = uint16(number)
+		case "OP":
+			entry.Opcode = uint16(number)
+		case "I":
+			entry.I = uint16(number)
+		case "SP":
+			entry.Sp = uint8(number)
+		case "DT":
+			entry.Delay = byte(number)
+		case "ST":
+			entry.Sound = byte(number)
+		default:
+			return entry, fmt.Errorf("unknown trace field %q", key)
+		}
+	}
+
+	return entry, nil
+}
+
+// binaryTraceMagic starts every binary trace.
+var binaryTraceMagic = []byte("C8TR\x01")
+
+// TraceWriter writes trace entries as text or in the compact binary format.
+// The first error is kept and returned by Flush.
+type TraceWriter struct {
+	w      *bufio.Writer
+	binary bool
+	// platform decodes mnemonics of the text trace
+	platform Platform
+	err      error
+}
+
+// NewTextTraceWriter creates writer with one line per instruction of platform, see TraceEntry.Format.
+func NewTextTraceWriter(w io.Writer, platform Platform) *TraceWriter {
+	return &TraceWriter{w: bufio.NewWriter(w), platform: platform}
+}
+
+// NewBinaryTraceWriter creates writer storing each instruction in 25 bytes (little endian
+// pc, opcode, V0-VF, I, sp, delay and sound timers) after a 5 byte header.
+func NewBinaryTraceWriter(w io.Writer) *TraceWriter {
+	writer := &TraceWriter{w: bufio.NewWriter(w), binary: true}
+	_, writer.err = writer.w.Write(binaryTraceMagic)
+	return writer
+}
+
+func (t *TraceWriter) Trace(entry TraceEntry) {
+	if t.err != nil {
+		return
+	}
+
+	if t.binary {
+		t.err = binary.Write(t.w, binary.LittleEndian, entry)
+	} else {
+		_, t.err = fmt.Fprintln(t.w, entry.Format(t.platform))
+	}
+}
+
+// Flush writes buffered entries and returns the first error which occurred while writing.
+func (t *TraceWriter) Flush() error {
+	if t.err != nil {
+		return t.err
+	}
+	return t.w.Flush()
+}
+
+// ReadTrace reads all entries of a text or binary trace, the format is detected from the header.
+func ReadTrace(r io.Reader) ([]TraceEntry, error) {
+	reader := bufio.NewReader(r)
+	header, err := reader.Peek(len(binaryTraceMagic))
+	if err == nil && bytes.Equal(header, binaryTraceMagic) {
+		reader.Discard(len(binaryTraceMagic))
+		return readBinaryTrace(reader)
+	}
+
+	var entries []TraceEntry
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		entry, err := ParseTraceEntry(text)
+		if err != nil {
+			return nil, fmt.Errorf("trace line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+func readBinaryTrace(r io.Reader) ([]TraceEntry, error) {
+	var entries []TraceEntry
+	for {
+		var entry TraceEntry
+		err := binary.Read(r, binary.LittleEndian, &entry)
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("binary trace entry %d: %w", len(entries), err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// TraceField is a part of the state compared by DiffTraces.
+type TraceField int
+
+const (
+	TraceRegisters TraceField = 1 << iota
+	TraceIndex
+	TraceStack
+	TraceTimers
+	TraceAll = TraceRegisters | TraceIndex | TraceStack | TraceTimers
+)
+
+// TraceDivergence describes the first entry where two traces differ.
+type TraceDivergence struct {
+	// IndexA and IndexB are positions of the differing entries in both traces.
+	IndexA, IndexB int
+	A, B           TraceEntry
+	// Differences lists differing parts of the state, e.g. "V3: 04 != 05".
+	Differences []string
+}
+
+// AlignTraces returns the position in b of the first entry executing the same instruction
+// as the first entry of a. Emulators often start logging at different points, e.g. after
+// running a boot routine. It returns -1 if no entry in b matches.
+func AlignTraces(a, b []TraceEntry) int {
+	if len(a) == 0 {
+		return 0
+	}
+	for i, entry := range b {
+		if entry.Pc == a[0].Pc && entry.Opcode == a[0].Opcode {
+			return i
+		}
+	}
+	return -1
+}
+
+// DiffTraces compares a with b starting at offset in b and returns the first divergence,
+// only the given fields are compared besides pc and opcode. It returns nil if traces are
+// the same up to the end of the shorter one.
+func DiffTraces(a, b []TraceEntry, offset int, fields TraceField) *TraceDivergence {
+	for i := 0; i < len(a) && i+offset < len(b); i++ {
+		differences := compareEntries(a[i], b[i+offset], fields)
+		if len(differences) > 0 {
+			return &TraceDivergence{IndexA: i, IndexB: i + offset, A: a[i], B: b[i+offset], Differences: differences}
+		}
+	}
+	return nil
+}
+
+func compareEntries(a, b TraceEntry, fields TraceField) []string {
+	var differences []string
+	if a.Pc != b.Pc {
+		differences = append(differences, fmt.Sprintf("PC: %04X != %04X", a.Pc, b.Pc))
+	}
+	if a.Opcode != b.Opcode {
+		differences = append(differences, fmt.Sprintf("OP: %04X != %04X", a.Opcode, b.Opcode))
+	}
+	if fields&TraceRegisters != 0 {
+		for r := range a.Registers {
+			if a.Registers[r] != b.Registers[r] {
+				differences = append(differences, fmt.Sprintf("V%X: %02X != %02X", r, a.Registers[r], b.Registers[r]))
+			}
+		}
+	}
+	if fields&TraceIndex != 0 && a.I != b.I {
+		differences = append(differences, fmt.Sprintf("I: %04X != %04X", a.I, b.I))
+	}
+	if fields&TraceStack != 0 && a.Sp != b.Sp {
+		differences = append(differences, fmt.Sprintf("SP: %02X != %02X", a.Sp, b.Sp))
+	}
+	if fields&TraceTimers != 0 {
+		if a.Delay != b.Delay {
+			differences = append(differences, fmt.Sprintf("DT: %02X != %02X", a.Delay, b.Delay))
+		}
+		if a.Sound != b.Sound {
+			differences = append(differences, fmt.Sprintf("ST: %02X != %02X", a.Sound, b.Sound))
+		}
+	}
+	return differences
+}
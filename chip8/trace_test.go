@@ -0,0 +1,136 @@
+package chip8
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type traceCollector struct {
+	entries []TraceEntry
+}
+
+func (t *traceCollector) Trace(entry TraceEntry) {
+	t.entries = append(t.entries, entry)
+}
+
+func TestTrace(t *testing.T) {
+	t.Run("Step traces state after the instruction", func(t *testing.T) {
+		chip := NewChip8()
+		chip.LoadProgram([]byte{0x6a, 0x02, 0xa2, 0x31})
+		collector := &traceCollector{}
+		chip.Tracer = collector
+
+		chip.Step()
+		chip.Step()
+
+		if len(collector.entries) != 2 {
+			t.Fatalf("got %d entries, want 2", len(collector.entries))
+		}
+		got := collector.entries[1]
+		AssertAddress(t, got.Pc, 0x202)
+		AssertAddress(t, got.Opcode, 0xa231)
+		AssertAddress(t, got.I, 0x231)
+		AssertBytes(t, got.Registers[0xa], 0x02)
+	})
+
+	t.Run("Text entry can be parsed back", func(t *testing.T) {
+		entry := TraceEntry{Pc: 0x200, Opcode: 0x6a02, I: 0x2f0, Sp: 1, Delay: 3, Sound: 4}
+		entry.Registers[0xa] = 0x02
+
+		line := entry.String()
+		want := "PC=0200 OP=6A02 V=00000000000000000000020000000000 I=02F0 SP=01 DT=03 ST=04 ; LD VA, 0x02"
+		if line != want {
+			t.Fatalf("got %q, want %q", line, want)
+		}
+
+		got, err := ParseTraceEntry(line)
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		if got != entry {
+			t.Errorf("got %+v, want %+v", got, entry)
+		}
+	})
+
+	t.Run("Mnemonics follow the platform", func(t *testing.T) {
+		entry := TraceEntry{Pc: 0x200, Opcode: 0xb120}
+
+		if got := entry.Mnemonic(PlatformChip8X); got != "COL V1, V2" {
+			t.Errorf("got %q, want COL V1, V2", got)
+		}
+		if got := entry.Format(PlatformChip8); !strings.HasSuffix(got, "; JP V0, 0x120") {
+			t.Errorf("got %q, want it to end with JP V0, 0x120", got)
+		}
+	})
+
+	t.Run("Text and binary traces are read back", func(t *testing.T) {
+		entries := []TraceEntry{{Pc: 0x200, Opcode: 0x00e0}, {Pc: 0x202, Opcode: 0x1202, Sp: 2}}
+
+		for _, binary := range []bool{false, true} {
+			var buf bytes.Buffer
+			writer := NewTextTraceWriter(&buf, PlatformChip8)
+			if binary {
+				writer = NewBinaryTraceWriter(&buf)
+			}
+			for _, entry := range entries {
+				writer.Trace(entry)
+			}
+			if err := writer.Flush(); err != nil {
+				t.Fatalf("didn't expect an error, got %v", err)
+			}
+
+			got, err := ReadTrace(&buf)
+			if err != nil {
+				t.Fatalf("didn't expect an error, got %v", err)
+			}
+			if !reflect.DeepEqual(got, entries) {
+				t.Errorf("binary %v: got %v, want %v", binary, got, entries)
+			}
+		}
+	})
+}
+
+func TestDiffTraces(t *testing.T) {
+	a := []TraceEntry{{Pc: 0x200, Opcode: 0x6001}, {Pc: 0x202, Opcode: 0x7001}, {Pc: 0x204, Opcode: 0x1204}}
+	b := []TraceEntry{{Pc: 0x000, Opcode: 0x0000}, {Pc: 0x200, Opcode: 0x6001}, {Pc: 0x202, Opcode: 0x7001}, {Pc: 0x204, Opcode: 0x1204}}
+	a[0].Registers[0] = 1
+	a[1].Registers[0] = 2
+	a[2].Registers[0] = 2
+	b[2].Registers[0] = 1
+	b[3].Registers[0] = 2
+
+	t.Run("Traces are aligned at the first matching instruction", func(t *testing.T) {
+		got := AlignTraces(a, b)
+
+		if got != 1 {
+			t.Errorf("got %d, want 1", got)
+		}
+	})
+
+	t.Run("First divergence is reported", func(t *testing.T) {
+		b[1].Registers[0] = 1
+
+		got := DiffTraces(a, b, 1, TraceAll)
+
+		if got == nil {
+			t.Fatalf("expected a divergence")
+		}
+		if got.IndexA != 1 || got.IndexB != 2 {
+			t.Errorf("got indexes %d and %d, want 1 and 2", got.IndexA, got.IndexB)
+		}
+		want := []string{"V0: 02 != 01"}
+		if !reflect.DeepEqual(got.Differences, want) {
+			t.Errorf("got %v, want %v", got.Differences, want)
+		}
+	})
+
+	t.Run("Ignored fields are not compared", func(t *testing.T) {
+		got := DiffTraces(a, b, 1, TraceAll&^TraceRegisters)
+
+		if got != nil {
+			t.Errorf("didn't expect a divergence, got %+v", got)
+		}
+	})
+}
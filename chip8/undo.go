@@ -0,0 +1,151 @@
+package chip8
+
+import "image/color"
+
+// memoryUndo is the value of a byte of memory before it was written.
+type memoryUndo struct {
+	address uint16
+	old     byte
+}
+
+// pixelUndo is the color of a pixel before it was drawn.
+type pixelUndo struct {
+	position int
+	old      color.RGBA
+}
+
+// undoEntry contains everything needed to restore the state before one instruction. Registers,
+// timers and the stack are small so they're copied, only changed bytes of memory and pixels
+// of the screen are recorded. CHIP-8X colours are copied only before colour instructions.
+type undoEntry struct {
+	pc        uint16
+	i         uint16
+	registers [16]byte
+	timers    [2]byte
+	stack     []uint16
+	memory    []memoryUndo
+	screen    []pixelUndo
+	// colors is true if background and foreground contain the colours before the instruction
+	colors     bool
+	background int
+	foreground []byte
+}
+
+// UndoLog records changes made by instructions executed by Step so they can be undone by StepBack.
+// It keeps at most limit instructions, the oldest ones are forgotten.
+type UndoLog struct {
+	entries []undoEntry
+	// start is the position of the oldest entry and count the number of entries
+	start, count int
+	recording    bool
+}
+
+// NewUndoLog creates log which remembers the last limit instructions.
+func NewUndoLog(limit int) *UndoLog {
+	if limit < 1 {
+		limit = 1
+	}
+	return &UndoLog{entries: make([]undoEntry, limit)}
+}
+
+// Len returns the number of instructions which can be undone.
+func (l *UndoLog) Len() int {
+	return l.count
+}
+
+// Clear forgets all recorded instructions, e.g. after the state was changed by a debugger.
+func (l *UndoLog) Clear() {
+	l.start = 0
+	l.count = 0
+}
+
+// begin starts recording of an instruction executed with the current state of c.
+func (l *UndoLog) begin(c *Chip8) {
+	var entry *undoEntry
+	if l.count < len(l.entries) {
+		entry = &l.entries[(l.start+l.count)%len(l.entries)]
+		l.count++
+	} else {
+		// reuse the oldest entry
+		entry = &l.entries[l.start]
+		l.start = (l.start + 1) % len(l.entries)
+	}
+
+	entry.pc = c.Pc
+	entry.i = c.I
+	copy(entry.registers[:], c.Registers)
+	copy(entry.timers[:], c.Timers)
+	entry.stack = append(entry.stack[:0], c.Stack...)
+	entry.memory = entry.memory[:0]
+	entry.screen = entry.screen[:0]
+	entry.colors = false
+	l.recording = true
+}
+
+func (l *UndoLog) end() {
+	l.recording = false
+}
+
+func (l *UndoLog) last() *undoEntry {
+	return &l.entries[(l.start+l.count-1)%len(l.entries)]
+}
+
+func (l *UndoLog) recordMemory(address uint16, old byte) {
+	if l.recording {
+		entry := l.last()
+		entry.memory = append(entry.memory, memoryUndo{address: address, old: old})
+	}
+}
+
+func (l *UndoLog) recordPixel(position int, old color.RGBA) {
+	if l.recording {
+		entry := l.last()
+		entry.screen = append(entry.screen, pixelUndo{position: position, old: old})
+	}
+}
+
+func (l *UndoLog) recordColors(colors *ColorMap) {
+	if l.recording {
+		entry := l.last()
+		entry.colors = true
+		entry.background = colors.Background
+		entry.foreground = append(entry.foreground[:0], colors.Foreground...)
+	}
+}
+
+// StepBack restores the state before the last instruction recorded in Undo.
+// It returns false if there's nothing to undo.
+func (c *Chip8) StepBack() bool {
+	if c.Undo == nil || c.Undo.count == 0 {
+		return false
+	}
+
+	entry := c.Undo.last()
+	c.Undo.count--
+
+	// changes are undone in reverse order, a byte or pixel may have been changed more than once
+	for i := len(entry.screen) - 1; i >= 0; i-- {
+		c.Screen[entry.screen[i].position] = entry.screen[i].old
+	}
+	for i := len(entry.memory) - 1; i >= 0; i-- {
+		c.WriteMemory(entry.memory[i].address, entry.memory[i].old)
+	}
+	if entry.colors && c.Colors != nil {
+		c.Colors.Background = entry.background
+		copy(c.Colors.Foreground, entry.foreground)
+	}
+	c.Pc = entry.pc
+	c.I = entry.i
+	copy(c.Registers, entry.registers[:])
+	copy(c.Timers, entry.timers[:])
+	c.Stack = append(c.Stack[:0], entry.stack...)
+	return true
+}
+
+// setPixel is used by instructions changing the screen.
+func (c *Chip8) setPixel(position int, value color.RGBA) {
+	if c.Undo != nil && c.Screen[position] != value {
+		c.Undo.recordPixel(position, c.Screen[position])
+	}
+	c.Screen[position] = value
+}
@@ -0,0 +1,135 @@
+package chip8
+
+import (
+	"reflect"
+	"testing"
+)
+
+// chipState is the part of the chip restored by StepBack.
+type chipState struct {
+	Memory    []byte
+	Registers []byte
+	Timers    []byte
+	Stack     []uint16
+	Screen    []byte
+	Pc, I     uint16
+}
+
+func stateOf(c *Chip8) chipState {
+	screen := make([]byte, len(c.Screen))
+	for i, pixel := range c.Screen {
+		if pixel == c.PrimaryColor {
+			screen[i] = 1
+		}
+	}
+	return chipState{
+		Memory:    append([]byte(nil), c.Memory...),
+		Registers: append([]byte(nil), c.Registers...),
+		Timers:    append([]byte(nil), c.Timers...),
+		Stack:     append([]uint16{}, c.Stack...),
+		Screen:    screen,
+		Pc:        c.Pc,
+		I:         c.I,
+	}
+}
+
+func TestStepBack(t *testing.T) {
+	// 0x200: I = font of 0, V0 = 123, call 0x20c, 0x206: clear screen, 0x208: jump to 0x208
+	// 0x20c: draw 5 rows at V0, V0, I = 0x300, BCD of V0, store V0-V2, return
+	program := []byte{
+		0xa0, 0x00, 0x60, 0x7b, 0x22, 0x0c, 0x00, 0xe0, 0x12, 0x08, 0x00, 0x00,
+		0xd0, 0x05, 0xa3, 0x00, 0xf0, 0x33, 0xf2, 0x55, 0x00, 0xee,
+	}
+
+	newChip := func(history int) *Chip8 {
+		chip := NewChip8()
+		chip.Keypad = NewMovieKeypad(nil)
+		chip.ClearScreen()
+		chip.LoadFont()
+		chip.LoadProgram(program)
+		chip.Undo = NewUndoLog(history)
+		return chip
+	}
+
+	t.Run("Stepping back restores every state", func(t *testing.T) {
+		chip := newChip(100)
+		var states []chipState
+		for i := 0; i < 10; i++ {
+			states = append(states, stateOf(chip))
+			chip.Step()
+			chip.UpdateTimers()
+		}
+
+		for i := len(states) - 1; i >= 0; i-- {
+			if !chip.StepBack() {
+				t.Fatalf("expected instruction %d to be undone", i)
+			}
+			if got := stateOf(chip); !reflect.DeepEqual(got, states[i]) {
+				t.Fatalf("state before instruction %d isn't restored, got pc %x, want %x", i, got.Pc, states[i].Pc)
+			}
+		}
+
+		if chip.StepBack() {
+			t.Errorf("didn't expect more instructions to undo")
+		}
+	})
+
+	t.Run("History is bounded", func(t *testing.T) {
+		chip := newChip(3)
+		for i := 0; i < 5; i++ {
+			chip.Step()
+		}
+
+		undone := 0
+		for chip.StepBack() {
+			undone++
+		}
+
+		if undone != 3 || chip.Pc != 0x204 {
+			t.Errorf("got %d instructions undone at %x, want 3 at 204", undone, chip.Pc)
+		}
+	})
+
+	t.Run("Stepping back restores CHIP-8X colours", func(t *testing.T) {
+		// V0 = 0x11, V1 = 3, V2 = 5, cycle the background, colour zones of V0 and V1,
+		// colour 2 rows of the column of V1, jump to 0x20c
+		chip := NewChip8()
+		chip.SetPlatform(PlatformChip8X)
+		chip.LoadProgram([]byte{0x60, 0x11, 0x61, 0x03, 0x62, 0x05, 0x02, 0xa0, 0xb0, 0x20, 0xb1, 0x22, 0x12, 0x0c})
+		chip.Undo = NewUndoLog(10)
+
+		var states []ColorMap
+		for i := 0; i < 6; i++ {
+			states = append(states, ColorMap{
+				Background: chip.Colors.Background,
+				Foreground: append([]byte(nil), chip.Colors.Foreground...),
+				columns:    chip.Colors.columns,
+			})
+			chip.Step()
+		}
+
+		for i := len(states) - 1; i >= 0; i-- {
+			chip.StepBack()
+			if !reflect.DeepEqual(*chip.Colors, states[i]) {
+				t.Fatalf("colours before instruction %d aren't restored", i)
+			}
+		}
+	})
+
+	t.Run("Executing again after stepping back", func(t *testing.T) {
+		chip := newChip(10)
+		for i := 0; i < 6; i++ {
+			chip.Step()
+		}
+		want := stateOf(chip)
+
+		chip.StepBack()
+		chip.StepBack()
+		chip.Step()
+		chip.Step()
+
+		if got := stateOf(chip); !reflect.DeepEqual(got, want) {
+			t.Errorf("got different state after executing again")
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package chip8
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WatchAccess is a kind of access which triggers a watchpoint, kinds can be combined.
+type WatchAccess int
+
+const (
+	WatchRead WatchAccess = 1 << iota
+	WatchWrite
+	// WatchChange triggers on writes which change the value.
+	WatchChange
+)
+
+// WatchTarget tells whether a watchpoint watches memory or a register.
+type WatchTarget int
+
+const (
+	WatchMemory WatchTarget = iota
+	WatchRegister
+)
+
+// Watchpoint stops the execution when the watched byte of memory or register is accessed.
+type Watchpoint struct {
+	Target WatchTarget
+	// Address is the memory address or the number of the register V0-VF.
+	Address uint16
+	Access  WatchAccess
+	// Condition must be true for the watchpoint to trigger, nil triggers on every access.
+	Condition *WatchCondition
+}
+
+// Name returns name of the watched location, e.g. "V3" or "0x2F0".
+func (w *Watchpoint) Name() string {
+	if w.Target == WatchRegister {
+		return fmt.Sprintf("V%X", w.Address)
+	}
+	return fmt.Sprintf("0x%03X", w.Address)
+}
+
+// WatchCondition compares the value written to or read from the watched location with a number,
+// e.g. "value == 0x09". The value before a write is called old, e.g. "old != 0".
+type WatchCondition struct {
+	// Operand is "value" or "old".
+	Operand  string
+	Operator string
+	Number   int
+}
+
+var watchOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// ParseWatchCondition parses condition in the format "operand operator number".
+func ParseWatchCondition(text string) (*WatchCondition, error) {
+	for _, operator := range watchOperators {
+		i := strings.Index(text, operator)
+		if i < 0 {
+			continue
+		}
+
+		operand := strings.TrimSpace(text[:i])
+		if operand != "value" && operand != "old" {
+			return nil, fmt.Errorf("watch condition %q: unknown operand %q, expected value or old", text, operand)
+		}
+		number, err := strconv.ParseUint(strings.TrimSpace(text[i+len(operator):]), 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("watch condition %q: expected a byte after %s", text, operator)
+		}
+		return &WatchCondition{Operand: operand, Operator: operator, Number: int(number)}, nil
+	}
+
+	return nil, fmt.Errorf("watch condition %q has no comparison operator", text)
+}
+
+func (c *WatchCondition) String() string {
+	return fmt.Sprintf("%s %s 0x%02X", c.Operand, c.Operator, c.Number)
+}
+
+// Matches evaluates the condition for an access which replaced old with value.
+// Reads use the same byte as old and value.
+func (c *WatchCondition) Matches(old, value byte) bool {
+	operand := int(value)
+	if c.Operand == "old" {
+		operand = int(old)
+	}
+
+	switch c.Operator {
+	case "==":
+		return operand == c.Number
+	case "!=":
+		return operand != c.Number
+	case "<=":
+		return operand <= c.Number
+	case ">=":
+		return operand >= c.Number
+	case "<":
+		return operand < c.Number
+	case ">":
+		return operand > c.Number
+	}
+	return false
+}
+
+// WatchHit is an access which triggered a watchpoint.
+type WatchHit struct {
+	Watchpoint *Watchpoint
+	// Pc is the address of the instruction which made the access.
+	Pc uint16
+	// Access is WatchRead or WatchWrite.
+	Access     WatchAccess
+	Old, Value byte
+}
+
+// String describes the hit, e.g. "write of V3 at 0x204: 0x05 -> 0x06".
+func (h WatchHit) String() string {
+	if h.Access == WatchRead {
+		return fmt.Sprintf("read of %s at 0x%03X: 0x%02X", h.Watchpoint.Name(), h.Pc, h.Value)
+	}
+	return fmt.Sprintf("write of %s at 0x%03X: 0x%02X -> 0x%02X", h.Watchpoint.Name(), h.Pc, h.Old, h.Value)
+}
+
+// Watchpoints checks accesses made by instructions executed by Step. Hits are collected
+// until they're taken, e.g. by a debugger after every instruction.
+type Watchpoints struct {
+	watchpoints []*Watchpoint
+	hits        []WatchHit
+	// pc of the instruction being executed
+	pc uint16
+}
+
+// Add starts watching the location of watchpoint.
+func (w *Watchpoints) Add(watchpoint *Watchpoint) {
+	w.watchpoints = append(w.watchpoints, watchpoint)
+}
+
+// Remove stops watching watchpoint added by Add.
+func (w *Watchpoints) Remove(watchpoint *Watchpoint) {
+	for i, current := range w.watchpoints {
+		if current == watchpoint {
+			w.watchpoints = append(w.watchpoints[:i], w.watchpoints[i+1:]...)
+			return
+		}
+	}
+}
+
+// List returns all watchpoints in the order they were added.
+func (w *Watchpoints) List() []*Watchpoint {
+	return append([]*Watchpoint(nil), w.watchpoints...)
+}
+
+// TakeHits returns hits since the last call.
+func (w *Watchpoints) TakeHits() []WatchHit {
+	hits := w.hits
+	w.hits = nil
+	return hits
+}
+
+// check records hits of watchpoints on location caused by a read (old == value) or a write.
+func (w *Watchpoints) check(target WatchTarget, address uint16, write bool, old, value byte) {
+	for _, watchpoint := range w.watchpoints {
+		if watchpoint.Target != target || watchpoint.Address != address {
+			continue
+		}
+
+		access := WatchRead
+		if write {
+			access = WatchWrite
+		}
+		triggered := watchpoint.Access&access != 0 || (write && old != value && watchpoint.Access&WatchChange != 0)
+		if !triggered || (watchpoint.Condition != nil && !watchpoint.Condition.Matches(old, value)) {
+			continue
+		}
+
+		w.hits = append(w.hits, WatchHit{Watchpoint: watchpoint, Pc: w.pc, Access: access, Old: old, Value: value})
+	}
+}
+
+// registersAccessed checks registers read and written by an instruction, both are bit masks
+// of registers and old contains values of registers before the instruction.
+func (w *Watchpoints) registersAccessed(reads, writes uint16, old []byte, registers []byte) {
+	for r := uint16(0); r < 16; r++ {
+		if reads&(1<<r) != 0 {
+			w.check(WatchRegister, r, false, old[r], old[r])
+		}
+		if writes&(1<<r) != 0 {
+			w.check(WatchRegister, r, true, old[r], registers[r])
+		}
+	}
+}
+
+// registerAccesses returns bit masks of registers read and written by the instruction.
+func registerAccesses(instruction Instruction, quirks Quirks) (reads, writes uint16) {
+	x := uint16(1) << instruction.X
+	y := uint16(1) << instruction.Y
+	const f = uint16(1) << 0xf
+
+	switch instruction.Op {
+	case OpSkipEqual, OpSkipNotEqual, OpSkipKeyPressed, OpSkipKeyNotPressed:
+		return x, 0
+	case OpSkipEqualRegisters, OpSkipNotEqualRegisters:
+		return x | y, 0
+	case OpLoad, OpRandom, OpLoadDelayTimer, OpWaitKey:
+		return 0, x
+	case OpAdd:
+		return x, x
+	case OpMove:
+		return y, x
+	case OpShiftRight, OpShiftLeft:
+		if quirks.ShiftVx {
+			return x, x | f
+		}
+		return y, x | f
+	case OpOr, OpAnd, OpXor:
+		if quirks.KeepVF {
+			return x | y, x
+		}
+		return x | y, x | f
+	case OpAddRegisters, OpSub, OpSubReverse:
+		return x | y, x | f
+	case OpJumpOffset:
+		if quirks.JumpVx {
+			return x, 0
+		}
+		return 1, 0
+	case OpDraw:
+		return x | y, f
+	case OpSetDelayTimer, OpSetSoundTimer, OpAddIndex, OpLoadFont, OpStoreBCD:
+		return x, 0
+	case OpStoreRegisters:
+		return x<<1 - 1, 0
+	case OpLoadRegisters:
+		return 0, x<<1 - 1
+	case OpColorZones, OpColorRows:
+		// V(X+1) is the vertical position of the zones
+		return x | uint16(1)<<((instruction.X+1)&0xf) | y, 0
+	}
+	return 0, 0
+}
@@ -0,0 +1,134 @@
+package chip8
+
+import (
+	"testing"
+)
+
+func newWatchedChip(program []byte, watchpoints ...*Watchpoint) *Chip8 {
+	chip := NewChip8()
+	chip.Keypad = NewMovieKeypad(nil)
+	chip.LoadProgram(program)
+	chip.Watchpoints = &Watchpoints{}
+	for _, watchpoint := range watchpoints {
+		chip.Watchpoints.Add(watchpoint)
+	}
+	return chip
+}
+
+func TestWatchpoints(t *testing.T) {
+	t.Run("Write of memory by FX33", func(t *testing.T) {
+		// I = 0x300, V0 = 123, BCD of V0
+		chip := newWatchedChip([]byte{0xa3, 0x00, 0x60, 0x7b, 0xf0, 0x33},
+			&Watchpoint{Target: WatchMemory, Address: 0x302, Access: WatchWrite})
+
+		chip.Step()
+		chip.Step()
+		if hits := chip.Watchpoints.TakeHits(); len(hits) != 0 {
+			t.Fatalf("got %v, want no hits", hits)
+		}
+
+		chip.Step()
+		hits := chip.Watchpoints.TakeHits()
+		if len(hits) != 1 || hits[0].String() != "write of 0x302 at 0x204: 0x00 -> 0x03" {
+			t.Errorf("got %v, want write of 0x302 at 0x204", hits)
+		}
+	})
+
+	t.Run("Read of memory by DXYN", func(t *testing.T) {
+		// I = 0x300, draw 2 rows of sprite
+		chip := newWatchedChip([]byte{0xa3, 0x00, 0xd0, 0x02},
+			&Watchpoint{Target: WatchMemory, Address: 0x301, Access: WatchRead})
+
+		chip.Step()
+		chip.Step()
+
+		if hits := chip.Watchpoints.TakeHits(); len(hits) != 1 || hits[0].Access != WatchRead {
+			t.Errorf("got %v, want one read", hits)
+		}
+	})
+
+	t.Run("Change of register ignores writes of the same value", func(t *testing.T) {
+		// V3 = 5, V3 = 5, V3 = 6
+		chip := newWatchedChip([]byte{0x63, 0x05, 0x63, 0x05, 0x63, 0x06},
+			&Watchpoint{Target: WatchRegister, Address: 0x3, Access: WatchChange})
+
+		changes := 0
+		for i := 0; i < 3; i++ {
+			chip.Step()
+			changes += len(chip.Watchpoints.TakeHits())
+		}
+
+		if changes != 2 {
+			t.Errorf("got %d changes, want 2", changes)
+		}
+	})
+
+	t.Run("Registers read by FX55", func(t *testing.T) {
+		// I = 0x300, store V0-V2
+		chip := newWatchedChip([]byte{0xa3, 0x00, 0xf2, 0x55},
+			&Watchpoint{Target: WatchRegister, Address: 0x2, Access: WatchRead},
+			&Watchpoint{Target: WatchRegister, Address: 0x3, Access: WatchRead})
+
+		chip.Step()
+		chip.Step()
+
+		hits := chip.Watchpoints.TakeHits()
+		if len(hits) != 1 || hits[0].Watchpoint.Name() != "V2" {
+			t.Errorf("got %v, want read of V2", hits)
+		}
+	})
+
+	t.Run("Condition filters hits", func(t *testing.T) {
+		condition, err := ParseWatchCondition("value == 0x09")
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		// V0 += 3 in a loop writes 3, 6, 9, 12 and 15, only 9 matches the condition
+		chip := newWatchedChip([]byte{0x70, 0x03, 0x12, 0x00},
+			&Watchpoint{Target: WatchRegister, Address: 0x0, Access: WatchWrite, Condition: condition})
+
+		var hits []WatchHit
+		for i := 0; i < 10; i++ {
+			chip.Step()
+			hits = append(hits, chip.Watchpoints.TakeHits()...)
+		}
+
+		if len(hits) != 1 || hits[0].Old != 6 || hits[0].Value != 9 {
+			t.Errorf("got %v, want one write of 9", hits)
+		}
+	})
+}
+
+func TestParseWatchCondition(t *testing.T) {
+	t.Run("Conditions", func(t *testing.T) {
+		cases := []struct {
+			text       string
+			old, value byte
+			want       bool
+		}{
+			{"value == 0x09", 0, 9, true},
+			{"value==9", 0, 8, false},
+			{"old != 0", 1, 0, true},
+			{"value >= 10", 0, 10, true},
+			{"value < 10", 0, 10, false},
+		}
+
+		for _, c := range cases {
+			condition, err := ParseWatchCondition(c.text)
+			if err != nil {
+				t.Fatalf("didn't expect an error, got %v", err)
+			}
+			if got := condition.Matches(c.old, c.value); got != c.want {
+				t.Errorf("%s with old %d and value %d: got %v, want %v", c.text, c.old, c.value, got, c.want)
+			}
+		}
+	})
+
+	t.Run("Invalid conditions", func(t *testing.T) {
+		for _, text := range []string{"value", "score == 1", "value == 256"} {
+			if _, err := ParseWatchCondition(text); err == nil {
+				t.Errorf("expected an error for %q", text)
+			}
+		}
+	})
+}
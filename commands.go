@@ -0,0 +1,255 @@
+package main
+
+import (
+	"chip8emulator/chip8"
+	"flag"
+	"fmt"
+	"image/color"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// commands contains subcommands which can be run instead of the window, e.g.
+// "go run . screenshot snake.ch8".
+var commands = map[string]func(args []string) error{
+	"screenshot": runScreenshot,
+	"record":     runRecord,
+	"terminal":   runTerminal,
+	"tracediff":  runTraceDiff,
+	"gdb":        runGDB,
+	"dap":        runDAP,
+	"profile":    runProfile,
+	"cfg":        runCFG,
+}
+
+// headlessRun runs a program without the window, keys are played back from an input movie.
+type headlessRun struct {
+	chip   *chip8.Chip8
+	keypad *chip8.MovieKeypad
+}
+
+// headlessSeed is the seed of random numbers in headless runs, it makes every run of a program
+// with the same input movie produce the same frames.
+const headlessSeed = 1
+
+// newHeadlessRun creates chip8 of the platform with font and program from romPath loaded into
+// memory. Keys are read from the movie at moviePath, if it's empty no keys are pressed.
+func newHeadlessRun(romPath, moviePath string, platform chip8.Platform) (*headlessRun, error) {
+	program, err := os.ReadFile(romPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var movie *chip8.Movie
+	if moviePath != "" {
+		movie, err = chip8.LoadMovie(moviePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	chip := chip8.NewChip8()
+	chip.SetPlatform(platform)
+	chip.ClearScreen()
+	chip.LoadFont()
+	chip.LoadProgram(program)
+	keypad := chip8.NewMovieKeypad(movie)
+	chip.Keypad = keypad
+	chip.Random = rand.New(rand.NewSource(headlessSeed))
+	chip.Engine = chip8.NewCachedEngine(chip)
+
+	return &headlessRun{chip: chip, keypad: keypad}, nil
+}
+
+// run executes the given number of frames, each is one tick of the timers with instructions
+// executed according to the timing of the chip. onFrame is called after every frame with the
+// state of the buzzer and returning false from it stops the run early.
+func (h *headlessRun) run(frames int, onFrame func(frame int, sound bool) bool) {
+	for frame := 0; frame < frames; frame++ {
+		h.keypad.SetFrame(frame)
+		sound := h.chip.RunTick(nil)
+		if onFrame != nil && !onFrame(frame, sound) {
+			return
+		}
+	}
+}
+
+// writeFile creates file at path and fills it using write.
+func writeFile(path string, write func(w io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := write(file); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// parseHexColor parses color written as RRGGBB, e.g. "38f620".
+func parseHexColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	value, err := strconv.ParseUint(s, 16, 32)
+	if err != nil || len(s) != 6 {
+		return color.RGBA{}, fmt.Errorf("color %q is not in RRGGBB format", s)
+	}
+
+	return color.RGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 0xff}, nil
+}
+
+// engines create the engine selected by the -engine flag, nil executes instructions by Step.
+var engines = map[string]func(chip *chip8.Chip8) chip8.Engine{
+	"interpreter": func(chip *chip8.Chip8) chip8.Engine { return nil },
+	"cached":      func(chip *chip8.Chip8) chip8.Engine { return chip8.NewCachedEngine(chip) },
+	"compiled":    func(chip *chip8.Chip8) chip8.Engine { return chip8.NewCompiledEngine(chip) },
+}
+
+// engineFlag selects the engine executing instructions of headless runs.
+type engineFlag struct {
+	name *string
+}
+
+func addEngineFlag(flags *flag.FlagSet) engineFlag {
+	return engineFlag{name: flags.String("engine", "cached", "engine executing instructions: interpreter, cached or compiled")}
+}
+
+// attach sets the selected engine of chip.
+func (e engineFlag) attach(chip *chip8.Chip8) error {
+	newEngine, ok := engines[*e.name]
+	if !ok {
+		return fmt.Errorf("unknown engine %q", *e.name)
+	}
+	chip.Engine = newEngine(chip)
+	return nil
+}
+
+// quirksFlag selects the quirk profile of the chip.
+type quirksFlag struct {
+	name *string
+}
+
+func addQuirksFlag(flags *flag.FlagSet) quirksFlag {
+	usage := "behaviour of ambiguous instructions: " + strings.Join(chip8.QuirkProfileNames(), ", ")
+	return quirksFlag{name: flags.String("quirks", "chip8", usage)}
+}
+
+// attach sets quirks of the selected profile to chip.
+func (q quirksFlag) attach(chip *chip8.Chip8) error {
+	quirks, err := chip8.QuirkProfile(*q.name)
+	if err != nil {
+		return err
+	}
+	chip.Quirks = quirks
+	return nil
+}
+
+// platformFlag selects the variant of CHIP-8.
+type platformFlag struct {
+	name *string
+}
+
+func addPlatformFlag(flags *flag.FlagSet) platformFlag {
+	usage := "variant of CHIP-8: " + strings.Join(chip8.PlatformNames(), ", ")
+	return platformFlag{name: flags.String("platform", "chip8", usage)}
+}
+
+// platform returns the selected platform.
+func (p platformFlag) platform() (chip8.Platform, error) {
+	return chip8.ParsePlatform(*p.name)
+}
+
+// attach sets the selected platform to chip before the font and the program are loaded.
+func (p platformFlag) attach(chip *chip8.Chip8) error {
+	platform, err := p.platform()
+	if err != nil {
+		return err
+	}
+	chip.SetPlatform(platform)
+	return nil
+}
+
+// timingFlags select the speed of instructions.
+type timingFlags struct {
+	tickrate *int
+	ips      *int
+	timing   *string
+}
+
+func addTimingFlags(flags *flag.FlagSet) timingFlags {
+	return timingFlags{
+		tickrate: flags.Int("tickrate", chip8.DefaultTickrate, "number of instructions executed per frame"),
+		ips:      flags.Int("ips", 0, "number of instructions executed per second, overrides -tickrate"),
+		timing:   flags.String("timing", "uniform", "cost of instructions: uniform or vip (cycles of the COSMAC VIP)"),
+	}
+}
+
+// followsTickrate reports whether the speed is set by the tickrate, so it can be changed while running.
+func (t timingFlags) followsTickrate() bool {
+	return *t.timing == "uniform" && *t.ips <= 0
+}
+
+// attach sets the selected timing of chip.
+func (t timingFlags) attach(chip *chip8.Chip8) error {
+	switch *t.timing {
+	case "uniform":
+		chip.Timing = chip8.TickrateTiming(*t.tickrate)
+		if *t.ips > 0 {
+			chip.Timing = chip8.InstructionsPerSecond(*t.ips)
+		}
+	case "vip":
+		chip.Timing = chip8.VIPTiming
+	default:
+		return fmt.Errorf("unknown timing %q", *t.timing)
+	}
+	return nil
+}
+
+// traceFlags are flags enabling the execution trace.
+type traceFlags struct {
+	path   *string
+	format *string
+}
+
+func addTraceFlags(flags *flag.FlagSet) traceFlags {
+	return traceFlags{
+		path:   flags.String("trace", "", "write state after every instruction to this file"),
+		format: flags.String("trace-format", "text", "format of the trace, text or binary"),
+	}
+}
+
+// attach creates the trace file and sets it as the tracer of chip, the platform has to be set
+// before so instructions are disassembled for it. The returned function flushes and closes
+// the file, it does nothing if tracing is disabled.
+func (t traceFlags) attach(chip *chip8.Chip8) (func() error, error) {
+	if *t.path == "" {
+		return func() error { return nil }, nil
+	}
+	if *t.format != "text" && *t.format != "binary" {
+		return nil, fmt.Errorf("unknown trace format %q", *t.format)
+	}
+
+	file, err := os.Create(*t.path)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := chip8.NewTextTraceWriter(file, chip.Platform)
+	if *t.format == "binary" {
+		writer = chip8.NewBinaryTraceWriter(file)
+	}
+	chip.Tracer = writer
+
+	return func() error {
+		chip.Tracer = nil
+		if err := writer.Flush(); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}, nil
+}
@@ -0,0 +1,336 @@
+// Package cosmac emulates the COSMAC VIP at the hardware level: the RCA CDP1802 CPU, the memory
+// map of the VIP and the CDP1861 video chip. Chip8 programs are executed by the original
+// interpreter running on the emulated CPU instead of the Emulate switch of package chip8.
+package cosmac
+
+// Bus connects the CPU to memory and devices.
+type Bus interface {
+	// Read returns the byte at address.
+	Read(address uint16) byte
+	// Write stores value at address.
+	Write(address uint16, value byte)
+	// Output is called by OUT 1-7 with the byte read from memory.
+	Output(port, value byte)
+	// Input is called by INP 1-7 and returns the byte stored into memory.
+	Input(port byte) byte
+	// Flag reports whether the external flag EF1-EF4 is asserted.
+	Flag(flag byte) bool
+}
+
+// CPU is the state of the RCA CDP1802.
+type CPU struct {
+	// R contains the 16 scratchpad registers, R(P) is the program counter and R(X) the data pointer.
+	R [16]uint16
+	// P selects the program counter, X the data pointer.
+	P, X byte
+	// D is the accumulator and DF its carry.
+	D  byte
+	DF bool
+	// Q is the output flip-flop, on the VIP it drives the tone of the speaker.
+	Q bool
+	// IE enables interrupts, T holds X and P saved by the last interrupt.
+	IE bool
+	T  byte
+	// Idle is set by IDL until an interrupt or DMA request.
+	Idle bool
+
+	Bus Bus
+}
+
+// Reset puts the CPU into the state after the reset signal, execution starts at 0 with R0.
+func (c *CPU) Reset() {
+	c.R[0] = 0
+	c.P, c.X, c.Q, c.IE, c.Idle = 0, 0, false, true, false
+}
+
+// fetch reads the byte at the program counter and advances it.
+func (c *CPU) fetch() byte {
+	value := c.Bus.Read(c.R[c.P])
+	c.R[c.P]++
+	return value
+}
+
+// Interrupt saves X and P into T and continues with R1 as the program counter and R2 as the data
+// pointer. It does nothing if interrupts are disabled and returns the number of machine cycles.
+func (c *CPU) Interrupt() int {
+	if !c.IE {
+		return 0
+	}
+	c.T = c.X<<4 | c.P
+	c.X, c.P = 2, 1
+	c.IE = false
+	c.Idle = false
+	return 1
+}
+
+// DMAOut reads the byte at R0 for a device and advances R0, it takes one machine cycle.
+func (c *CPU) DMAOut() byte {
+	value := c.Bus.Read(c.R[0])
+	c.R[0]++
+	c.Idle = false
+	return value
+}
+
+// Step executes one instruction and returns the number of machine cycles it took, long branches
+// and skips take 3 cycles and the rest 2. An idle CPU doesn't execute anything.
+func (c *CPU) Step() int {
+	if c.Idle {
+		return 1
+	}
+
+	opcode := c.fetch()
+	i, n := opcode>>4, opcode&0xf
+	switch i {
+	case 0x0:
+		if n == 0 {
+			// IDL
+			c.Idle = true
+		} else {
+			// LDN
+			c.D = c.Bus.Read(c.R[n])
+		}
+	case 0x1:
+		c.R[n]++
+	case 0x2:
+		c.R[n]--
+	case 0x3:
+		c.shortBranch(n)
+	case 0x4:
+		// LDA
+		c.D = c.Bus.Read(c.R[n])
+		c.R[n]++
+	case 0x5:
+		// STR
+		c.Bus.Write(c.R[n], c.D)
+	case 0x6:
+		c.inputOutput(n)
+	case 0x7:
+		c.control(n)
+	case 0x8:
+		c.D = byte(c.R[n])
+	case 0x9:
+		c.D = byte(c.R[n] >> 8)
+	case 0xa:
+		c.R[n] = c.R[n]&0xff00 | uint16(c.D)
+	case 0xb:
+		c.R[n] = c.R[n]&0x00ff | uint16(c.D)<<8
+	case 0xc:
+		c.longBranch(n)
+		return 3
+	case 0xd:
+		c.P = n
+	case 0xe:
+		c.X = n
+	case 0xf:
+		c.arithmetic(n)
+	}
+	return 2
+}
+
+// condition evaluates the condition of branches 1-7 and their negations 9-F, 0 and 8 are always
+// and never true.
+func (c *CPU) condition(n byte) bool {
+	var result bool
+	switch n & 7 {
+	case 0:
+		result = true
+	case 1:
+		result = c.Q
+	case 2:
+		result = c.D == 0
+	case 3:
+		result = c.DF
+	default:
+		result = c.Bus.Flag(n&7 - 3)
+	}
+	if n >= 8 {
+		return !result
+	}
+	return result
+}
+
+// shortBranch replaces the low byte of the program counter with the next byte if the condition holds.
+func (c *CPU) shortBranch(n byte) {
+	pc := c.R[c.P]
+	if c.condition(n) {
+		c.R[c.P] = pc&0xff00 | uint16(c.Bus.Read(pc))
+	} else {
+		c.R[c.P] = pc + 1
+	}
+}
+
+// longBranch jumps to the next two bytes, or skips them, if the condition holds. Skips use
+// conditions of their own.
+func (c *CPU) longBranch(n byte) {
+	pc := c.R[c.P]
+	var skip, taken bool
+	switch n {
+	case 0x4:
+		// NOP
+		return
+	case 0x5:
+		skip, taken = true, !c.Q
+	case 0x6:
+		skip, taken = true, c.D != 0
+	case 0x7:
+		skip, taken = true, !c.DF
+	case 0x8:
+		skip, taken = true, true
+	case 0xc:
+		skip, taken = true, c.IE
+	case 0xd:
+		skip, taken = true, c.Q
+	case 0xe:
+		skip, taken = true, c.D == 0
+	case 0xf:
+		skip, taken = true, c.DF
+	default:
+		// the conditions of long branches match short branches without the external flags
+		taken = c.condition(n)
+	}
+
+	switch {
+	case skip && taken:
+		c.R[c.P] = pc + 2
+	case skip:
+	case taken:
+		c.R[c.P] = uint16(c.Bus.Read(pc))<<8 | uint16(c.Bus.Read(pc+1))
+	default:
+		c.R[c.P] = pc + 2
+	}
+}
+
+// inputOutput executes IRX, OUT 1-7 and INP 1-7.
+func (c *CPU) inputOutput(n byte) {
+	switch {
+	case n == 0:
+		c.R[c.X]++
+	case n < 8:
+		c.Bus.Output(n, c.Bus.Read(c.R[c.X]))
+		c.R[c.X]++
+	case n > 8:
+		value := c.Bus.Input(n - 8)
+		c.Bus.Write(c.R[c.X], value)
+		c.D = value
+	}
+}
+
+// control executes the 7N group: returns, memory reference with R(X), Q and carry arithmetic.
+func (c *CPU) control(n byte) {
+	switch n {
+	case 0x0, 0x1:
+		// RET and DIS
+		value := c.Bus.Read(c.R[c.X])
+		c.R[c.X]++
+		c.X, c.P = value>>4, value&0xf
+		c.IE = n == 0
+	case 0x2:
+		// LDXA
+		c.D = c.Bus.Read(c.R[c.X])
+		c.R[c.X]++
+	case 0x3:
+		// STXD
+		c.Bus.Write(c.R[c.X], c.D)
+		c.R[c.X]--
+	case 0x4:
+		c.add(c.Bus.Read(c.R[c.X]), c.DF)
+	case 0x5:
+		c.subtract(c.Bus.Read(c.R[c.X]), c.D, c.DF)
+	case 0x6:
+		// SHRC
+		carry := c.DF
+		c.DF = c.D&1 != 0
+		c.D >>= 1
+		if carry {
+			c.D |= 0x80
+		}
+	case 0x7:
+		c.subtract(c.D, c.Bus.Read(c.R[c.X]), c.DF)
+	case 0x8:
+		// SAV
+		c.Bus.Write(c.R[c.X], c.T)
+	case 0x9:
+		// MARK
+		c.T = c.X<<4 | c.P
+		c.Bus.Write(c.R[2], c.T)
+		c.X = c.P
+		c.R[2]--
+	case 0xa:
+		c.Q = false
+	case 0xb:
+		c.Q = true
+	case 0xc:
+		c.add(c.fetch(), c.DF)
+	case 0xd:
+		c.subtract(c.fetch(), c.D, c.DF)
+	case 0xe:
+		// SHLC
+		carry := c.DF
+		c.DF = c.D&0x80 != 0
+		c.D <<= 1
+		if carry {
+			c.D |= 1
+		}
+	case 0xf:
+		c.subtract(c.D, c.fetch(), c.DF)
+	}
+}
+
+// arithmetic executes the FN group, logic and arithmetic with M(R(X)) or the immediate byte.
+func (c *CPU) arithmetic(n byte) {
+	var operand byte
+	switch {
+	case n == 0x6 || n == 0xe:
+		// shifts have no operand
+	case n < 8:
+		operand = c.Bus.Read(c.R[c.X])
+	default:
+		operand = c.fetch()
+	}
+
+	switch n & 7 {
+	case 0x0:
+		c.D = operand
+	case 0x1:
+		c.D |= operand
+	case 0x2:
+		c.D &= operand
+	case 0x3:
+		c.D ^= operand
+	case 0x4:
+		c.add(operand, false)
+	case 0x5:
+		c.subtract(operand, c.D, true)
+	case 0x6:
+		if n == 0x6 {
+			c.DF = c.D&1 != 0
+			c.D >>= 1
+		} else {
+			c.DF = c.D&0x80 != 0
+			c.D <<= 1
+		}
+	case 0x7:
+		c.subtract(c.D, operand, true)
+	}
+}
+
+// add sets D to D plus value plus carry, DF is the carry out.
+func (c *CPU) add(value byte, carry bool) {
+	sum := int(c.D) + int(value)
+	if carry {
+		sum++
+	}
+	c.D = byte(sum)
+	c.DF = sum > 0xff
+}
+
+// subtract sets D to a minus b, noBorrow is DF of the previous subtraction. DF is 1 if there was
+// no borrow.
+func (c *CPU) subtract(a, b byte, noBorrow bool) {
+	difference := int(a) - int(b)
+	if !noBorrow {
+		difference--
+	}
+	c.D = byte(difference)
+	c.DF = difference >= 0
+}
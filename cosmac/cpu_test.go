@@ -0,0 +1,170 @@
+package cosmac
+
+import "testing"
+
+// testBus is 64 KB of RAM with flags set by the test.
+type testBus struct {
+	memory  [0x10000]byte
+	flags   [5]bool
+	outputs []byte
+}
+
+func (b *testBus) Read(address uint16) byte         { return b.memory[address] }
+func (b *testBus) Write(address uint16, value byte) { b.memory[address] = value }
+func (b *testBus) Output(port, value byte)          { b.outputs = append(b.outputs, port, value) }
+func (b *testBus) Input(port byte) byte             { return 0x40 + port }
+func (b *testBus) Flag(flag byte) bool              { return b.flags[flag] }
+
+// newTestCPU creates CPU after reset with program at 0.
+func newTestCPU(program ...byte) (*CPU, *testBus) {
+	bus := &testBus{}
+	copy(bus.memory[:], program)
+	cpu := &CPU{Bus: bus}
+	cpu.Reset()
+	return cpu, bus
+}
+
+// run executes n instructions and returns the number of machine cycles they took.
+func run(cpu *CPU, n int) int {
+	cycles := 0
+	for i := 0; i < n; i++ {
+		cycles += cpu.Step()
+	}
+	return cycles
+}
+
+func TestCPU(t *testing.T) {
+	t.Run("Load immediate and store through a register", func(t *testing.T) {
+		// LDI 0x12, PHI R3, LDI 0x34, PLO R3, LDI 0xAB, STR R3
+		cpu, bus := newTestCPU(0xf8, 0x12, 0xb3, 0xf8, 0x34, 0xa3, 0xf8, 0xab, 0x53)
+
+		cycles := run(cpu, 6)
+
+		if cpu.R[3] != 0x1234 || bus.memory[0x1234] != 0xab || cycles != 12 {
+			t.Errorf("got R3 0x%X, 0x%X at 0x1234 in %d cycles, want R3 0x1234, 0xAB in 12 cycles", cpu.R[3], bus.memory[0x1234], cycles)
+		}
+	})
+
+	arithmeticTests := []struct {
+		name    string
+		program []byte
+		d       byte
+		df      bool
+	}{
+		{"ADI sets DF on carry", []byte{0xf8, 0xf0, 0xfc, 0x20}, 0x10, true},
+		{"ADCI adds DF", []byte{0xf8, 0xf0, 0xfc, 0x20, 0x7c, 0x01}, 0x12, false},
+		{"SMI sets DF without borrow", []byte{0xf8, 0x20, 0xff, 0x10}, 0x10, true},
+		{"SMI clears DF on borrow", []byte{0xf8, 0x10, 0xff, 0x20}, 0xf0, false},
+		{"SDI subtracts D from the immediate byte", []byte{0xf8, 0x10, 0xfd, 0x30}, 0x20, true},
+		{"SMBI subtracts the borrow", []byte{0xf8, 0x10, 0xff, 0x20, 0x7f, 0x00}, 0xef, true},
+		{"SHL shifts the top bit into DF", []byte{0xf8, 0x81, 0xfe}, 0x02, true},
+		{"SHRC shifts DF into the top bit", []byte{0xf8, 0x81, 0xfe, 0xf8, 0x02, 0x76}, 0x81, false},
+		{"XRI xors the immediate byte", []byte{0xf8, 0x0f, 0xfb, 0xff}, 0xf0, false},
+	}
+	for _, test := range arithmeticTests {
+		t.Run(test.name, func(t *testing.T) {
+			cpu, _ := newTestCPU(test.program...)
+
+			for cpu.R[0] < uint16(len(test.program)) {
+				cpu.Step()
+			}
+
+			if cpu.D != test.d || cpu.DF != test.df {
+				t.Errorf("got D 0x%02X, DF %v, want D 0x%02X, DF %v", cpu.D, cpu.DF, test.d, test.df)
+			}
+		})
+	}
+
+	branchTests := []struct {
+		name    string
+		program []byte
+		flags   [5]bool
+		pc      uint16
+		cycles  int
+	}{
+		{"BZ branches when D is 0", []byte{0x32, 0x40}, [5]bool{}, 0x40, 2},
+		{"BNZ doesn't branch when D is 0", []byte{0x3a, 0x40}, [5]bool{}, 0x02, 2},
+		{"B3 branches when EF3 is asserted", []byte{0x36, 0x40}, [5]bool{3: true}, 0x40, 2},
+		{"BN1 doesn't branch when EF1 is asserted", []byte{0x3c, 0x40}, [5]bool{1: true}, 0x02, 2},
+		{"SKP skips the next byte", []byte{0x38, 0x40}, [5]bool{}, 0x02, 2},
+		{"LBR jumps to the next two bytes", []byte{0xc0, 0x12, 0x34}, [5]bool{}, 0x1234, 3},
+		{"LBNZ doesn't branch when D is 0", []byte{0xca, 0x12, 0x34}, [5]bool{}, 0x03, 3},
+		{"LSZ skips two bytes when D is 0", []byte{0xce, 0x12, 0x34}, [5]bool{}, 0x03, 3},
+		{"LSNZ doesn't skip when D is 0", []byte{0xc6, 0x12, 0x34}, [5]bool{}, 0x01, 3},
+		{"NOP takes 3 cycles", []byte{0xc4}, [5]bool{}, 0x01, 3},
+	}
+	for _, test := range branchTests {
+		t.Run(test.name, func(t *testing.T) {
+			cpu, bus := newTestCPU(test.program...)
+			bus.flags = test.flags
+
+			cycles := cpu.Step()
+
+			if cpu.R[0] != test.pc || cycles != test.cycles {
+				t.Errorf("got pc 0x%X in %d cycles, want 0x%X in %d cycles", cpu.R[0], cycles, test.pc, test.cycles)
+			}
+		})
+	}
+
+	t.Run("MARK and RET call a subroutine with another program counter", func(t *testing.T) {
+		// 0x00: LDI 0x10, PLO R2, LDI 0x20, PLO R3, MARK, SEP R3
+		// 0x20: SEX 2, INC R2, RET
+		cpu, bus := newTestCPU(0xf8, 0x10, 0xa2, 0xf8, 0x20, 0xa3, 0x79, 0xd3)
+		copy(bus.memory[0x20:], []byte{0xe2, 0x12, 0x70})
+
+		run(cpu, 9)
+
+		if cpu.P != 0 || cpu.X != 0 || cpu.R[0] != 0x08 || cpu.R[2] != 0x11 || !cpu.IE {
+			t.Errorf("got P %d, X %d, R0 0x%X, R2 0x%X, IE %v, want P 0, X 0, R0 0x08, R2 0x11, IE true",
+				cpu.P, cpu.X, cpu.R[0], cpu.R[2], cpu.IE)
+		}
+	})
+
+	t.Run("Interrupt saves X and P and switches to R1 and R2", func(t *testing.T) {
+		cpu, _ := newTestCPU()
+		cpu.X, cpu.P = 3, 4
+
+		cycles := cpu.Interrupt()
+
+		if cpu.T != 0x34 || cpu.X != 2 || cpu.P != 1 || cpu.IE || cycles != 1 {
+			t.Errorf("got T 0x%02X, X %d, P %d, IE %v in %d cycles, want T 0x34, X 2, P 1, IE false in 1 cycle",
+				cpu.T, cpu.X, cpu.P, cpu.IE, cycles)
+		}
+		if cpu.Interrupt() != 0 {
+			t.Errorf("expected disabled interrupts to be ignored")
+		}
+	})
+
+	t.Run("OUT reads memory at R(X) and INP writes it", func(t *testing.T) {
+		// LDI 0x40, PLO R2, SEX 2, OUT 2, INP 1
+		cpu, bus := newTestCPU(0xf8, 0x40, 0xa2, 0xe2, 0x62, 0x69)
+		bus.memory[0x40] = 0x07
+
+		run(cpu, 5)
+
+		if len(bus.outputs) != 2 || bus.outputs[0] != 2 || bus.outputs[1] != 0x07 {
+			t.Errorf("got outputs %X, want 02 07", bus.outputs)
+		}
+		if bus.memory[0x41] != 0x41 || cpu.D != 0x41 {
+			t.Errorf("got 0x%02X at 0x41 and D 0x%02X, want 0x41", bus.memory[0x41], cpu.D)
+		}
+	})
+
+	t.Run("IDL waits until DMA", func(t *testing.T) {
+		// SEP R3 with IDL at 0x10
+		cpu, bus := newTestCPU(0xd3)
+		cpu.R[3] = 0x10
+		bus.memory[0x10] = 0x00
+		run(cpu, 4)
+
+		if !cpu.Idle || cpu.R[3] != 0x11 {
+			t.Fatalf("got idle %v at 0x%X, want idle at 0x11", cpu.Idle, cpu.R[3])
+		}
+
+		cpu.DMAOut()
+
+		if cpu.Idle || cpu.R[0] != 0x02 {
+			t.Errorf("got idle %v, R0 0x%X, want running with R0 0x02", cpu.Idle, cpu.R[0])
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package cosmac
+
+import (
+	"chip8emulator/chip8"
+	"fmt"
+)
+
+// Timing of the CDP1861, it displays 128 of the 262 lines of a frame and every line takes 14
+// machine cycles of the CPU, 8 of them transfer the bytes of the line by DMA.
+const (
+	CyclesPerLine  = 14
+	LinesPerFrame  = 262
+	CyclesPerFrame = CyclesPerLine * LinesPerFrame
+	DisplayLines   = 128
+	// firstDisplayLine is the first line transferred by DMA.
+	firstDisplayLine = 80
+	// interruptCycle is the cycle of the frame when the interrupt is requested, 29 cycles before
+	// the first DMA so the interrupt routine can set R0 to the display buffer.
+	interruptCycle = firstDisplayLine*CyclesPerLine - 29
+)
+
+// InterpreterSize is the size of the original CHIP-8 interpreter, programs start after it.
+const InterpreterSize = 0x200
+
+// monitorInterrupt is the display interrupt routine of the VIP monitor at 0x8143-0x816D. The
+// interpreter points R1 at 0x8146, it transfers 4 lines of every row of the display buffer at
+// RB.1 and decrements the timers in R8.1 and R8.0, Q sounds the tone while R8.0 isn't 0.
+var monitorInterrupt = []byte{
+	0x7a, 0x72, 0x70, 0x22, 0x78, 0x22, 0x52, 0xc4, 0x19, 0xf8, 0x00, 0xa0, 0x9b, 0xb0, 0xe2, 0xe2,
+	0x80, 0xe2, 0xe2, 0x20, 0xa0, 0xe2, 0x20, 0xa0, 0xe2, 0x20, 0xa0, 0x3c, 0x53, 0x98, 0x32, 0x67,
+	0xab, 0x2b, 0x8b, 0xb8, 0x88, 0x32, 0x43, 0x7b, 0x28, 0x30, 0x44,
+}
+
+// VIP is the COSMAC VIP: RAM from address 0, the ROM at 0x8000 and the CDP1861 video chip.
+// The monitor program of the ROM isn't emulated, only its interrupt routine is included and
+// execution starts at 0 as if the monitor was left by the reset switch.
+type VIP struct {
+	CPU CPU
+	// RAM is mirrored up to 0x8000.
+	RAM []byte
+	// ROM is mirrored from 0x8000 to the end of memory.
+	ROM    []byte
+	Keypad chip8.Keypad
+	// Frame contains lines transferred to the CDP1861 in the last frame, 8 bytes with 64 pixels
+	// in each. Lines aren't transferred while the display is off.
+	Frame [DisplayLines][8]byte
+
+	displayOn bool
+	// keyLatch is the key selected by OUT 2, EF3 is asserted while it's held down
+	keyLatch byte
+	// cycle is the machine cycle of the current frame
+	cycle int
+	// line is the next line transferred by DMA
+	line int
+	// interrupted is set when the interrupt of the current frame was taken
+	interrupted bool
+}
+
+// NewVIP creates VIP with ramSize bytes of RAM, e.g. 2048 or 4096, with the interpreter loaded
+// at 0 and the program after it. The interpreter uses the last 352 bytes of RAM for variables,
+// the stack and the display buffer.
+func NewVIP(interpreter, program []byte, ramSize int) (*VIP, error) {
+	if ramSize < 2048 || ramSize > 0x8000 || ramSize%256 != 0 {
+		return nil, fmt.Errorf("RAM size %d isn't a number of pages from 2048 to 32768", ramSize)
+	}
+	if len(interpreter) > InterpreterSize {
+		return nil, fmt.Errorf("interpreter has %d bytes, at most %d fit before the program", len(interpreter), InterpreterSize)
+	}
+	if space := ramSize - 0x160 - InterpreterSize; len(program) > space {
+		return nil, fmt.Errorf("program has %d bytes, at most %d fit in %d bytes of RAM", len(program), space, ramSize)
+	}
+
+	v := &VIP{RAM: make([]byte, ramSize), ROM: make([]byte, 0x200), Keypad: chip8.NewMovieKeypad(nil)}
+	copy(v.RAM, interpreter)
+	copy(v.RAM[InterpreterSize:], program)
+	copy(v.ROM[0x143:], monitorInterrupt)
+
+	v.CPU.Bus = v
+	v.CPU.Reset()
+	// the monitor leaves the page at the end of RAM in R1.1, the interpreter puts its display there
+	v.CPU.R[1] = uint16(ramSize/256-1) << 8
+	return v, nil
+}
+
+func (v *VIP) Read(address uint16) byte {
+	if address&0x8000 != 0 {
+		return v.ROM[int(address)%len(v.ROM)]
+	}
+	return v.RAM[int(address)%len(v.RAM)]
+}
+
+func (v *VIP) Write(address uint16, value byte) {
+	if address&0x8000 == 0 {
+		v.RAM[int(address)%len(v.RAM)] = value
+	}
+}
+
+// Output turns the display off on port 1 and latches the key checked by EF3 on port 2.
+func (v *VIP) Output(port, value byte) {
+	switch port {
+	case 1:
+		v.displayOn = false
+	case 2:
+		v.keyLatch = value & 0xf
+	}
+}
+
+// Input turns the display on on port 1, nothing drives the bus so it reads 0.
+func (v *VIP) Input(port byte) byte {
+	if port == 1 {
+		v.displayOn = true
+	}
+	return 0
+}
+
+// Flag asserts EF1 during the 4 lines before the start and the end of the display and EF3 while
+// the latched key is held down.
+func (v *VIP) Flag(flag byte) bool {
+	switch flag {
+	case 1:
+		line := v.cycle / CyclesPerLine
+		return (line >= firstDisplayLine-4 && line < firstDisplayLine) ||
+			(line >= firstDisplayLine+DisplayLines-4 && line < firstDisplayLine+DisplayLines)
+	case 3:
+		return v.Keypad.IsKeyDown(v.keyLatch)
+	}
+	return false
+}
+
+// RunTick executes one frame of the CDP1861, 1/60 of a second. It returns true if Q was set
+// during the frame, so the buzzer should sound.
+func (v *VIP) RunTick() bool {
+	v.Frame = [DisplayLines][8]byte{}
+	v.line = 0
+	v.interrupted = false
+	sound := v.CPU.Q
+
+	for v.cycle < CyclesPerFrame {
+		// DMA is requested at the start of a line and serviced after the instruction fetched next
+		request := (firstDisplayLine + v.line) * CyclesPerLine
+		dma := v.displayOn && v.line < DisplayLines && v.cycle >= request
+
+		switch {
+		case dma && v.CPU.Idle:
+			v.transferLine()
+		case v.CPU.Idle:
+			v.cycle++
+		default:
+			v.cycle += v.CPU.Step()
+			if dma {
+				v.transferLine()
+			}
+		}
+
+		if v.displayOn && !v.interrupted && v.cycle >= interruptCycle && v.cycle < firstDisplayLine*CyclesPerLine {
+			if cycles := v.CPU.Interrupt(); cycles > 0 {
+				v.cycle += cycles
+				v.interrupted = true
+			}
+		}
+		sound = sound || v.CPU.Q
+	}
+	v.cycle -= CyclesPerFrame
+	return sound
+}
+
+// transferLine reads the next line of the display from R0, one byte per machine cycle.
+func (v *VIP) transferLine() {
+	for k := range v.Frame[v.line] {
+		v.Frame[v.line][k] = v.CPU.DMAOut()
+	}
+	v.cycle += len(v.Frame[v.line])
+	v.line++
+}
+
+// CopyTo shows the state of the VIP in chip, so the frontend can draw and inspect it. The frame
+// is scaled to the size of the screen of chip, registers are read where the original
+// interpreter keeps them: V0-VF at 0xEF0 of 4 KB of RAM, PC in R5, I in RA and the delay and
+// sound timers in R8.
+func (v *VIP) CopyTo(chip *chip8.Chip8) {
+	copy(chip.Memory, v.RAM)
+	variables := len(v.RAM) - 0x110
+	copy(chip.Registers, v.RAM[variables:variables+16])
+	chip.Pc = v.CPU.R[5]
+	chip.I = v.CPU.R[0xa]
+	chip.Timers[0], chip.Timers[1] = byte(v.CPU.R[8]>>8), byte(v.CPU.R[8])
+
+	for y := 0; y < int(chip.Height); y++ {
+		line := v.Frame[y*DisplayLines/int(chip.Height)]
+		for x := 0; x < int(chip.Width); x++ {
+			pixel := chip.SecondaryColor
+			if column := x * 64 / int(chip.Width); line[column/8]&(0x80>>(column%8)) != 0 {
+				pixel = chip.PrimaryColor
+			}
+			chip.Screen[x+y*int(chip.Width)] = pixel
+		}
+	}
+}
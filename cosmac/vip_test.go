@@ -0,0 +1,166 @@
+package cosmac
+
+import (
+	"chip8emulator/chip8"
+	"strings"
+	"testing"
+)
+
+// displaySetup is the start of the interpreter without CHIP-8: it points R1 at the interrupt
+// routine, R2 at the stack, RB at the display buffer, sets the delay timer to 3 and the sound
+// timer to 2 and turns the display on. The display uses R0 for DMA, so the program continues
+// with R3 as the program counter in the loop waiting for interrupts appended at 0x1B.
+var displaySetup = []byte{
+	0xf8, 0x81, 0xb1, 0xf8, 0x46, 0xa1, // R1 = 0x8146
+	0xf8, 0x0e, 0xb2, 0xf8, 0xcf, 0xa2, // R2 = 0x0ECF
+	0xf8, 0x0f, 0xbb, // RB.1 = 0x0F
+	0xf8, 0x03, 0xb8, 0xf8, 0x02, 0xa8, // R8 = 0x0302
+	0xe2, 0x69, // SEX 2, INP 1
+	0xf8, 0x1b, 0xa3, 0xd3, // R3 = 0x1B, SEP R3
+}
+
+// newDisplayVIP creates VIP running displaySetup followed by loop, every row of the display
+// buffer contains its number.
+func newDisplayVIP(t *testing.T, loop ...byte) *VIP {
+	t.Helper()
+	vip, err := NewVIP(append(displaySetup, loop...), nil, 4096)
+	if err != nil {
+		t.Fatalf("didn't expect an error, got %v", err)
+	}
+	for row := 0; row < 32; row++ {
+		for k := 0; k < 8; k++ {
+			vip.RAM[0xf00+row*8+k] = byte(row)
+		}
+	}
+	return vip
+}
+
+func TestVIP(t *testing.T) {
+	// the interrupt is taken after the instruction running when it's requested, loops of 2 and
+	// 3 cycle instructions interrupt the CPU in every cycle of them
+	loops := [][]byte{{0x00, 0x30, 0x1b}, {0xc0, 0x00, 0x1b}}
+	for mask := 0; mask < 32; mask++ {
+		for n := 1; n <= 5; n++ {
+			var loop []byte
+			for k := 0; k < n; k++ {
+				if mask&(1<<k) != 0 {
+					loop = append(loop, 0xc4)
+				} else {
+					loop = append(loop, 0xe2)
+				}
+			}
+			loops = append(loops, append(loop, 0x30, 0x1b))
+		}
+	}
+	t.Run("Interrupt routine displays every row 4 times whatever is interrupted", func(t *testing.T) {
+		for _, loop := range loops {
+			vip := newDisplayVIP(t, loop...)
+
+			for frame := 0; frame < 3; frame++ {
+				vip.RunTick()
+				for line, bytes := range vip.Frame {
+					for _, value := range bytes {
+						if int(value) != line/4 {
+							t.Fatalf("got line %d of frame %d %X with loop %X, want row %d", line, frame, bytes, loop, line/4)
+						}
+					}
+				}
+			}
+		}
+	})
+
+	t.Run("Interrupt routine decrements timers and sounds the tone", func(t *testing.T) {
+		vip := newDisplayVIP(t, 0x30, 0x1b)
+
+		got := []bool{vip.RunTick(), vip.RunTick(), vip.RunTick(), vip.RunTick()}
+
+		if vip.CPU.R[8] != 0 || !got[0] || !got[1] || got[3] {
+			t.Errorf("got R8 0x%04X and tone %v, want R8 0 and tone in the first 2 frames", vip.CPU.R[8], got)
+		}
+	})
+
+	t.Run("Nothing is displayed while the display is off", func(t *testing.T) {
+		vip := newDisplayVIP(t, 0xe2, 0x61, 0x30, 0x1d)
+
+		vip.RunTick()
+
+		if vip.Frame != [DisplayLines][8]byte{} {
+			t.Errorf("expected an empty frame")
+		}
+	})
+
+	t.Run("EF3 is asserted while the latched key is held down", func(t *testing.T) {
+		vip, err := NewVIP(nil, nil, 4096)
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		movie, err := chip8.ParseMovie(strings.NewReader("0 5"))
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		keypad := chip8.NewMovieKeypad(movie)
+		keypad.SetFrame(0)
+		vip.Keypad = keypad
+
+		vip.Output(2, 0x04)
+		if vip.Flag(3) {
+			t.Errorf("didn't expect EF3 for key 4")
+		}
+		vip.Output(2, 0x05)
+		if !vip.Flag(3) {
+			t.Errorf("expected EF3 for key 5")
+		}
+	})
+
+	t.Run("ROM and RAM are mirrored", func(t *testing.T) {
+		vip, err := NewVIP(nil, []byte{0xab}, 2048)
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		vip.Write(0x8000, 0x12)
+
+		if vip.Read(0x2200) != 0xab || vip.Read(0xc343) != 0x7a || vip.Read(0x8000) != 0 {
+			t.Errorf("got 0x%02X, 0x%02X and 0x%02X, want 0xAB, 0x7A and 0", vip.Read(0x2200), vip.Read(0xc343), vip.Read(0x8000))
+		}
+	})
+
+	t.Run("Copy state to chip8", func(t *testing.T) {
+		vip := newDisplayVIP(t, 0x30, 0x1b)
+		vip.RAM[0xef3] = 0x42
+		vip.CPU.R[5] = 0x234
+		vip.RunTick()
+		chip := chip8.NewChip8()
+
+		vip.CopyTo(chip)
+
+		if chip.Registers[3] != 0x42 || chip.Pc != 0x234 || chip.Timers[0] != 2 {
+			t.Errorf("got V3 0x%02X, pc 0x%X, delay %d, want 0x42, 0x234, 2", chip.Registers[3], chip.Pc, chip.Timers[0])
+		}
+		// row 1 contains 0x01, the last pixel of every byte is on
+		if chip.Screen[7+64] != chip.PrimaryColor || chip.Screen[6+64] != chip.SecondaryColor {
+			t.Errorf("expected only the last pixel of the byte on")
+		}
+	})
+
+	errorTests := []struct {
+		name        string
+		interpreter []byte
+		program     []byte
+		ramSize     int
+	}{
+		{"interpreter is larger than 512 bytes", make([]byte, 0x201), nil, 4096},
+		{"program doesn't fit before the variables", nil, make([]byte, 4096-0x160-0x1ff), 4096},
+		{"RAM isn't a number of pages", nil, nil, 3000},
+		{"RAM is smaller than 2 KB", nil, nil, 1024},
+	}
+	for _, test := range errorTests {
+		t.Run("Return error if "+test.name, func(t *testing.T) {
+			_, err := NewVIP(test.interpreter, test.program, test.ramSize)
+
+			if err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"chip8emulator/chip8"
+	"chip8emulator/debugger"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// defaultDAPTickrate is used when the launch configuration doesn't set the tickrate.
+const defaultDAPTickrate = 10
+
+// runDAP serves the Debug Adapter Protocol, by default on stdin and stdout as editors expect
+// from a debug adapter started as a process. The program is chosen by the launch request.
+func runDAP(args []string) error {
+	flags := flag.NewFlagSet("dap", flag.ContinueOnError)
+	addr := flags.String("addr", "", "address the server listens on instead of using stdin and stdout")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *addr != "" {
+		fmt.Fprintf(os.Stdout, "waiting for a debug adapter client on %s\n", *addr)
+		return debugger.ListenDAP(*addr, launchDAP)
+	}
+
+	stdio := struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}
+	err := (&debugger.DAPServer{Launch: launchDAP}).Serve(stdio)
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// launchDAP creates debugger of a headless run described by the launch configuration.
+func launchDAP(args debugger.LaunchArguments) (*debugger.Debugger, error) {
+	platform := chip8.PlatformChip8
+	if args.Platform != "" {
+		var err error
+		if platform, err = chip8.ParsePlatform(args.Platform); err != nil {
+			return nil, err
+		}
+	}
+	headless, err := newHeadlessRun(args.Program, args.Movie, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	tickrate := args.Tickrate
+	if tickrate == 0 {
+		tickrate = defaultDAPTickrate
+	}
+	d := debugger.New(headless.chip, tickrate)
+	d.OnFrame = headless.keypad.SetFrame
+	if args.History > 0 {
+		d.SetHistory(args.History)
+	}
+	return d, nil
+}
@@ -0,0 +1,763 @@
+package debugger
+
+import (
+	"bufio"
+	"chip8emulator/chip8"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LaunchArguments are arguments of the launch request, they're set in the launch configuration of the editor.
+type LaunchArguments struct {
+	// Program is the path of the .ch8 file.
+	Program string `json:"program"`
+	// Tickrate is the number of instructions per frame.
+	Tickrate int `json:"tickrate"`
+	// Symbols is the path of the symbol map written by the assembler, it enables breakpoints on source lines.
+	Symbols string `json:"symbols"`
+	// Movie is the path of an input movie played back during the run.
+	Movie       string `json:"movie"`
+	StopOnEntry bool   `json:"stopOnEntry"`
+	// History is the number of instructions which can be executed backwards, 0 uses DefaultHistory.
+	History int `json:"history"`
+	// Platform is the name of the variant of CHIP-8, e.g. "hires", empty means CHIP-8.
+	Platform string `json:"platform"`
+}
+
+// Launcher creates debugger for the program described by the launch arguments.
+type Launcher func(args LaunchArguments) (*Debugger, error)
+
+// references of variable containers returned by the scopes request
+const (
+	registersReference = 1 + iota
+	timersReference
+	stackReference
+)
+
+const dapThreadID = 1
+
+// ListenDAP accepts Debug Adapter Protocol connections on addr and serves them one at a time.
+func ListenDAP(addr string, launch Launcher) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		err = (&DAPServer{Launch: launch}).Serve(conn)
+		conn.Close()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+}
+
+type dapRequest struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type dapResponse struct {
+	Seq        int    `json:"seq"`
+	Type       string `json:"type"`
+	RequestSeq int    `json:"request_seq"`
+	Success    bool   `json:"success"`
+	Command    string `json:"command"`
+	Message    string `json:"message,omitempty"`
+	Body       any    `json:"body,omitempty"`
+}
+
+type dapEvent struct {
+	Seq   int    `json:"seq"`
+	Type  string `json:"type"`
+	Event string `json:"event"`
+	Body  any    `json:"body,omitempty"`
+}
+
+// DAPServer implements the Debug Adapter Protocol used by editors like VS Code for one session.
+type DAPServer struct {
+	Launch Launcher
+
+	w       io.Writer
+	writeMu sync.Mutex
+	seq     int
+
+	debugger *Debugger
+	symbols  *SymbolMap
+	launch   LaunchArguments
+
+	// mu protects fields describing the execution
+	mu        sync.Mutex
+	running   bool
+	interrupt chan struct{}
+
+	sourceBreakpoints      map[string][]uint16
+	instructionBreakpoints []uint16
+}
+
+// Serve handles requests from rw until the client disconnects.
+func (s *DAPServer) Serve(rw io.ReadWriter) error {
+	s.w = rw
+	s.sourceBreakpoints = map[string][]uint16{}
+	reader := bufio.NewReader(rw)
+
+	for {
+		request, err := readDAPRequest(reader)
+		if err != nil {
+			s.pause()
+			return err
+		}
+
+		body, err := s.handle(request)
+		response := dapResponse{Type: "response", RequestSeq: request.Seq, Command: request.Command, Success: err == nil, Body: body}
+		if err != nil {
+			response.Message = err.Error()
+		}
+		if err := s.send(&response); err != nil {
+			return err
+		}
+
+		switch request.Command {
+		case "launch":
+			if response.Success {
+				// breakpoints are sent by the client after this event
+				s.sendEvent("initialized", nil)
+			}
+		case "configurationDone":
+			if s.launch.StopOnEntry {
+				s.sendStopped("entry")
+			} else {
+				s.resume(s.debugger.Continue, "breakpoint")
+			}
+		case "disconnect", "terminate":
+			s.pause()
+			s.sendEvent("terminated", nil)
+			return nil
+		}
+	}
+}
+
+func readDAPRequest(r *bufio.Reader) (*dapRequest, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+
+	var request dapRequest
+	if err := json.Unmarshal(content, &request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// send writes message with a new sequence number, it's safe to call from the goroutine running the program.
+func (s *DAPServer) send(message any) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.seq++
+	switch m := message.(type) {
+	case *dapResponse:
+		m.Seq = s.seq
+	case *dapEvent:
+		m.Seq = s.seq
+	}
+
+	content, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	return err
+}
+
+func (s *DAPServer) sendEvent(event string, body any) {
+	s.send(&dapEvent{Type: "event", Event: event, Body: body})
+}
+
+func (s *DAPServer) sendStopped(reason string) {
+	s.sendEvent("stopped", map[string]any{"reason": reason, "threadId": dapThreadID, "allThreadsStopped": true})
+}
+
+// handle executes the request and returns the body of the response.
+func (s *DAPServer) handle(request *dapRequest) (any, error) {
+	if s.debugger == nil && request.Command != "initialize" && request.Command != "launch" &&
+		request.Command != "disconnect" && request.Command != "terminate" {
+		return nil, errors.New("program is not launched")
+	}
+
+	switch request.Command {
+	case "initialize":
+		return map[string]any{
+			"supportsConfigurationDoneRequest":  true,
+			"supportsReadMemoryRequest":         true,
+			"supportsWriteMemoryRequest":        true,
+			"supportsDisassembleRequest":        true,
+			"supportsInstructionBreakpoints":    true,
+			"supportsSetVariable":               true,
+			"supportsTerminateRequest":          true,
+			"supportsConditionalBreakpoints":    true,
+			"supportsHitConditionalBreakpoints": true,
+			"supportsLogPoints":                 true,
+			"supportsEvaluateForHovers":         true,
+			"supportsStepBack":                  true,
+		}, nil
+	case "launch":
+		return nil, s.handleLaunch(request.Arguments)
+	case "setBreakpoints":
+		return s.handleSetBreakpoints(request.Arguments)
+	case "setInstructionBreakpoints":
+		return s.handleSetInstructionBreakpoints(request.Arguments)
+	case "setExceptionBreakpoints", "configurationDone", "disconnect", "terminate":
+		return nil, nil
+	case "threads":
+		return map[string]any{"threads": []map[string]any{{"id": dapThreadID, "name": "chip8"}}}, nil
+	case "stackTrace":
+		return s.handleStackTrace(), nil
+	case "scopes":
+		return map[string]any{"scopes": []map[string]any{
+			{"name": "Registers", "variablesReference": registersReference, "expensive": false},
+			{"name": "Timers", "variablesReference": timersReference, "expensive": false},
+			{"name": "Stack", "variablesReference": stackReference, "expensive": false},
+		}}, nil
+	case "variables":
+		return s.handleVariables(request.Arguments)
+	case "setVariable":
+		return s.handleSetVariable(request.Arguments)
+	case "continue":
+		s.resume(s.debugger.Continue, "breakpoint")
+		return map[string]any{"allThreadsContinued": true}, nil
+	case "next":
+		s.resume(s.debugger.StepOver, "step")
+		return nil, nil
+	case "stepIn":
+		s.resume(func(<-chan struct{}) StopReason { return s.debugger.Step() }, "step")
+		return nil, nil
+	case "stepOut":
+		s.resume(s.debugger.StepOut, "step")
+		return nil, nil
+	case "stepBack":
+		s.resume(func(<-chan struct{}) StopReason { return s.debugger.StepBack() }, "step")
+		return nil, nil
+	case "reverseContinue":
+		s.resume(s.debugger.ReverseContinue, "breakpoint")
+		return nil, nil
+	case "evaluate":
+		return s.handleEvaluate(request.Arguments)
+	case "pause":
+		if !s.pause() {
+			s.sendStopped("pause")
+		}
+		return nil, nil
+	case "readMemory":
+		return s.handleReadMemory(request.Arguments)
+	case "writeMemory":
+		return s.handleWriteMemory(request.Arguments)
+	case "disassemble":
+		return s.handleDisassemble(request.Arguments)
+	}
+
+	return nil, fmt.Errorf("request %q is not supported", request.Command)
+}
+
+// resume runs the program in the background and sends stopped event when run returns.
+// Stops caused by breakpoints and pauses are reported as such, other stops as reason.
+func (s *DAPServer) resume(run func(interrupt <-chan struct{}) StopReason, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return
+	}
+
+	interrupt := make(chan struct{}, 1)
+	s.running = true
+	s.interrupt = interrupt
+
+	go func() {
+		stop := run(interrupt)
+
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+
+		switch stop {
+		case StopBreakpoint:
+			s.sendStopped("breakpoint")
+		case StopInterrupt:
+			s.sendStopped("pause")
+		case StopWatchpoint:
+			s.sendStopped("data breakpoint")
+		case StopHistoryStart:
+			s.sendStopped("start of history")
+		default:
+			s.sendStopped(reason)
+		}
+	}()
+}
+
+// pause interrupts the running program, it returns false if the program wasn't running.
+func (s *DAPServer) pause() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return false
+	}
+
+	select {
+	case s.interrupt <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+func (s *DAPServer) handleLaunch(arguments json.RawMessage) error {
+	var args LaunchArguments
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return err
+	}
+	if args.Program == "" {
+		return errors.New("launch configuration needs a program")
+	}
+
+	d, err := s.Launch(args)
+	if err != nil {
+		return err
+	}
+
+	symbols := &SymbolMap{}
+	if args.Symbols != "" {
+		symbols, err = LoadSymbolMap(args.Symbols)
+		if err != nil {
+			return err
+		}
+	}
+
+	d.OnLog = func(breakpoint *Breakpoint, message string) {
+		s.sendEvent("output", map[string]any{"category": "console", "output": message + "\n"})
+	}
+	s.debugger = d
+	s.symbols = symbols
+	s.launch = args
+	return nil
+}
+
+func (s *DAPServer) handleSetBreakpoints(arguments json.RawMessage) (any, error) {
+	var args struct {
+		Source struct {
+			Path string `json:"path"`
+		} `json:"source"`
+		Breakpoints []struct {
+			Line int `json:"line"`
+			dapBreakpointOptions
+		} `json:"breakpoints"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	for _, address := range s.sourceBreakpoints[args.Source.Path] {
+		s.debugger.ClearBreakpoint(address)
+	}
+	s.sourceBreakpoints[args.Source.Path] = nil
+
+	breakpoints := []map[string]any{}
+	for _, requested := range args.Breakpoints {
+		addresses := s.symbols.Addresses(args.Source.Path, requested.Line)
+		breakpoint := map[string]any{"verified": len(addresses) > 0, "line": requested.Line}
+		if len(addresses) == 0 {
+			breakpoint["message"] = "no instruction is generated from this line"
+		} else {
+			breakpoint["instructionReference"] = formatAddress(addresses[0])
+		}
+
+		for _, address := range addresses {
+			if err := s.addBreakpoint(address, requested.dapBreakpointOptions); err != nil {
+				breakpoint["verified"] = false
+				breakpoint["message"] = err.Error()
+				break
+			}
+		}
+		s.sourceBreakpoints[args.Source.Path] = append(s.sourceBreakpoints[args.Source.Path], addresses...)
+		breakpoints = append(breakpoints, breakpoint)
+	}
+
+	return map[string]any{"breakpoints": breakpoints}, nil
+}
+
+func (s *DAPServer) handleSetInstructionBreakpoints(arguments json.RawMessage) (any, error) {
+	var args struct {
+		Breakpoints []struct {
+			InstructionReference string `json:"instructionReference"`
+			Offset               int    `json:"offset"`
+			dapBreakpointOptions
+		} `json:"breakpoints"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	for _, address := range s.instructionBreakpoints {
+		s.debugger.ClearBreakpoint(address)
+	}
+	s.instructionBreakpoints = nil
+
+	breakpoints := []map[string]any{}
+	for _, requested := range args.Breakpoints {
+		address, err := parseAddress(requested.InstructionReference)
+		if err != nil {
+			breakpoints = append(breakpoints, map[string]any{"verified": false, "message": err.Error()})
+			continue
+		}
+		address += uint16(requested.Offset)
+
+		if err := s.addBreakpoint(address, requested.dapBreakpointOptions); err != nil {
+			breakpoints = append(breakpoints, map[string]any{"verified": false, "message": err.Error()})
+			continue
+		}
+		s.instructionBreakpoints = append(s.instructionBreakpoints, address)
+		breakpoints = append(breakpoints, map[string]any{"verified": true, "instructionReference": formatAddress(address)})
+	}
+
+	return map[string]any{"breakpoints": breakpoints}, nil
+}
+
+// dapBreakpointOptions are optional properties of source and instruction breakpoints.
+type dapBreakpointOptions struct {
+	Condition    string `json:"condition"`
+	HitCondition string `json:"hitCondition"`
+	LogMessage   string `json:"logMessage"`
+}
+
+// addBreakpoint adds breakpoint at address, the condition is an Expression and the hit condition
+// is the number of the hit which stops the execution.
+func (s *DAPServer) addBreakpoint(address uint16, options dapBreakpointOptions) error {
+	breakpoint := &Breakpoint{Address: address}
+	var err error
+
+	if options.Condition != "" {
+		if breakpoint.Condition, err = ParseExpression(options.Condition); err != nil {
+			return err
+		}
+	}
+	if options.HitCondition != "" {
+		hitCondition := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(options.HitCondition), ">="))
+		if breakpoint.HitCount, err = strconv.Atoi(hitCondition); err != nil {
+			return fmt.Errorf("hit condition %q should be a number of hits", options.HitCondition)
+		}
+	}
+	if options.LogMessage != "" {
+		if breakpoint.Log, err = ParseLogMessage(options.LogMessage); err != nil {
+			return err
+		}
+	}
+
+	s.debugger.AddBreakpoint(breakpoint)
+	return nil
+}
+
+// handleEvaluate evaluates an Expression, e.g. typed to the debug console.
+func (s *DAPServer) handleEvaluate(arguments json.RawMessage) (any, error) {
+	var args struct {
+		Expression string `json:"expression"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	expression, err := ParseExpression(args.Expression)
+	if err != nil {
+		return nil, err
+	}
+
+	s.debugger.Lock()
+	defer s.debugger.Unlock()
+	value := expression.Evaluate(s.debugger.Chip)
+
+	return map[string]any{"result": fmt.Sprintf("%d (0x%X)", value, value), "variablesReference": 0}, nil
+}
+
+// handleStackTrace returns the current instruction followed by calls of subroutines on the stack.
+func (s *DAPServer) handleStackTrace() any {
+	s.debugger.Lock()
+	defer s.debugger.Unlock()
+
+	chip := s.debugger.Chip
+	addresses := []uint16{chip.Pc}
+	for i := len(chip.Stack) - 1; i >= 0; i-- {
+		addresses = append(addresses, chip.Stack[i])
+	}
+
+	frames := []map[string]any{}
+	for id, address := range addresses {
+		name := formatAddress(address)
+		if label, ok := s.symbols.Label(address); ok {
+			name = label
+		}
+
+		frame := map[string]any{"id": id, "name": name, "line": 0, "column": 0, "instructionPointerReference": formatAddress(address)}
+		if location, ok := s.symbols.Location(address); ok {
+			frame["source"] = map[string]any{"name": fileName(location.File), "path": location.File}
+			frame["line"] = location.Line
+			frame["column"] = 1
+		}
+		frames = append(frames, frame)
+	}
+
+	return map[string]any{"stackFrames": frames, "totalFrames": len(frames)}
+}
+
+func (s *DAPServer) handleVariables(arguments json.RawMessage) (any, error) {
+	var args struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	s.debugger.Lock()
+	defer s.debugger.Unlock()
+	chip := s.debugger.Chip
+
+	variables := []map[string]any{}
+	add := func(name string, value uint16, memory bool) {
+		variable := map[string]any{"name": name, "value": formatValue(value), "variablesReference": 0}
+		if memory {
+			variable["memoryReference"] = formatAddress(value)
+		}
+		variables = append(variables, variable)
+	}
+
+	switch args.VariablesReference {
+	case registersReference:
+		for i, value := range chip.Registers {
+			add(fmt.Sprintf("V%X", i), uint16(value), false)
+		}
+		add("I", chip.I, true)
+		add("PC", chip.Pc, true)
+		add("SP", uint16(len(chip.Stack)), false)
+	case timersReference:
+		add("DT", uint16(chip.Timers[0]), false)
+		add("ST", uint16(chip.Timers[1]), false)
+	case stackReference:
+		for i, address := range chip.Stack {
+			add(fmt.Sprintf("[%d]", i), address, true)
+		}
+	default:
+		return nil, fmt.Errorf("unknown variables reference %d", args.VariablesReference)
+	}
+
+	return map[string]any{"variables": variables}, nil
+}
+
+func (s *DAPServer) handleSetVariable(arguments json.RawMessage) (any, error) {
+	var args struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	value, err := strconv.ParseUint(strings.TrimSpace(args.Value), 0, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q", args.Value)
+	}
+
+	s.debugger.Lock()
+	defer s.debugger.Unlock()
+	chip := s.debugger.Chip
+
+	switch name := strings.ToUpper(args.Name); name {
+	case "I":
+		chip.I = uint16(value)
+	case "PC":
+		chip.Pc = uint16(value)
+	default:
+		variable, err := byteVariable(chip, args.Name)
+		if err != nil {
+			return nil, err
+		}
+		if value > 0xff {
+			return nil, fmt.Errorf("%s is a byte, %q doesn't fit in it", name, args.Value)
+		}
+		*variable = byte(value)
+	}
+	s.debugger.edited()
+
+	return map[string]any{"value": formatValue(uint16(value))}, nil
+}
+
+// byteVariable returns the timer or V register called name.
+func byteVariable(chip *chip8.Chip8, name string) (*byte, error) {
+	switch upper := strings.ToUpper(name); {
+	case upper == "DT":
+		return &chip.Timers[0], nil
+	case upper == "ST":
+		return &chip.Timers[1], nil
+	case len(upper) == 2 && upper[0] == 'V':
+		register, err := strconv.ParseUint(upper[1:], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("unknown register %q", name)
+		}
+		return &chip.Registers[register], nil
+	}
+	return nil, fmt.Errorf("%q can't be changed", name)
+}
+
+func (s *DAPServer) handleReadMemory(arguments json.RawMessage) (any, error) {
+	var args struct {
+		MemoryReference string `json:"memoryReference"`
+		Offset          int    `json:"offset"`
+		Count           int    `json:"count"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	address, err := parseAddress(args.MemoryReference)
+	if err != nil {
+		return nil, err
+	}
+
+	s.debugger.Lock()
+	defer s.debugger.Unlock()
+	memory := s.debugger.Chip.Memory
+
+	start := int(address) + args.Offset
+	end := start + args.Count
+	unreadable := 0
+	if start < 0 || start > len(memory) {
+		return map[string]any{"address": formatAddress(uint16(start)), "unreadableBytes": args.Count}, nil
+	}
+	if end > len(memory) {
+		unreadable = end - len(memory)
+		end = len(memory)
+	}
+
+	return map[string]any{
+		"address":         formatAddress(uint16(start)),
+		"data":            base64.StdEncoding.EncodeToString(memory[start:end]),
+		"unreadableBytes": unreadable,
+	}, nil
+}
+
+func (s *DAPServer) handleWriteMemory(arguments json.RawMessage) (any, error) {
+	var args struct {
+		MemoryReference string `json:"memoryReference"`
+		Offset          int    `json:"offset"`
+		Data            string `json:"data"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	address, err := parseAddress(args.MemoryReference)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(args.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	s.debugger.Lock()
+	defer s.debugger.Unlock()
+	chip := s.debugger.Chip
+
+	start := int(address) + args.Offset
+	if start < 0 || start+len(data) > len(chip.Memory) {
+		return nil, errors.New("write is outside of the memory")
+	}
+	// WriteMemory invalidates decoded instructions of the engine
+	for i, value := range data {
+		chip.WriteMemory(uint16(start+i), value)
+	}
+	s.debugger.edited()
+
+	return map[string]any{"bytesWritten": len(data)}, nil
+}
+
+func (s *DAPServer) handleDisassemble(arguments json.RawMessage) (any, error) {
+	var args struct {
+		MemoryReference   string `json:"memoryReference"`
+		Offset            int    `json:"offset"`
+		InstructionOffset int    `json:"instructionOffset"`
+		InstructionCount  int    `json:"instructionCount"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	address, err := parseAddress(args.MemoryReference)
+	if err != nil {
+		return nil, err
+	}
+
+	s.debugger.Lock()
+	defer s.debugger.Unlock()
+	chip := s.debugger.Chip
+	memory := chip.Memory
+
+	start := int(address) + args.Offset + args.InstructionOffset*2
+	instructions := []map[string]any{}
+	for i := 0; i < args.InstructionCount; i++ {
+		current := start + i*2
+		if current < 0 || current+1 >= len(memory) {
+			// the client expects exactly instructionCount entries
+			instructions = append(instructions, map[string]any{"address": formatAddress(uint16(max(current, 0))), "instruction": "??"})
+			continue
+		}
+
+		instruction := map[string]any{
+			"address":          formatAddress(uint16(current)),
+			"instructionBytes": fmt.Sprintf("%02X %02X", memory[current], memory[current+1]),
+			"instruction":      chip8.DisassembleInstruction(chip.DecodeBytes(memory[current], memory[current+1])),
+		}
+		if label, ok := s.symbols.Label(uint16(current)); ok {
+			instruction["symbol"] = label
+		}
+		if location, ok := s.symbols.Location(uint16(current)); ok {
+			instruction["location"] = map[string]any{"name": fileName(location.File), "path": location.File}
+			instruction["line"] = location.Line
+		}
+		instructions = append(instructions, instruction)
+	}
+
+	return map[string]any{"instructions": instructions}, nil
+}
+
+func formatAddress(address uint16) string {
+	return fmt.Sprintf("0x%03X", address)
+}
+
+func formatValue(value uint16) string {
+	return fmt.Sprintf("0x%02X (%d)", value, value)
+}
+
+func parseAddress(reference string) (uint16, error) {
+	address, err := strconv.ParseUint(strings.TrimSpace(reference), 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", reference)
+	}
+	return uint16(address), nil
+}
@@ -0,0 +1,346 @@
+package debugger
+
+import (
+	"bufio"
+	"chip8emulator/chip8"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// dapMessage is a response or an event received by dapClient.
+type dapMessage struct {
+	Type       string          `json:"type"`
+	Command    string          `json:"command"`
+	Event      string          `json:"event"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// dapClient sends requests to DAPServer over an in-memory connection.
+type dapClient struct {
+	t        testing.TB
+	conn     net.Conn
+	messages chan dapMessage
+	seq      int
+}
+
+func newDAPClient(t testing.TB, launch Launcher) *dapClient {
+	server, client := net.Pipe()
+	go (&DAPServer{Launch: launch}).Serve(server)
+	t.Cleanup(func() { client.Close() })
+
+	c := &dapClient{t: t, conn: client, messages: make(chan dapMessage, 16)}
+	go c.read()
+	return c
+}
+
+func (c *dapClient) read() {
+	reader := bufio.NewReader(c.conn)
+	for {
+		var length int
+		if _, err := fmt.Fscanf(reader, "Content-Length: %d\r\n\r\n", &length); err != nil {
+			close(c.messages)
+			return
+		}
+		content := make([]byte, length)
+		if _, err := io.ReadFull(reader, content); err != nil {
+			close(c.messages)
+			return
+		}
+		var message dapMessage
+		json.Unmarshal(content, &message)
+		c.messages <- message
+	}
+}
+
+// request sends command and returns its response, body is decoded into result if it's not nil.
+func (c *dapClient) request(command string, arguments any, result any) dapMessage {
+	c.t.Helper()
+	c.seq++
+	content, _ := json.Marshal(map[string]any{"seq": c.seq, "type": "request", "command": command, "arguments": arguments})
+	fmt.Fprintf(c.conn, "Content-Length: %d\r\n\r\n%s", len(content), content)
+
+	for {
+		message := c.next()
+		if message.Type == "response" && message.RequestSeq == c.seq {
+			if !message.Success {
+				c.t.Fatalf("request %s failed: %s", command, message.Message)
+			}
+			if result != nil {
+				if err := json.Unmarshal(message.Body, result); err != nil {
+					c.t.Fatal(err)
+				}
+			}
+			return message
+		}
+	}
+}
+
+// waitEvent skips messages until event is received and returns its body.
+func (c *dapClient) waitEvent(event string) json.RawMessage {
+	c.t.Helper()
+	for {
+		message := c.next()
+		if message.Type == "event" && message.Event == event {
+			return message.Body
+		}
+	}
+}
+
+func (c *dapClient) next() dapMessage {
+	c.t.Helper()
+	select {
+	case message, ok := <-c.messages:
+		if !ok {
+			c.t.Fatal("connection was closed")
+		}
+		return message
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timed out waiting for a message")
+	}
+	return dapMessage{}
+}
+
+func TestDAPServer(t *testing.T) {
+	// 0x200: V0 += 1, 0x202: call 0x206, 0x204: jump to 0x200, 0x206: V1 += 2, 0x208: return
+	program := []byte{0x70, 0x01, 0x22, 0x06, 0x12, 0x00, 0x71, 0x02, 0x00, 0xEE}
+	symbols := "0x200 main\n0x200 game.8o:1\n0x202 game.8o:2\n0x204 game.8o:3\n0x206 add\n0x206 game.8o:6\n0x208 game.8o:7\n"
+
+	symbolsPath := filepath.Join(t.TempDir(), "game.sym")
+	if err := os.WriteFile(symbolsPath, []byte(symbols), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var d *Debugger
+	launch := func(args LaunchArguments) (*Debugger, error) {
+		d = newTestDebugger(program)
+		return d, nil
+	}
+
+	start := func(t *testing.T) *dapClient {
+		client := newDAPClient(t, launch)
+		client.request("initialize", map[string]any{"adapterID": "chip8"}, nil)
+		client.request("launch", LaunchArguments{Program: "game.ch8", Symbols: symbolsPath, StopOnEntry: true}, nil)
+		client.waitEvent("initialized")
+		return client
+	}
+
+	t.Run("Source breakpoints", func(t *testing.T) {
+		client := start(t)
+
+		var breakpoints struct {
+			Breakpoints []struct {
+				Verified bool `json:"verified"`
+			} `json:"breakpoints"`
+		}
+		client.request("setBreakpoints", map[string]any{
+			"source":      map[string]any{"path": "/home/user/game.8o"},
+			"breakpoints": []map[string]any{{"line": 6}, {"line": 4}},
+		}, &breakpoints)
+
+		if len(breakpoints.Breakpoints) != 2 || !breakpoints.Breakpoints[0].Verified || breakpoints.Breakpoints[1].Verified {
+			t.Fatalf("got %+v, want line 6 verified and line 4 unverified", breakpoints.Breakpoints)
+		}
+
+		client.request("configurationDone", nil, nil)
+		client.waitEvent("stopped")
+		client.request("continue", map[string]any{"threadId": 1}, nil)
+
+		var stopped struct {
+			Reason string `json:"reason"`
+		}
+		json.Unmarshal(client.waitEvent("stopped"), &stopped)
+		if stopped.Reason != "breakpoint" {
+			t.Errorf("got reason %q, want breakpoint", stopped.Reason)
+		}
+
+		var trace struct {
+			StackFrames []struct {
+				Name string `json:"name"`
+				Line int    `json:"line"`
+			} `json:"stackFrames"`
+		}
+		client.request("stackTrace", map[string]any{"threadId": 1}, &trace)
+
+		if len(trace.StackFrames) != 2 {
+			t.Fatalf("got %d frames, want 2", len(trace.StackFrames))
+		}
+		if trace.StackFrames[0].Name != "add" || trace.StackFrames[0].Line != 6 {
+			t.Errorf("got frame %+v, want add at line 6", trace.StackFrames[0])
+		}
+		if trace.StackFrames[1].Name != "main+0x2" || trace.StackFrames[1].Line != 2 {
+			t.Errorf("got frame %+v, want the call at main+0x2 on line 2", trace.StackFrames[1])
+		}
+	})
+
+	t.Run("Conditional breakpoints and logpoints", func(t *testing.T) {
+		client := start(t)
+
+		client.request("setInstructionBreakpoints", map[string]any{"breakpoints": []map[string]any{
+			{"instructionReference": "0x206", "logMessage": "V0 is {V0}"},
+			{"instructionReference": "0x204", "condition": "V0 == 2"},
+		}}, nil)
+		client.request("configurationDone", nil, nil)
+		client.waitEvent("stopped")
+		client.request("continue", map[string]any{"threadId": 1}, nil)
+
+		var output struct {
+			Output string `json:"output"`
+		}
+		json.Unmarshal(client.waitEvent("output"), &output)
+		if output.Output != "V0 is 1\n" {
+			t.Errorf("got output %q, want V0 is 1", output.Output)
+		}
+
+		client.waitEvent("stopped")
+		var result struct {
+			Result string `json:"result"`
+		}
+		client.request("evaluate", map[string]any{"expression": "V0 + V1"}, &result)
+		if result.Result != "6 (0x6)" {
+			t.Errorf("got %q, want 6 (0x6)", result.Result)
+		}
+	})
+
+	t.Run("Step over a call", func(t *testing.T) {
+		client := start(t)
+		client.request("configurationDone", nil, nil)
+		client.waitEvent("stopped")
+
+		client.request("next", map[string]any{"threadId": 1}, nil)
+		client.waitEvent("stopped")
+		client.request("next", map[string]any{"threadId": 1}, nil)
+		client.waitEvent("stopped")
+
+		d.Lock()
+		defer d.Unlock()
+		if d.Chip.Pc != 0x204 || d.Chip.Registers[1] != 2 {
+			t.Errorf("got pc %x and V1 %d, want 204 and 2", d.Chip.Pc, d.Chip.Registers[1])
+		}
+	})
+
+	t.Run("Variables and memory", func(t *testing.T) {
+		client := start(t)
+		client.request("setVariable", map[string]any{"variablesReference": registersReference, "name": "VA", "value": "0x2a"}, nil)
+
+		var variables struct {
+			Variables []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"variables"`
+		}
+		client.request("variables", map[string]any{"variablesReference": registersReference}, &variables)
+		if got := variables.Variables[0xA]; got.Name != "VA" || got.Value != "0x2A (42)" {
+			t.Errorf("got %+v, want VA = 0x2A (42)", got)
+		}
+
+		client.request("writeMemory", map[string]any{"memoryReference": "0x300", "data": "vu8="}, nil)
+		var memory struct {
+			Data string `json:"data"`
+		}
+		client.request("readMemory", map[string]any{"memoryReference": "0x300", "count": 2}, &memory)
+		if memory.Data != "vu8=" {
+			t.Errorf("got %q, want vu8=", memory.Data)
+		}
+
+		var disassembly struct {
+			Instructions []struct {
+				Instruction string `json:"instruction"`
+				Symbol      string `json:"symbol"`
+			} `json:"instructions"`
+		}
+		client.request("disassemble", map[string]any{"memoryReference": "0x206", "instructionCount": 2}, &disassembly)
+		if len(disassembly.Instructions) != 2 || disassembly.Instructions[0].Instruction != "ADD V1, 0x02" ||
+			disassembly.Instructions[0].Symbol != "add" || disassembly.Instructions[1].Instruction != "RET" {
+			t.Errorf("got %+v", disassembly.Instructions)
+		}
+	})
+
+	t.Run("Step back after writeMemory", func(t *testing.T) {
+		client := start(t)
+		client.request("configurationDone", nil, nil)
+		client.waitEvent("stopped")
+		client.request("stepIn", map[string]any{"threadId": 1}, nil)
+		client.waitEvent("stopped")
+
+		// the step can't be undone on top of the changed memory
+		client.request("writeMemory", map[string]any{"memoryReference": "0x200", "data": "cAU="}, nil)
+		client.request("stepBack", map[string]any{"threadId": 1}, nil)
+
+		var stopped struct {
+			Reason string `json:"reason"`
+		}
+		json.Unmarshal(client.waitEvent("stopped"), &stopped)
+		if stopped.Reason != "start of history" {
+			t.Errorf("got reason %q, want start of history", stopped.Reason)
+		}
+
+		d.Lock()
+		defer d.Unlock()
+		if d.Chip.Pc != 0x202 || d.Chip.Registers[0] != 1 || d.Chip.Memory[0x201] != 0x05 {
+			t.Errorf("got pc %x, V0 %d and %02x at 201, want 202, 1 and 05",
+				d.Chip.Pc, d.Chip.Registers[0], d.Chip.Memory[0x201])
+		}
+	})
+
+	t.Run("Values which don't fit in a byte are refused", func(t *testing.T) {
+		server := &DAPServer{debugger: newTestDebugger(program)}
+
+		for _, name := range []string{"V3", "DT", "ST"} {
+			arguments, _ := json.Marshal(map[string]any{"name": name, "value": "300"})
+			if _, err := server.handleSetVariable(arguments); err == nil {
+				t.Errorf("expected an error for %s = 300", name)
+			}
+		}
+		arguments, _ := json.Marshal(map[string]any{"name": "I", "value": "300"})
+		if _, err := server.handleSetVariable(arguments); err != nil || server.debugger.Chip.I != 300 {
+			t.Errorf("got I %d and %v, want 300", server.debugger.Chip.I, err)
+		}
+	})
+
+	t.Run("writeMemory invalidates decoded instructions", func(t *testing.T) {
+		server := &DAPServer{debugger: newTestDebugger(program)}
+		chip := server.debugger.Chip
+		// hooks of the debugger make the engine use Step
+		chip.Undo, chip.Watchpoints = nil, nil
+		engine := chip8.NewCachedEngine(chip)
+		engine.Run(1)
+
+		// 0x200: V0 += 1 is replaced by V0 += 5
+		chip.Pc = 0x200
+		arguments, _ := json.Marshal(map[string]any{"memoryReference": "0x200", "data": "cAU="})
+		if _, err := server.handleWriteMemory(arguments); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		engine.Run(1)
+
+		if chip.Registers[0] != 6 {
+			t.Errorf("got V0 %d, want 6", chip.Registers[0])
+		}
+	})
+
+	t.Run("Pause", func(t *testing.T) {
+		client := start(t)
+		client.request("configurationDone", nil, nil)
+		client.waitEvent("stopped")
+
+		client.request("continue", map[string]any{"threadId": 1}, nil)
+		client.request("pause", map[string]any{"threadId": 1}, nil)
+
+		var stopped struct {
+			Reason string `json:"reason"`
+		}
+		json.Unmarshal(client.waitEvent("stopped"), &stopped)
+		if stopped.Reason != "pause" {
+			t.Errorf("got reason %q, want pause", stopped.Reason)
+		}
+	})
+}
@@ -0,0 +1,378 @@
+// Package debugger controls execution of a chip8 program: breakpoints, stepping and
+// continuing. Remote debugging protocols are served on top of Debugger.
+package debugger
+
+import (
+	"chip8emulator/chip8"
+	"sort"
+	"sync"
+)
+
+// StopReason tells why the execution stopped.
+type StopReason int
+
+const (
+	StopStep StopReason = iota
+	StopBreakpoint
+	StopInterrupt
+	// StopWatchpoint means the last instruction accessed a watched location, see WatchHits.
+	StopWatchpoint
+	// StopHistoryStart means reverse execution reached the oldest recorded instruction.
+	StopHistoryStart
+)
+
+// checkInterruptEvery is the number of instructions executed by Continue between checks for an interrupt.
+// The chip is unlocked between the checks.
+const checkInterruptEvery = 1000
+
+// DefaultHistory is the number of instructions which can be executed backwards, see SetHistory.
+const DefaultHistory = 100000
+
+// Breakpoint stops the execution before the instruction at Address is executed.
+type Breakpoint struct {
+	Address uint16
+	// Condition must be true for the breakpoint to be hit, nil is always true.
+	Condition *Expression
+	// HitCount is the hit from which the breakpoint stops the execution, earlier hits are only counted.
+	HitCount int
+	// Log turns the breakpoint into a logpoint, it's passed to OnLog instead of stopping the execution.
+	Log *LogMessage
+	// Hits counts how many times the breakpoint was reached with its condition true.
+	Hits int
+}
+
+// Debugger executes a program loaded into Chip one instruction at a time.
+type Debugger struct {
+	Chip *chip8.Chip8
+	// Tickrate is the number of instructions per frame, timers are updated after every frame.
+	// Frames end early when an instruction waits for the display.
+	Tickrate int
+	// OnFrame is called after the timers were updated, e.g. to advance an input movie.
+	OnFrame func(frame int)
+	// OnLog receives messages of logpoints, it's called while the chip is locked.
+	OnLog func(breakpoint *Breakpoint, message string)
+
+	// mu protects the state of the chip, it's held while instructions are executed.
+	mu           sync.Mutex
+	breakpoints  map[uint16]*Breakpoint
+	watchHits    []chip8.WatchHit
+	instructions int
+	frame        int
+	// frameLengths are numbers of instructions in previous frames, used by stepping back
+	frameLengths []int
+}
+
+// New creates debugger for chip which executes tickrate instructions per frame.
+func New(chip *chip8.Chip8, tickrate int) *Debugger {
+	if tickrate < 1 {
+		tickrate = 1
+	}
+	if chip.Watchpoints == nil {
+		chip.Watchpoints = &chip8.Watchpoints{}
+	}
+	if chip.Undo == nil {
+		chip.Undo = chip8.NewUndoLog(DefaultHistory)
+	}
+	return &Debugger{Chip: chip, Tickrate: tickrate, breakpoints: map[uint16]*Breakpoint{}}
+}
+
+// Lock gives exclusive access to the chip, e.g. to read its memory while it's running.
+func (d *Debugger) Lock() {
+	d.mu.Lock()
+}
+
+func (d *Debugger) Unlock() {
+	d.mu.Unlock()
+}
+
+// SetBreakpoint adds breakpoint at address and returns it.
+func (d *Debugger) SetBreakpoint(address uint16) *Breakpoint {
+	return d.AddBreakpoint(&Breakpoint{Address: address})
+}
+
+// AddBreakpoint adds breakpoint with a condition, hit count or log message. It replaces
+// the breakpoint at the same address.
+func (d *Debugger) AddBreakpoint(breakpoint *Breakpoint) *Breakpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.breakpoints[breakpoint.Address] = breakpoint
+	return breakpoint
+}
+
+// ClearBreakpoint removes breakpoint at address.
+func (d *Debugger) ClearBreakpoint(address uint16) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.breakpoints, address)
+}
+
+// Breakpoints returns all breakpoints sorted by address.
+func (d *Debugger) Breakpoints() []*Breakpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	breakpoints := make([]*Breakpoint, 0, len(d.breakpoints))
+	for _, breakpoint := range d.breakpoints {
+		breakpoints = append(breakpoints, breakpoint)
+	}
+	sort.Slice(breakpoints, func(i, j int) bool {
+		return breakpoints[i].Address < breakpoints[j].Address
+	})
+	return breakpoints
+}
+
+// SetHistory changes the number of instructions which can be executed backwards and forgets
+// the recorded ones, 0 disables recording.
+func (d *Debugger) SetHistory(instructions int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.Chip.Undo = nil
+	if instructions > 0 {
+		d.Chip.Undo = chip8.NewUndoLog(instructions)
+	}
+}
+
+// SetWatchpoint starts watching location of watchpoint.
+func (d *Debugger) SetWatchpoint(watchpoint *chip8.Watchpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Chip.Watchpoints.Add(watchpoint)
+}
+
+// ClearWatchpoint removes watchpoint added by SetWatchpoint.
+func (d *Debugger) ClearWatchpoint(watchpoint *chip8.Watchpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Chip.Watchpoints.Remove(watchpoint)
+}
+
+// Watchpoints returns all watchpoints in the order they were set.
+func (d *Debugger) Watchpoints() []*chip8.Watchpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.Chip.Watchpoints.List()
+}
+
+// WatchHits returns accesses which caused the last StopWatchpoint.
+func (d *Debugger) WatchHits() []chip8.WatchHit {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.watchHits
+}
+
+// Step executes one instruction, it returns StopWatchpoint if the instruction triggered a watchpoint.
+func (d *Debugger) Step() StopReason {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.Chip.Watchpoints.TakeHits()
+	d.step()
+	if d.watchpointHit() {
+		return StopWatchpoint
+	}
+	return StopStep
+}
+
+// Continue executes instructions until pc reaches a breakpoint, a watchpoint is triggered or interrupt
+// receives a value or is closed. The instruction at the current pc is always executed, even if it has a breakpoint.
+func (d *Debugger) Continue(interrupt <-chan struct{}) StopReason {
+	return d.runUntil(interrupt, func() bool { return false })
+}
+
+// StepOver executes one instruction, calls of subroutines are executed until they return.
+func (d *Debugger) StepOver(interrupt <-chan struct{}) StopReason {
+	d.mu.Lock()
+	chip := d.Chip
+	isCall := chip.InstructionAt(chip.Pc).Op == chip8.OpCall
+	next := chip.Pc + 2
+	depth := len(chip.Stack)
+	d.mu.Unlock()
+
+	if !isCall {
+		return d.Step()
+	}
+	return d.runUntil(interrupt, func() bool {
+		return chip.Pc == next && len(chip.Stack) == depth
+	})
+}
+
+// StepOut executes instructions until the current subroutine returns.
+func (d *Debugger) StepOut(interrupt <-chan struct{}) StopReason {
+	d.mu.Lock()
+	depth := len(d.Chip.Stack)
+	d.mu.Unlock()
+
+	if depth == 0 {
+		return d.Step()
+	}
+	return d.runUntil(interrupt, func() bool {
+		return len(d.Chip.Stack) < depth
+	})
+}
+
+// StepBack undoes the last executed instruction.
+func (d *Debugger) StepBack() StopReason {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.stepBack() {
+		return StopHistoryStart
+	}
+	return StopStep
+}
+
+// ReverseContinue executes instructions backwards until pc reaches a breakpoint, the oldest recorded
+// instruction is undone or interrupt receives a value. Logpoints and watchpoints are ignored.
+func (d *Debugger) ReverseContinue(interrupt <-chan struct{}) StopReason {
+	for {
+		select {
+		case <-interrupt:
+			return StopInterrupt
+		default:
+		}
+
+		if reason, stopped := d.runBackBatch(); stopped {
+			return reason
+		}
+	}
+}
+
+func (d *Debugger) runBackBatch() (StopReason, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i := 0; i < checkInterruptEvery; i++ {
+		if !d.stepBack() {
+			return StopHistoryStart, true
+		}
+
+		breakpoint, ok := d.breakpoints[d.Chip.Pc]
+		if ok && breakpoint.Log == nil && (breakpoint.Condition == nil || breakpoint.Condition.True(d.Chip)) {
+			return StopBreakpoint, true
+		}
+	}
+	return 0, false
+}
+
+// runUntil executes instructions until done returns true (StopStep), a breakpoint or watchpoint is hit
+// or an interrupt is received. The chip is unlocked between batches of instructions so it can be inspected while running.
+func (d *Debugger) runUntil(interrupt <-chan struct{}, done func() bool) StopReason {
+	d.mu.Lock()
+	d.Chip.Watchpoints.TakeHits()
+	d.mu.Unlock()
+
+	for {
+		select {
+		case <-interrupt:
+			return StopInterrupt
+		default:
+		}
+
+		if reason, stopped := d.runBatch(done); stopped {
+			return reason
+		}
+	}
+}
+
+func (d *Debugger) runBatch(done func() bool) (StopReason, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i := 0; i < checkInterruptEvery; i++ {
+		d.step()
+		if d.watchpointHit() {
+			return StopWatchpoint, true
+		}
+		if done() {
+			return StopStep, true
+		}
+		if d.breakpointHit() {
+			return StopBreakpoint, true
+		}
+	}
+	return 0, false
+}
+
+// step executes one instruction and updates timers at the end of every frame.
+func (d *Debugger) step() {
+	d.Chip.Step()
+	d.instructions++
+
+	if d.instructions >= d.Tickrate || d.Chip.WaitingForDisplay() {
+		d.frameLengths = append(d.frameLengths, d.instructions)
+		if len(d.frameLengths) > DefaultHistory {
+			d.frameLengths = d.frameLengths[1:]
+		}
+		d.instructions = 0
+		d.Chip.UpdateTimers()
+		d.frame++
+		if d.OnFrame != nil {
+			d.OnFrame(d.frame)
+		}
+	}
+}
+
+// stepBack undoes one instruction and moves back the counting of frames.
+func (d *Debugger) stepBack() bool {
+	if !d.Chip.StepBack() {
+		return false
+	}
+
+	if d.instructions > 0 {
+		d.instructions--
+		return true
+	}
+	// the undone instruction ended the previous frame
+	d.instructions = d.Tickrate - 1
+	if n := len(d.frameLengths); n > 0 {
+		d.instructions = d.frameLengths[n-1] - 1
+		d.frameLengths = d.frameLengths[:n-1]
+	}
+	d.frame--
+	if d.OnFrame != nil {
+		d.OnFrame(d.frame)
+	}
+	return true
+}
+
+// edited forgets the executed instructions after the state of the chip was changed by the user,
+// they can't be undone on top of the new state. The caller holds the lock.
+func (d *Debugger) edited() {
+	if d.Chip.Undo != nil {
+		d.Chip.Undo.Clear()
+	}
+	d.frameLengths = d.frameLengths[:0]
+}
+
+// watchpointHit reports whether the last instruction triggered a watchpoint and remembers its hits.
+func (d *Debugger) watchpointHit() bool {
+	hits := d.Chip.Watchpoints.TakeHits()
+	if len(hits) == 0 {
+		return false
+	}
+	d.watchHits = hits
+	return true
+}
+
+// breakpointHit reports whether the next instruction has a breakpoint which stops the execution.
+// Logpoints are logged and don't stop it.
+func (d *Debugger) breakpointHit() bool {
+	breakpoint, ok := d.breakpoints[d.Chip.Pc]
+	if !ok || (breakpoint.Condition != nil && !breakpoint.Condition.True(d.Chip)) {
+		return false
+	}
+
+	breakpoint.Hits++
+	if breakpoint.Hits < breakpoint.HitCount {
+		return false
+	}
+	if breakpoint.Log != nil {
+		if d.OnLog != nil {
+			d.OnLog(breakpoint, breakpoint.Log.Format(d.Chip))
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,226 @@
+package debugger
+
+import (
+	"chip8emulator/chip8"
+	"testing"
+)
+
+// newTestDebugger creates debugger for program loaded at 0x200.
+func newTestDebugger(program []byte) *Debugger {
+	chip := chip8.NewChip8()
+	chip.Keypad = chip8.NewMovieKeypad(nil)
+	chip.LoadFont()
+	chip.LoadProgram(program)
+	return New(chip, 10)
+}
+
+func TestDebugger(t *testing.T) {
+	// 0x200: V0 += 1, 0x202: V1 += 2, 0x204: jump to 0x200
+	program := []byte{0x70, 0x01, 0x71, 0x02, 0x12, 0x00}
+
+	t.Run("Step executes one instruction", func(t *testing.T) {
+		d := newTestDebugger(program)
+
+		d.Step()
+
+		if d.Chip.Pc != 0x202 || d.Chip.Registers[0] != 1 {
+			t.Errorf("got pc %x and V0 %d, want 202 and 1", d.Chip.Pc, d.Chip.Registers[0])
+		}
+	})
+
+	t.Run("Continue stops at breakpoint", func(t *testing.T) {
+		d := newTestDebugger(program)
+		d.SetBreakpoint(0x204)
+
+		reason := d.Continue(nil)
+
+		if reason != StopBreakpoint || d.Chip.Pc != 0x204 {
+			t.Errorf("got reason %d at %x, want breakpoint at 204", reason, d.Chip.Pc)
+		}
+
+		// continuing from a breakpoint runs the loop once more
+		d.Continue(nil)
+		if d.Chip.Registers[0] != 2 {
+			t.Errorf("got V0 %d, want 2", d.Chip.Registers[0])
+		}
+	})
+
+	t.Run("Continue stops on interrupt", func(t *testing.T) {
+		d := newTestDebugger(program)
+		interrupt := make(chan struct{})
+		close(interrupt)
+
+		if reason := d.Continue(interrupt); reason != StopInterrupt {
+			t.Errorf("got reason %d, want interrupt", reason)
+		}
+	})
+
+	t.Run("Timers are updated every tickrate instructions", func(t *testing.T) {
+		d := newTestDebugger(program)
+		d.Chip.Timers[0] = 5
+		frames := 0
+		d.OnFrame = func(frame int) { frames = frame }
+
+		for i := 0; i < 20; i++ {
+			d.Step()
+		}
+
+		if d.Chip.Timers[0] != 3 || frames != 2 {
+			t.Errorf("got delay timer %d after %d frames, want 3 after 2", d.Chip.Timers[0], frames)
+		}
+	})
+
+	t.Run("Waiting for the display ends the frame", func(t *testing.T) {
+		// 0x200: draw 0 rows, 0x202: V0 += 1, 0x204: jump to 0x200
+		d := newTestDebugger([]byte{0xd0, 0x00, 0x70, 0x01, 0x12, 0x00})
+		d.Chip.Quirks = chip8.QuirksVIP
+		frames := 0
+		d.OnFrame = func(frame int) { frames = frame }
+
+		for i := 0; i < 4; i++ {
+			d.Step()
+		}
+		if frames != 2 {
+			t.Errorf("got %d frames, want 2", frames)
+		}
+
+		// the second frame had 3 instructions, the last one is left after stepping back
+		d.StepBack()
+		if frames != 1 || d.instructions != 2 {
+			t.Errorf("got %d frames and %d instructions, want 1 frame and 2 instructions", frames, d.instructions)
+		}
+	})
+
+	t.Run("Conditional breakpoint stops on the interesting iteration", func(t *testing.T) {
+		d := newTestDebugger(program)
+		condition, _ := ParseExpression("V0 == 3")
+		breakpoint := d.AddBreakpoint(&Breakpoint{Address: 0x202, Condition: condition})
+
+		d.Continue(nil)
+
+		if d.Chip.Registers[0] != 3 || breakpoint.Hits != 1 {
+			t.Errorf("got V0 %d after %d hits, want 3 after 1", d.Chip.Registers[0], breakpoint.Hits)
+		}
+	})
+
+	t.Run("Breakpoint stops from the hit count", func(t *testing.T) {
+		d := newTestDebugger(program)
+		d.AddBreakpoint(&Breakpoint{Address: 0x200, HitCount: 4})
+
+		// the first instruction is executed before breakpoints are checked, so it isn't a hit
+		d.Continue(nil)
+
+		if d.Chip.Registers[0] != 4 {
+			t.Errorf("got V0 %d, want 4", d.Chip.Registers[0])
+		}
+	})
+
+	t.Run("Logpoint logs without stopping", func(t *testing.T) {
+		d := newTestDebugger(program)
+		message, _ := ParseLogMessage("V1={V1}")
+		d.AddBreakpoint(&Breakpoint{Address: 0x204, Log: message})
+		d.SetBreakpoint(0x202)
+		var logs []string
+		d.OnLog = func(breakpoint *Breakpoint, message string) { logs = append(logs, message) }
+
+		d.Continue(nil)
+		d.Continue(nil)
+
+		if len(logs) != 1 || logs[0] != "V1=2" || d.Chip.Pc != 0x202 {
+			t.Errorf("got logs %v at %x, want [V1=2] at 202", logs, d.Chip.Pc)
+		}
+	})
+
+	t.Run("Continue stops at watchpoint", func(t *testing.T) {
+		d := newTestDebugger(program)
+		condition, _ := chip8.ParseWatchCondition("value == 6")
+		d.SetWatchpoint(&chip8.Watchpoint{Target: chip8.WatchRegister, Address: 0x1, Access: chip8.WatchChange, Condition: condition})
+
+		reason := d.Continue(nil)
+
+		hits := d.WatchHits()
+		if reason != StopWatchpoint || d.Chip.Pc != 0x204 || len(hits) != 1 || hits[0].Pc != 0x202 {
+			t.Errorf("got reason %d at %x with hits %v, want watchpoint at 204 hit by 202", reason, d.Chip.Pc, hits)
+		}
+	})
+}
+
+func TestReverseExecution(t *testing.T) {
+	// 0x200: V0 += 1, 0x202: V1 += 2, 0x204: jump to 0x200
+	program := []byte{0x70, 0x01, 0x71, 0x02, 0x12, 0x00}
+
+	t.Run("Step back undoes the last instruction", func(t *testing.T) {
+		d := newTestDebugger(program)
+		d.Step()
+		d.Step()
+
+		if reason := d.StepBack(); reason != StopStep || d.Chip.Pc != 0x202 || d.Chip.Registers[1] != 0 {
+			t.Errorf("got reason %d at %x with V1 %d, want step at 202 with V1 0", reason, d.Chip.Pc, d.Chip.Registers[1])
+		}
+	})
+
+	t.Run("Reverse continue stops at the previous breakpoint", func(t *testing.T) {
+		d := newTestDebugger(program)
+		for i := 0; i < 30; i++ {
+			d.Step()
+		}
+		condition, _ := ParseExpression("V0 == 5")
+		d.AddBreakpoint(&Breakpoint{Address: 0x202, Condition: condition})
+
+		reason := d.ReverseContinue(nil)
+
+		if reason != StopBreakpoint || d.Chip.Pc != 0x202 || d.Chip.Registers[0] != 5 {
+			t.Errorf("got reason %d at %x with V0 %d, want breakpoint at 202 with V0 5", reason, d.Chip.Pc, d.Chip.Registers[0])
+		}
+	})
+
+	t.Run("Reverse continue stops at the start of the history", func(t *testing.T) {
+		d := newTestDebugger(program)
+		d.SetHistory(4)
+		d.Chip.Timers[0] = 10
+		for i := 0; i < 25; i++ {
+			d.Step()
+		}
+
+		reason := d.ReverseContinue(nil)
+
+		// 21 instructions were executed, which is 2 frames and 1 instruction
+		if reason != StopHistoryStart || d.Chip.Registers[0] != 7 || d.Chip.Timers[0] != 8 || d.frame != 2 {
+			t.Errorf("got reason %d with V0 %d, delay %d in frame %d, want start of history with V0 7, delay 8 in frame 2",
+				reason, d.Chip.Registers[0], d.Chip.Timers[0], d.frame)
+		}
+	})
+}
+
+func TestStepOverAndOut(t *testing.T) {
+	// 0x200: call 0x206, 0x202: V1 = 1, 0x204: jump to 0x204, 0x206: V0 += 1, 0x208: return
+	program := []byte{0x22, 0x06, 0x61, 0x01, 0x12, 0x04, 0x70, 0x01, 0x00, 0xee}
+
+	t.Run("Step over executes the whole subroutine", func(t *testing.T) {
+		d := newTestDebugger(program)
+
+		reason := d.StepOver(nil)
+
+		if reason != StopStep || d.Chip.Pc != 0x202 || d.Chip.Registers[0] != 1 {
+			t.Errorf("got reason %d at %x with V0 %d, want step at 202 with V0 1", reason, d.Chip.Pc, d.Chip.Registers[0])
+		}
+	})
+
+	t.Run("Step over stops at breakpoint inside the subroutine", func(t *testing.T) {
+		d := newTestDebugger(program)
+		d.SetBreakpoint(0x208)
+
+		if reason := d.StepOver(nil); reason != StopBreakpoint || d.Chip.Pc != 0x208 {
+			t.Errorf("got reason %d at %x, want breakpoint at 208", reason, d.Chip.Pc)
+		}
+	})
+
+	t.Run("Step out returns from the subroutine", func(t *testing.T) {
+		d := newTestDebugger(program)
+		d.Step()
+
+		if reason := d.StepOut(nil); reason != StopStep || d.Chip.Pc != 0x202 {
+			t.Errorf("got reason %d at %x, want step at 202", reason, d.Chip.Pc)
+		}
+	})
+}
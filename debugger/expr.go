@@ -0,0 +1,395 @@
+package debugger
+
+import (
+	"chip8emulator/chip8"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression is evaluated against the state of the chip, e.g. "V3 > 10 && I == 0x2F0" or
+// "mem[0x300] != 0". Values are integers, comparisons and logical operators return 1 or 0.
+//
+// Operands are numbers (decimal or 0x hexadecimal), registers V0-VF, I, PC, SP (depth of the
+// stack), DT, ST and bytes of memory mem[address]. Operators in the order of precedence:
+//
+//	! - (unary)
+//	* / %
+//	+ -
+//	<< >>
+//	&
+//	^
+//	|
+//	== != < <= > >=
+//	&&
+//	||
+type Expression struct {
+	source string
+	root   expressionNode
+}
+
+// expressionNode is a node of the parsed expression tree.
+type expressionNode interface {
+	evaluate(chip *chip8.Chip8) int
+}
+
+type numberNode int
+
+type registerNode string
+
+type memoryNode struct {
+	address expressionNode
+}
+
+type unaryNode struct {
+	operator string
+	operand  expressionNode
+}
+
+type binaryNode struct {
+	operator    string
+	left, right expressionNode
+}
+
+// ParseExpression parses text into an expression.
+func ParseExpression(text string) (*Expression, error) {
+	tokens, err := tokenizeExpression(text)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &expressionParser{tokens: tokens}
+	root, err := parser.parseBinary(0)
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", text, err)
+	}
+	if parser.position < len(parser.tokens) {
+		return nil, fmt.Errorf("expression %q: unexpected %q", text, parser.tokens[parser.position])
+	}
+	return &Expression{source: text, root: root}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate returns the value of the expression for the current state of chip.
+func (e *Expression) Evaluate(chip *chip8.Chip8) int {
+	return e.root.evaluate(chip)
+}
+
+// True reports whether the expression evaluates to a value different from 0.
+func (e *Expression) True(chip *chip8.Chip8) bool {
+	return e.Evaluate(chip) != 0
+}
+
+// binaryOperators are sorted from the lowest precedence.
+var binaryOperators = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<=", ">=", "<", ">"},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// expressionSymbols are recognized by the tokenizer, longer symbols first.
+var expressionSymbols = []string{"||", "&&", "==", "!=", "<=", ">=", "<<", ">>",
+	"<", ">", "|", "^", "&", "+", "-", "*", "/", "%", "!", "(", ")", "[", "]"}
+
+func tokenizeExpression(text string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(text); {
+		if text[i] == ' ' || text[i] == '\t' {
+			i++
+			continue
+		}
+
+		if isWordCharacter(text[i]) {
+			start := i
+			for i < len(text) && isWordCharacter(text[i]) {
+				i++
+			}
+			tokens = append(tokens, text[start:i])
+			continue
+		}
+
+		symbol := ""
+		for _, s := range expressionSymbols {
+			if strings.HasPrefix(text[i:], s) {
+				symbol = s
+				break
+			}
+		}
+		if symbol == "" {
+			return nil, fmt.Errorf("expression %q: unexpected character %q", text, text[i])
+		}
+		tokens = append(tokens, symbol)
+		i += len(symbol)
+	}
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("expression is empty")
+	}
+	return tokens, nil
+}
+
+func isWordCharacter(c byte) bool {
+	return c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+// expressionParser is a recursive descent parser of expressions.
+type expressionParser struct {
+	tokens   []string
+	position int
+}
+
+func (p *expressionParser) peek() string {
+	if p.position >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.position]
+}
+
+func (p *expressionParser) next() string {
+	token := p.peek()
+	p.position++
+	return token
+}
+
+func (p *expressionParser) expect(token string) error {
+	if got := p.next(); got != token {
+		if got == "" {
+			return fmt.Errorf("expected %q at the end", token)
+		}
+		return fmt.Errorf("expected %q, got %q", token, got)
+	}
+	return nil
+}
+
+// parseBinary parses operators with the given precedence and higher.
+func (p *expressionParser) parseBinary(precedence int) (expressionNode, error) {
+	if precedence == len(binaryOperators) {
+		return p.parseUnary()
+	}
+
+	left, err := p.parseBinary(precedence + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for contains(binaryOperators[precedence], p.peek()) {
+		operator := p.next()
+		right, err := p.parseBinary(precedence + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{operator: operator, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseUnary() (expressionNode, error) {
+	if operator := p.peek(); operator == "!" || operator == "-" {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{operator: operator, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *expressionParser) parsePrimary() (expressionNode, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end")
+	case token == "(":
+		node, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expect(")")
+	case strings.EqualFold(token, "mem"):
+		if err := p.expect("["); err != nil {
+			return nil, err
+		}
+		address, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		return memoryNode{address: address}, p.expect("]")
+	case token[0] >= '0' && token[0] <= '9':
+		number, err := strconv.ParseUint(token, 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", token)
+		}
+		return numberNode(number), nil
+	}
+
+	register := strings.ToUpper(token)
+	switch register {
+	case "I", "PC", "SP", "DT", "ST":
+		return registerNode(register), nil
+	}
+	if len(register) == 2 && register[0] == 'V' && strings.ContainsRune("0123456789ABCDEF", rune(register[1])) {
+		return registerNode(register), nil
+	}
+	return nil, fmt.Errorf("unknown name %q", token)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (n numberNode) evaluate(chip *chip8.Chip8) int {
+	return int(n)
+}
+
+func (n registerNode) evaluate(chip *chip8.Chip8) int {
+	switch n {
+	case "I":
+		return int(chip.I)
+	case "PC":
+		return int(chip.Pc)
+	case "SP":
+		return len(chip.Stack)
+	case "DT":
+		return int(chip.Timers[0])
+	case "ST":
+		return int(chip.Timers[1])
+	}
+	register, _ := strconv.ParseUint(string(n[1:]), 16, 8)
+	return int(chip.Registers[register])
+}
+
+func (n memoryNode) evaluate(chip *chip8.Chip8) int {
+	address := n.address.evaluate(chip)
+	if address < 0 || address > 0xffff {
+		return 0
+	}
+	return int(chip.ReadMemory(uint16(address)))
+}
+
+func (n unaryNode) evaluate(chip *chip8.Chip8) int {
+	value := n.operand.evaluate(chip)
+	if n.operator == "-" {
+		return -value
+	}
+	return boolToInt(value == 0)
+}
+
+func (n binaryNode) evaluate(chip *chip8.Chip8) int {
+	left := n.left.evaluate(chip)
+	// logical operators don't evaluate the right side if the result is known
+	switch n.operator {
+	case "&&":
+		return boolToInt(left != 0 && n.right.evaluate(chip) != 0)
+	case "||":
+		return boolToInt(left != 0 || n.right.evaluate(chip) != 0)
+	}
+
+	right := n.right.evaluate(chip)
+	switch n.operator {
+	case "==":
+		return boolToInt(left == right)
+	case "!=":
+		return boolToInt(left != right)
+	case "<":
+		return boolToInt(left < right)
+	case "<=":
+		return boolToInt(left <= right)
+	case ">":
+		return boolToInt(left > right)
+	case ">=":
+		return boolToInt(left >= right)
+	case "|":
+		return left | right
+	case "^":
+		return left ^ right
+	case "&":
+		return left & right
+	case "<<":
+		return left << uint(right&0x1f)
+	case ">>":
+		return left >> uint(right&0x1f)
+	case "+":
+		return left + right
+	case "-":
+		return left - right
+	case "*":
+		return left * right
+	case "/", "%":
+		// division by zero evaluates to 0 instead of stopping the emulator
+		if right == 0 {
+			return 0
+		}
+		if n.operator == "/" {
+			return left / right
+		}
+		return left % right
+	}
+	return 0
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// LogMessage is a message of a logpoint, expressions in braces are replaced by their values,
+// e.g. "score {mem[0x300]} at {PC}".
+type LogMessage struct {
+	// texts surround the expressions, there's one more text than expressions
+	texts       []string
+	expressions []*Expression
+}
+
+// ParseLogMessage parses text of a logpoint message.
+func ParseLogMessage(text string) (*LogMessage, error) {
+	message := &LogMessage{}
+	rest := text
+	for {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			message.texts = append(message.texts, rest)
+			return message, nil
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("log message %q: missing }", text)
+		}
+
+		expression, err := ParseExpression(rest[start+1 : start+end])
+		if err != nil {
+			return nil, fmt.Errorf("log message %q: %w", text, err)
+		}
+		message.texts = append(message.texts, rest[:start])
+		message.expressions = append(message.expressions, expression)
+		rest = rest[start+end+1:]
+	}
+}
+
+// Format returns the message with values of expressions for the current state of chip.
+func (m *LogMessage) Format(chip *chip8.Chip8) string {
+	var builder strings.Builder
+	for i, expression := range m.expressions {
+		builder.WriteString(m.texts[i])
+		builder.WriteString(strconv.Itoa(expression.Evaluate(chip)))
+	}
+	builder.WriteString(m.texts[len(m.texts)-1])
+	return builder.String()
+}
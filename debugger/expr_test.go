@@ -0,0 +1,71 @@
+package debugger
+
+import (
+	"chip8emulator/chip8"
+	"testing"
+)
+
+func TestExpression(t *testing.T) {
+	chip := chip8.NewChip8()
+	chip.Registers[0x3] = 12
+	chip.Registers[0xa] = 0xf0
+	chip.I = 0x2f0
+	chip.Memory[0x300] = 7
+	chip.Stack = append(chip.Stack, 0x202)
+
+	t.Run("Values of expressions", func(t *testing.T) {
+		cases := map[string]int{
+			"V3 > 10 && I == 0x2F0":    1,
+			"mem[0x300] != 0":          1,
+			"mem[I + 0x10] * 2":        14,
+			"v3 + 2 * 3":               18,
+			"(v3 + 2) * 3":             42,
+			"VA & 0x30 | 1":            0x31,
+			"VA >> 4 == 15":            1,
+			"!(SP == 1) || PC < 0x200": 0,
+			"-V3 + 20":                 8,
+			"V3 / 0":                   0,
+			"mem[0xffff]":              0,
+		}
+
+		for text, want := range cases {
+			expression, err := ParseExpression(text)
+			if err != nil {
+				t.Fatalf("didn't expect an error, got %v", err)
+			}
+			if got := expression.Evaluate(chip); got != want {
+				t.Errorf("%s: got %d, want %d", text, got, want)
+			}
+		}
+	})
+
+	t.Run("Invalid expressions", func(t *testing.T) {
+		for _, text := range []string{"", "V3 >", "VG == 1", "mem[1", "(1 + 2", "score == 1", "V3 = 1", "1 2"} {
+			if _, err := ParseExpression(text); err == nil {
+				t.Errorf("expected an error for %q", text)
+			}
+		}
+	})
+
+	t.Run("Log message", func(t *testing.T) {
+		message, err := ParseLogMessage("score {mem[0x300]} at {PC}")
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		got := message.Format(chip)
+		want := "score 7 at 512"
+
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Log message with invalid expression", func(t *testing.T) {
+		for _, text := range []string{"score {mem[}", "score {V3"} {
+			if _, err := ParseLogMessage(text); err == nil {
+				t.Errorf("expected an error for %q", text)
+			}
+		}
+	})
+}
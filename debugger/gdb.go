@@ -0,0 +1,581 @@
+package debugger
+
+import (
+	"bufio"
+	"chip8emulator/chip8"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// gdbRegister is a register of the chip exposed to GDB, registers are numbered by their
+// position in gdbRegisters.
+type gdbRegister struct {
+	name     string
+	bitsize  int
+	typeName string
+}
+
+var gdbRegisters = func() []gdbRegister {
+	var registers []gdbRegister
+	for i := 0; i < 16; i++ {
+		registers = append(registers, gdbRegister{name: fmt.Sprintf("v%x", i), bitsize: 8, typeName: "uint8"})
+	}
+	return append(registers,
+		gdbRegister{name: "i", bitsize: 16, typeName: "data_ptr"},
+		gdbRegister{name: "pc", bitsize: 16, typeName: "code_ptr"},
+		gdbRegister{name: "sp", bitsize: 8, typeName: "uint8"},
+		gdbRegister{name: "dt", bitsize: 8, typeName: "uint8"},
+		gdbRegister{name: "st", bitsize: 8, typeName: "uint8"},
+	)
+}()
+
+const (
+	gdbRegisterI = 16 + iota
+	gdbRegisterPc
+	gdbRegisterSp
+	gdbRegisterDt
+	gdbRegisterSt
+)
+
+// gdbTargetXML describes registers of the chip so GDB doesn't need built-in knowledge of chip8.
+var gdbTargetXML = func() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?>` + "\n")
+	b.WriteString(`<!DOCTYPE target SYSTEM "gdb-target.dtd">` + "\n")
+	b.WriteString(`<target version="1.0">` + "\n")
+	b.WriteString(`  <feature name="org.chip8.core">` + "\n")
+	for i, register := range gdbRegisters {
+		fmt.Fprintf(&b, `    <reg name="%s" bitsize="%d" type="%s" regnum="%d"/>`+"\n", register.name, register.bitsize, register.typeName, i)
+	}
+	b.WriteString("  </feature>\n</target>\n")
+	return b.String()
+}()
+
+// ListenGDB accepts GDB connections on addr (e.g. "localhost:2159") and serves them one at a time.
+func ListenGDB(addr string, d *Debugger) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		err = (&GDBServer{Debugger: d}).Serve(conn)
+		conn.Close()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+}
+
+// GDBServer implements the GDB remote serial protocol for one connection.
+type GDBServer struct {
+	Debugger *Debugger
+
+	conn       io.ReadWriter
+	writeMu    sync.Mutex
+	packets    chan string
+	interrupts chan struct{}
+	readErr    error
+	// watchpoints set by Z2-Z4 packets by "type,address"
+	watchpoints map[string]*chip8.Watchpoint
+}
+
+// Serve handles packets from conn until the client detaches or closes the connection.
+func (s *GDBServer) Serve(conn io.ReadWriter) error {
+	s.conn = conn
+	s.packets = make(chan string)
+	s.interrupts = make(chan struct{}, 1)
+	go s.read(bufio.NewReader(conn))
+
+	for packet := range s.packets {
+		reply, done := s.handle(packet)
+		if err := s.send(reply); err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+	return s.readErr
+}
+
+// read splits the incoming stream into packets and interrupts (byte 0x03). It stops
+// acknowledging packets after QStartNoAckMode, which is still acknowledged itself.
+func (s *GDBServer) read(r *bufio.Reader) {
+	defer close(s.packets)
+
+	noAck := false
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			s.readErr = err
+			return
+		}
+
+		switch b {
+		case 0x03:
+			select {
+			case s.interrupts <- struct{}{}:
+			default:
+			}
+		case '$':
+			data, err := r.ReadString('#')
+			if err != nil {
+				s.readErr = err
+				return
+			}
+			checksum := make([]byte, 2)
+			if _, err := io.ReadFull(r, checksum); err != nil {
+				s.readErr = err
+				return
+			}
+			data = strings.TrimSuffix(data, "#")
+
+			if !noAck {
+				if fmt.Sprintf("%02x", gdbChecksum(data)) != strings.ToLower(string(checksum)) {
+					s.write("-")
+					continue
+				}
+				s.write("+")
+			}
+			packet := gdbUnescape(data)
+			noAck = noAck || packet == "QStartNoAckMode"
+			s.packets <- packet
+		}
+		// acknowledgements '+' and '-' from the client are ignored
+	}
+}
+
+func (s *GDBServer) send(data string) error {
+	escaped := gdbEscape(data)
+	return s.write(fmt.Sprintf("$%s#%02x", escaped, gdbChecksum(escaped)))
+}
+
+// write sends raw data, acknowledgements are written by the reading goroutine so writes are serialized.
+func (s *GDBServer) write(data string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, err := io.WriteString(s.conn, data)
+	return err
+}
+
+// handle returns reply to packet and whether the session should end.
+func (s *GDBServer) handle(packet string) (string, bool) {
+	if packet == "" {
+		return "", false
+	}
+
+	d := s.Debugger
+	switch packet[0] {
+	case '?':
+		return "S05", false
+	case 'g':
+		d.Lock()
+		defer d.Unlock()
+		return s.readRegisters(), false
+	case 'G':
+		d.Lock()
+		defer d.Unlock()
+		return s.writeRegisters(packet[1:]), false
+	case 'p':
+		number, err := strconv.ParseUint(packet[1:], 16, 8)
+		if err != nil || int(number) >= len(gdbRegisters) {
+			return "E01", false
+		}
+		d.Lock()
+		defer d.Unlock()
+		return s.readRegister(int(number)), false
+	case 'P':
+		d.Lock()
+		defer d.Unlock()
+		return s.writeRegister(packet[1:]), false
+	case 'm':
+		d.Lock()
+		defer d.Unlock()
+		return s.readMemory(packet[1:]), false
+	case 'M':
+		d.Lock()
+		defer d.Unlock()
+		return s.writeMemory(packet[1:]), false
+	case 'Z', 'z':
+		return s.breakpoint(packet), false
+	case 's':
+		if address, ok := gdbResumeAddress(packet); ok {
+			d.Lock()
+			d.Chip.Pc = address
+			d.edited()
+			d.Unlock()
+		}
+		d.Step()
+		return "S05", false
+	case 'c':
+		if address, ok := gdbResumeAddress(packet); ok {
+			d.Lock()
+			d.Chip.Pc = address
+			d.edited()
+			d.Unlock()
+		}
+		// drop interrupts sent before continuing
+		select {
+		case <-s.interrupts:
+		default:
+		}
+		switch d.Continue(s.interrupts) {
+		case StopInterrupt:
+			return "S02", false
+		case StopWatchpoint:
+			return gdbWatchReply(d.WatchHits()), false
+		}
+		return "T05swbreak:;", false
+	case 'b':
+		return s.reverse(packet), false
+	case 'H':
+		return "OK", false
+	case 'k':
+		return "", true
+	case 'D':
+		return "OK", true
+	case 'q', 'Q':
+		return s.query(packet), false
+	}
+
+	// empty reply tells the client the packet is not supported
+	return "", false
+}
+
+func (s *GDBServer) query(packet string) string {
+	switch {
+	case strings.HasPrefix(packet, "qSupported"):
+		return "PacketSize=4000;qXfer:features:read+;swbreak+;QStartNoAckMode+;ReverseStep+;ReverseContinue+"
+	case packet == "QStartNoAckMode":
+		// the reading goroutine has already stopped acknowledging packets
+		return "OK"
+	case strings.HasPrefix(packet, "qXfer:features:read:target.xml:"):
+		return gdbXferRead(gdbTargetXML, strings.TrimPrefix(packet, "qXfer:features:read:target.xml:"))
+	case packet == "qAttached":
+		return "1"
+	case packet == "qC":
+		return "QC1"
+	case packet == "qfThreadInfo":
+		return "m1"
+	case packet == "qsThreadInfo":
+		return "l"
+	}
+	return ""
+}
+
+// registerValue returns value of register number n.
+func (s *GDBServer) registerValue(n int) uint16 {
+	chip := s.Debugger.Chip
+	switch n {
+	case gdbRegisterI:
+		return chip.I
+	case gdbRegisterPc:
+		return chip.Pc
+	case gdbRegisterSp:
+		return uint16(len(chip.Stack))
+	case gdbRegisterDt:
+		return uint16(chip.Timers[0])
+	case gdbRegisterSt:
+		return uint16(chip.Timers[1])
+	}
+	return uint16(chip.Registers[n])
+}
+
+func (s *GDBServer) setRegisterValue(n int, value uint16) {
+	chip := s.Debugger.Chip
+	switch n {
+	case gdbRegisterI:
+		chip.I = value
+	case gdbRegisterPc:
+		chip.Pc = value
+	case gdbRegisterSp:
+		// stack grows with zeroed return addresses or loses its top entries
+		for len(chip.Stack) < int(value) {
+			chip.Stack = append(chip.Stack, 0)
+		}
+		chip.Stack = chip.Stack[:value]
+	case gdbRegisterDt:
+		chip.Timers[0] = byte(value)
+	case gdbRegisterSt:
+		chip.Timers[1] = byte(value)
+	default:
+		chip.Registers[n] = byte(value)
+	}
+	s.Debugger.edited()
+}
+
+// readRegister encodes register n as little endian hexadecimal bytes.
+func (s *GDBServer) readRegister(n int) string {
+	value := s.registerValue(n)
+	if gdbRegisters[n].bitsize == 8 {
+		return fmt.Sprintf("%02x", value)
+	}
+	return fmt.Sprintf("%02x%02x", value&0xff, value>>8)
+}
+
+func (s *GDBServer) readRegisters() string {
+	var b strings.Builder
+	for n := range gdbRegisters {
+		b.WriteString(s.readRegister(n))
+	}
+	return b.String()
+}
+
+func (s *GDBServer) writeRegisters(data string) string {
+	bytes, err := hex.DecodeString(data)
+	if err != nil {
+		return "E01"
+	}
+
+	for n, register := range gdbRegisters {
+		size := register.bitsize / 8
+		if len(bytes) < size {
+			break
+		}
+		value := uint16(bytes[0])
+		if size == 2 {
+			value |= uint16(bytes[1]) << 8
+		}
+		s.setRegisterValue(n, value)
+		bytes = bytes[size:]
+	}
+	return "OK"
+}
+
+func (s *GDBServer) writeRegister(data string) string {
+	number, value, ok := strings.Cut(data, "=")
+	n, err := strconv.ParseUint(number, 16, 8)
+	if !ok || err != nil || int(n) >= len(gdbRegisters) {
+		return "E01"
+	}
+	bytes, err := hex.DecodeString(value)
+	if err != nil || len(bytes) != gdbRegisters[n].bitsize/8 {
+		return "E01"
+	}
+
+	registerValue := uint16(bytes[0])
+	if len(bytes) == 2 {
+		registerValue |= uint16(bytes[1]) << 8
+	}
+	s.setRegisterValue(int(n), registerValue)
+	return "OK"
+}
+
+// memoryRange parses "addr,length" and checks it's inside the memory of the chip.
+func (s *GDBServer) memoryRange(data string) (int, int, bool) {
+	addr, length, ok := strings.Cut(data, ",")
+	start, err1 := strconv.ParseUint(addr, 16, 32)
+	size, err2 := strconv.ParseUint(length, 16, 32)
+	if !ok || err1 != nil || err2 != nil || start+size > uint64(len(s.Debugger.Chip.Memory)) {
+		return 0, 0, false
+	}
+	return int(start), int(start + size), true
+}
+
+func (s *GDBServer) readMemory(data string) string {
+	start, end, ok := s.memoryRange(data)
+	if !ok {
+		return "E01"
+	}
+	return hex.EncodeToString(s.Debugger.Chip.Memory[start:end])
+}
+
+func (s *GDBServer) writeMemory(data string) string {
+	memoryRange, values, ok := strings.Cut(data, ":")
+	start, end, rangeOk := s.memoryRange(memoryRange)
+	bytes, err := hex.DecodeString(values)
+	if !ok || !rangeOk || err != nil || len(bytes) != end-start {
+		return "E01"
+	}
+	for i, value := range bytes {
+		s.Debugger.Chip.WriteMemory(uint16(start+i), value)
+	}
+	s.Debugger.edited()
+	return "OK"
+}
+
+// reverse handles bs (reverse step) and bc (reverse continue) packets.
+func (s *GDBServer) reverse(packet string) string {
+	var reason StopReason
+	switch packet {
+	case "bs":
+		reason = s.Debugger.StepBack()
+	case "bc":
+		select {
+		case <-s.interrupts:
+		default:
+		}
+		reason = s.Debugger.ReverseContinue(s.interrupts)
+	default:
+		return ""
+	}
+
+	switch reason {
+	case StopHistoryStart:
+		return "T05replaylog:begin;"
+	case StopInterrupt:
+		return "S02"
+	case StopBreakpoint:
+		return "T05swbreak:;"
+	}
+	return "S05"
+}
+
+// gdbWatchAccess maps types of Z packets to accesses of watchpoints.
+var gdbWatchAccess = map[string]chip8.WatchAccess{
+	"2": chip8.WatchWrite,
+	"3": chip8.WatchRead,
+	"4": chip8.WatchRead | chip8.WatchWrite,
+}
+
+// breakpoint handles Z (insert) and z (remove) packets of software and hardware breakpoints
+// and watchpoints.
+func (s *GDBServer) breakpoint(packet string) string {
+	fields := strings.Split(packet[1:], ",")
+	if len(fields) < 2 {
+		return ""
+	}
+	if _, ok := gdbWatchAccess[fields[0]]; ok {
+		return s.watchpoint(packet[0] == 'Z', fields)
+	}
+	if fields[0] != "0" && fields[0] != "1" {
+		return ""
+	}
+	address, err := strconv.ParseUint(fields[1], 16, 16)
+	if err != nil {
+		return "E01"
+	}
+
+	if packet[0] == 'Z' {
+		s.Debugger.SetBreakpoint(uint16(address))
+	} else {
+		s.Debugger.ClearBreakpoint(uint16(address))
+	}
+	return "OK"
+}
+
+// watchpoint inserts or removes watchpoints on every byte of "type,address,length".
+func (s *GDBServer) watchpoint(insert bool, fields []string) string {
+	address, err := strconv.ParseUint(fields[1], 16, 16)
+	length := uint64(1)
+	if len(fields) > 2 {
+		length, _ = strconv.ParseUint(fields[2], 16, 16)
+	}
+	if err != nil || length == 0 {
+		return "E01"
+	}
+	if s.watchpoints == nil {
+		s.watchpoints = map[string]*chip8.Watchpoint{}
+	}
+
+	for current := address; current < address+length; current++ {
+		key := fmt.Sprintf("%s,%x", fields[0], current)
+		if watchpoint, ok := s.watchpoints[key]; ok {
+			s.Debugger.ClearWatchpoint(watchpoint)
+			delete(s.watchpoints, key)
+		}
+		if insert {
+			watchpoint := &chip8.Watchpoint{Target: chip8.WatchMemory, Address: uint16(current), Access: gdbWatchAccess[fields[0]]}
+			s.Debugger.SetWatchpoint(watchpoint)
+			s.watchpoints[key] = watchpoint
+		}
+	}
+	return "OK"
+}
+
+// gdbWatchReply returns stop reply for the first hit of a memory watchpoint, e.g. "T05watch:2f0;".
+func gdbWatchReply(hits []chip8.WatchHit) string {
+	for _, hit := range hits {
+		if hit.Watchpoint.Target != chip8.WatchMemory {
+			continue
+		}
+		kind := "watch"
+		if hit.Watchpoint.Access&chip8.WatchWrite == 0 {
+			kind = "rwatch"
+		} else if hit.Watchpoint.Access&chip8.WatchRead != 0 {
+			kind = "awatch"
+		}
+		return fmt.Sprintf("T05%s:%x;", kind, hit.Watchpoint.Address)
+	}
+	return "S05"
+}
+
+// gdbResumeAddress parses optional address of 's' and 'c' packets.
+func gdbResumeAddress(packet string) (uint16, bool) {
+	if len(packet) == 1 {
+		return 0, false
+	}
+	address, err := strconv.ParseUint(packet[1:], 16, 16)
+	return uint16(address), err == nil
+}
+
+// gdbXferRead returns part of document requested by "offset,length" of a qXfer packet.
+func gdbXferRead(document, request string) string {
+	offsetText, lengthText, _ := strings.Cut(request, ",")
+	offset, err1 := strconv.ParseUint(offsetText, 16, 32)
+	length, err2 := strconv.ParseUint(lengthText, 16, 32)
+	if err1 != nil || err2 != nil {
+		return "E01"
+	}
+	if offset >= uint64(len(document)) {
+		return "l"
+	}
+
+	end := offset + length
+	if end >= uint64(len(document)) {
+		return "l" + document[offset:]
+	}
+	return "m" + document[offset:end]
+}
+
+func gdbChecksum(data string) byte {
+	var sum byte
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+	return sum
+}
+
+// gdbEscape escapes characters which can't appear in the data of a packet.
+func gdbEscape(data string) string {
+	var b strings.Builder
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '#', '$', '}', '*':
+			b.WriteByte('}')
+			b.WriteByte(data[i] ^ 0x20)
+		default:
+			b.WriteByte(data[i])
+		}
+	}
+	return b.String()
+}
+
+func gdbUnescape(data string) string {
+	if !strings.Contains(data, "}") {
+		return data
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(data); i++ {
+		if data[i] == '}' && i+1 < len(data) {
+			i++
+			b.WriteByte(data[i] ^ 0x20)
+			continue
+		}
+		b.WriteByte(data[i])
+	}
+	return b.String()
+}
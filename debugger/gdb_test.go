@@ -0,0 +1,165 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// gdbClient sends packets to GDBServer over an in-memory connection.
+type gdbClient struct {
+	t      testing.TB
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newGDBClient(t testing.TB, d *Debugger) *gdbClient {
+	server, client := net.Pipe()
+	go (&GDBServer{Debugger: d}).Serve(server)
+	t.Cleanup(func() { client.Close() })
+	return &gdbClient{t: t, conn: client, reader: bufio.NewReader(client)}
+}
+
+// request sends packet and returns the reply.
+func (c *gdbClient) request(packet string) string {
+	c.t.Helper()
+	fmt.Fprintf(c.conn, "$%s#%02x", packet, gdbChecksum(packet))
+
+	if ack, err := c.reader.ReadByte(); err != nil || ack != '+' {
+		c.t.Fatalf("expected acknowledgement, got %q %v", ack, err)
+	}
+	if start, err := c.reader.ReadByte(); err != nil || start != '$' {
+		c.t.Fatalf("expected start of the reply, got %q %v", start, err)
+	}
+	reply, err := c.reader.ReadString('#')
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	checksum := make([]byte, 2)
+	c.reader.Read(checksum)
+	c.conn.Write([]byte("+"))
+
+	return gdbUnescape(strings.TrimSuffix(reply, "#"))
+}
+
+func TestGDBServer(t *testing.T) {
+	program := []byte{0x70, 0x01, 0x71, 0x02, 0x12, 0x00}
+
+	t.Run("Read registers", func(t *testing.T) {
+		d := newTestDebugger(program)
+		d.Chip.Registers[0x1] = 0xab
+		d.Chip.I = 0x123
+		client := newGDBClient(t, d)
+
+		got := client.request("g")
+		want := "00ab" + strings.Repeat("00", 14) + "2301" + "0002" + "00" + "00" + "00"
+
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Write register and memory", func(t *testing.T) {
+		d := newTestDebugger(program)
+		client := newGDBClient(t, d)
+		client.request("s")
+
+		if got := client.request("P10=f002"); got != "OK" {
+			t.Fatalf("got %q, want OK", got)
+		}
+		if got := client.request("M300,2:beef"); got != "OK" {
+			t.Fatalf("got %q, want OK", got)
+		}
+
+		if d.Chip.I != 0x2f0 {
+			t.Errorf("got I %x, want 2f0", d.Chip.I)
+		}
+		if got := client.request("m300,2"); got != "beef" {
+			t.Errorf("got %q, want beef", got)
+		}
+		if got := client.request("bs"); got != "T05replaylog:begin;" || d.Chip.Pc != 0x202 {
+			t.Errorf("got %q at %x, want the step before the writes forgotten", got, d.Chip.Pc)
+		}
+	})
+
+	t.Run("Packets after QStartNoAckMode aren't acknowledged", func(t *testing.T) {
+		client := newGDBClient(t, newTestDebugger(program))
+
+		if got := client.request("QStartNoAckMode"); got != "OK" {
+			t.Fatalf("got %q, want OK", got)
+		}
+		fmt.Fprintf(client.conn, "$?#%02x", gdbChecksum("?"))
+
+		if got, err := client.reader.ReadString('#'); err != nil || got != "$S05#" {
+			t.Errorf("got %q %v, want $S05# without acknowledgement", got, err)
+		}
+	})
+
+	t.Run("Breakpoint stops continue", func(t *testing.T) {
+		d := newTestDebugger(program)
+		client := newGDBClient(t, d)
+
+		if got := client.request("Z0,204,2"); got != "OK" {
+			t.Fatalf("got %q, want OK", got)
+		}
+		if got := client.request("c"); got != "T05swbreak:;" {
+			t.Fatalf("got %q, want T05swbreak:;", got)
+		}
+		if got := client.request("p11"); got != "0402" {
+			t.Errorf("got pc %q, want 0402", got)
+		}
+
+		client.request("z0,204,2")
+		if got := client.request("s"); got != "S05" {
+			t.Fatalf("got %q, want S05", got)
+		}
+		if d.Chip.Pc != 0x200 {
+			t.Errorf("got pc %x, want 200", d.Chip.Pc)
+		}
+	})
+
+	t.Run("Watchpoint stops continue", func(t *testing.T) {
+		// 0x200: I = 0x300, 0x202: store V0, 0x204: jump to 0x202
+		d := newTestDebugger([]byte{0xa3, 0x00, 0xf0, 0x55, 0x12, 0x02})
+		client := newGDBClient(t, d)
+
+		if got := client.request("Z2,301,1"); got != "OK" {
+			t.Fatalf("got %q, want OK", got)
+		}
+		if got := client.request("c"); got != "T05watch:301;" {
+			t.Fatalf("got %q, want T05watch:301;", got)
+		}
+
+		client.request("z2,301,1")
+		if got := d.Watchpoints(); len(got) != 0 {
+			t.Errorf("got %v, want no watchpoints", got)
+		}
+	})
+
+	t.Run("Reverse step and continue", func(t *testing.T) {
+		d := newTestDebugger(program)
+		client := newGDBClient(t, d)
+		client.request("s")
+		client.request("s")
+
+		if got := client.request("bs"); got != "S05" || d.Chip.Pc != 0x202 {
+			t.Fatalf("got %q at %x, want S05 at 202", got, d.Chip.Pc)
+		}
+		if got := client.request("bc"); got != "T05replaylog:begin;" || d.Chip.Pc != 0x200 {
+			t.Errorf("got %q at %x, want T05replaylog:begin; at 200", got, d.Chip.Pc)
+		}
+	})
+
+	t.Run("Target description lists registers", func(t *testing.T) {
+		d := newTestDebugger(program)
+		client := newGDBClient(t, d)
+
+		got := client.request("qXfer:features:read:target.xml:0,fff")
+
+		if !strings.HasPrefix(got, "l<?xml") || !strings.Contains(got, `name="pc" bitsize="16"`) {
+			t.Errorf("unexpected target description %q", got)
+		}
+	})
+}
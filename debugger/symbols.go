@@ -0,0 +1,130 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SourceLocation is a line in a source file of the program.
+type SourceLocation struct {
+	File string
+	Line int
+}
+
+// SymbolMap connects addresses of the program with labels and lines of the source written by an assembler.
+//
+// The map is stored as text, each line contains an address followed by a source location
+// (file:line) or a label:
+//
+//	# address  location or label
+//	0x200      game.8o:12
+//	0x200      main
+//	0x204      game.8o:13
+type SymbolMap struct {
+	locations map[uint16]SourceLocation
+	labels    map[uint16]string
+	// addresses of labels sorted in increasing order
+	labelAddresses []uint16
+}
+
+// ParseSymbolMap reads a symbol map from r.
+func ParseSymbolMap(r io.Reader) (*SymbolMap, error) {
+	symbols := &SymbolMap{locations: map[uint16]SourceLocation{}, labels: map[uint16]string{}}
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("symbol map line %d: expected address and location or label, got %q", line, text)
+		}
+
+		address, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(fields[0]), "0x"), 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("symbol map line %d: invalid address %q", line, fields[0])
+		}
+
+		// locations end with :line, everything else is a label
+		if i := strings.LastIndex(fields[1], ":"); i > 0 {
+			if sourceLine, err := strconv.Atoi(fields[1][i+1:]); err == nil {
+				symbols.locations[uint16(address)] = SourceLocation{File: fields[1][:i], Line: sourceLine}
+				continue
+			}
+		}
+		symbols.labels[uint16(address)] = fields[1]
+		symbols.labelAddresses = append(symbols.labelAddresses, uint16(address))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(symbols.labelAddresses, func(i, j int) bool {
+		return symbols.labelAddresses[i] < symbols.labelAddresses[j]
+	})
+	return symbols, nil
+}
+
+// LoadSymbolMap reads a symbol map from the file at path.
+func LoadSymbolMap(path string) (*SymbolMap, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseSymbolMap(file)
+}
+
+// Addresses returns addresses of instructions generated from line of file. Files are matched by
+// their names so paths in the map don't have to be the same as paths used by the editor.
+func (s *SymbolMap) Addresses(file string, line int) []uint16 {
+	var addresses []uint16
+	for address, location := range s.locations {
+		if location.Line == line && sameFile(location.File, file) {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i] < addresses[j] })
+	return addresses
+}
+
+// Location returns the source line of the instruction at address.
+func (s *SymbolMap) Location(address uint16) (SourceLocation, bool) {
+	location, ok := s.locations[address]
+	return location, ok
+}
+
+// Label returns name of address relative to the closest preceding label, e.g. "main+0x4".
+// It returns false if there's no label before address.
+func (s *SymbolMap) Label(address uint16) (string, bool) {
+	i := sort.Search(len(s.labelAddresses), func(i int) bool { return s.labelAddresses[i] > address }) - 1
+	if i < 0 {
+		return "", false
+	}
+
+	start := s.labelAddresses[i]
+	if start == address {
+		return s.labels[start], true
+	}
+	return fmt.Sprintf("%s+0x%X", s.labels[start], address-start), true
+}
+
+func sameFile(a, b string) bool {
+	return fileName(a) == fileName(b)
+}
+
+// fileName returns the last element of a path written with slashes or backslashes.
+func fileName(name string) string {
+	return path.Base(strings.ReplaceAll(name, "\\", "/"))
+}
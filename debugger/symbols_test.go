@@ -0,0 +1,65 @@
+package debugger
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSymbolMap(t *testing.T) {
+	source := `# program
+0x200 main
+0x200 src/game.8o:3
+0x202 src/game.8o:4
+0x204 src/game.8o:4
+0x206 draw
+0x206 src/game.8o:9
+`
+	symbols, err := ParseSymbolMap(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("didn't expect an error, got %v", err)
+	}
+
+	t.Run("Addresses of a line", func(t *testing.T) {
+		got := symbols.Addresses(`C:\projects\src\game.8o`, 4)
+		want := []uint16{0x202, 0x204}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Line without instructions", func(t *testing.T) {
+		if got := symbols.Addresses("game.8o", 5); len(got) != 0 {
+			t.Errorf("got %v, want no addresses", got)
+		}
+	})
+
+	t.Run("Location of an address", func(t *testing.T) {
+		got, ok := symbols.Location(0x206)
+		want := SourceLocation{File: "src/game.8o", Line: 9}
+
+		if !ok || got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Labels", func(t *testing.T) {
+		cases := map[uint16]string{0x200: "main", 0x204: "main+0x4", 0x20a: "draw+0x4"}
+		for address, want := range cases {
+			if got, _ := symbols.Label(address); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		}
+		if _, ok := symbols.Label(0x100); ok {
+			t.Errorf("didn't expect a label before the first one")
+		}
+	})
+
+	t.Run("Invalid address", func(t *testing.T) {
+		_, err := ParseSymbolMap(strings.NewReader("0xZZZ main\n"))
+		if err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
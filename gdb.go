@@ -0,0 +1,49 @@
+package main
+
+import (
+	"chip8emulator/debugger"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runGDB serves the program to GDB or another client of the remote serial protocol.
+// Execution is stopped at the first instruction until the client continues it.
+func runGDB(args []string) error {
+	flags := flag.NewFlagSet("gdb", flag.ContinueOnError)
+	addr := flags.String("addr", "localhost:2159", "address the server listens on")
+	tickrate := flags.Int("tickrate", 10, "number of instructions executed per frame")
+	movie := flags.String("movie", "", "input movie played back during the run")
+	history := flags.Int("history", debugger.DefaultHistory, "number of instructions which can be executed backwards")
+	trace := addTraceFlags(flags)
+	platform := addPlatformFlag(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: gdb [flags] file.ch8")
+	}
+
+	selected, err := platform.platform()
+	if err != nil {
+		return err
+	}
+	headless, err := newHeadlessRun(flags.Arg(0), *movie, selected)
+	if err != nil {
+		return err
+	}
+	closeTrace, err := trace.attach(headless.chip)
+	if err != nil {
+		return err
+	}
+	defer closeTrace()
+
+	d := debugger.New(headless.chip, *tickrate)
+	d.OnFrame = headless.keypad.SetFrame
+	d.SetHistory(*history)
+
+	fmt.Fprintf(os.Stdout, "waiting for GDB on %s, e.g. gdb -ex 'target remote %s'\n", *addr, *addr)
+	return debugger.ListenGDB(*addr, d)
+}
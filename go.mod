@@ -6,6 +6,7 @@ require (
 	github.com/gen2brain/raylib-go/raygui v0.0.0-20240125111008-83d871a38f28
 	github.com/gen2brain/raylib-go/raylib v0.0.0-20231230150416-17ce08145200
 	golang.org/x/exp v0.0.0-20240222234643-814bf88cf225
+	golang.org/x/term v0.16.0
 )
 
 require (
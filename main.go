@@ -2,12 +2,15 @@ package main
 
 import (
 	"chip8emulator/chip8"
+	"chip8emulator/cosmac"
+	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	gui "github.com/gen2brain/raylib-go/raygui"
 	rl "github.com/gen2brain/raylib-go/raylib"
@@ -24,6 +27,8 @@ const colorUIHeight = int32(100)
 const topUIHeight = int32(50)
 
 var state string = "menu"
+var romName string
+var activeRecording *recording
 var uiTextColor rl.Color
 var dropTarget rl.RenderTexture2D
 
@@ -33,6 +38,22 @@ type WrongFilenameExtension struct {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	trace := addTraceFlags(flag.CommandLine)
+	quirks := addQuirksFlag(flag.CommandLine)
+	platform := addPlatformFlag(flag.CommandLine)
+	timing := addTimingFlags(flag.CommandLine)
+	vipPath := flag.String("vip", "", "run programs on an emulated COSMAC VIP with the original interpreter from this file")
+	flag.Parse()
 
 	//initialize chip8
 	chip := chip8.NewChip8()
@@ -40,27 +61,47 @@ func main() {
 		chip.Screen[i] = rl.Black
 	}
 
-	font := []byte{
-		0xf0, 0x90, 0x90, 0x90, 0xf0, // 0
-		0x20, 0x60, 0x20, 0x20, 0x70, // 1
-		0xf0, 0x10, 0xf0, 0x80, 0xf0, // 2
-		0xf0, 0x10, 0xf0, 0x10, 0xf0, // 3
-		0x90, 0x90, 0xf0, 0x10, 0x10, // 4
-		0xf0, 0x80, 0xf0, 0x10, 0xf0, // 5
-		0xf0, 0x80, 0xf0, 0x90, 0xf0, // 6
-		0xf0, 0x10, 0x20, 0x40, 0x40, // 7
-		0xf0, 0x90, 0xf0, 0x90, 0xf0, // 8
-		0xf0, 0x90, 0xf0, 0x10, 0xf0, // 9
-		0xf0, 0x90, 0xf0, 0x90, 0x90, // A
-		0xe0, 0x90, 0xe0, 0x90, 0xe0, // B
-		0xf0, 0x80, 0x80, 0x80, 0xf0, // C
-		0xe0, 0x90, 0x90, 0x90, 0xe0, // D
-		0xf0, 0x80, 0xf0, 0x80, 0xf0, // E
-		0xf0, 0x80, 0xf0, 0x80, 0x80, // F
+	if err := quirks.attach(chip); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := platform.attach(chip); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := timing.attach(chip); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// the engine is created after the platform which decides the size of memory
+	chip.Engine = chip8.NewCachedEngine(chip)
+	closeTrace, err := trace.attach(chip)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer func() {
+		if err := closeTrace(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}()
+
+	// with -vip programs are executed by the interpreter on the emulated CPU, the VIP is created
+	// when a program is picked and its state is copied to chip after every frame
+	var interpreter []byte
+	var vip *cosmac.VIP
+	if *vipPath != "" {
+		interpreter, err = os.ReadFile(*vipPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 	}
 
-	copy(chip.Memory[0x00:len(font)], font)
+	chip.LoadFont()
 	emulator := chip8.Emulator{EmulatorStore: chip}
+	memory := newMemoryView(chip)
+	paused := false
 
 	rl.InitWindow(width, height+colorUIHeight, "Chip8")
 
@@ -107,6 +148,10 @@ func main() {
 	dropTarget = rl.LoadRenderTexture(width, height)
 
 	var colorTint rl.Color = uiTextColor
+	// colours of CHIP-8X are shown as they are
+	if chip.Colors != nil {
+		colorTint = rl.White
+	}
 
 	// load pixel font
 	pixelFont := rl.LoadFont("assets/pixelplay.png")
@@ -119,15 +164,41 @@ func main() {
 
 	var program []byte
 
-	var tickrateSpinner int32 = 10
+	var tickrateSpinner int32 = int32(*timing.tickrate)
 	mouseInTickrate := false
 	var mousePos rl.Vector2
 	tickrateSpinnerRect := rl.NewRectangle(100.0, 20.0, 100, 30)
 	// return to main menu button
 	var mainMenuButton bool
 
+	// ticks of emulated time run for the time the last frame took, so the timers keep 60 Hz
+	// when rendering lags
+	pacer := chip8.Pacer{MaxTicks: 10}
+	updateScreen := func() {
+		// HIRES programs change the size of the screen
+		if t.Width != int32(chip.Width) || t.Height != int32(chip.Height) {
+			rl.UnloadTexture(t)
+			resized := rl.Image{Format: rl.UncompressedR8g8b8a8, Width: int32(chip.Width), Height: int32(chip.Height), Mipmaps: 1}
+			t = rl.LoadTextureFromImage(&resized)
+			rl.SetTextureFilter(t, rl.TextureFilterNearest)
+			chip.Texture = t
+		}
+		rl.BeginTextureMode(target)
+		rl.DrawTexturePro(t, rl.Rectangle{X: 0, Y: 0, Width: float32(t.Width), Height: float32(t.Height)}, rl.Rectangle{X: 0, Y: 0, Width: float32(width), Height: float32(height)}, rl.Vector2{X: 0, Y: 0}, 0, rl.White)
+		rl.UpdateTexture(chip.Texture, chip.ScreenColors())
+		rl.EndTextureMode()
+	}
 	for !rl.WindowShouldClose() {
 		if state == "play" {
+			if interpreter != nil && vip == nil {
+				vip, err = cosmac.NewVIP(interpreter, program, chip.Platform.Layout().MemorySize)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+				vip.Keypad = chip.Keypad
+			}
+
 			rl.BeginDrawing()
 			// render topUI to buffer
 			rl.BeginTextureMode(topUITarget)
@@ -141,11 +212,18 @@ func main() {
 			tickrateSpinner = gui.Spinner(tickrateSpinnerRect, "tickrate", &tickrateSpinner, 1, 1000, mouseInTickrate)
 			mainMenuButton = gui.Button(rl.NewRectangle(0.0, 0.0, 100, 50), "Main Menu")
 			if mainMenuButton {
+				stopRecording()
+				paused = false
+				memory.profiler.Reset()
+				vip = nil
 				state = "menu"
-				chip.Pc = 0x200
 				continue
 			}
 
+			if activeRecording != nil {
+				rl.DrawText("REC", 220, 15, 20, rl.Red)
+			}
+
 			if rl.CheckCollisionPointRec(mousePos, tickrateSpinnerRect) {
 				mouseInTickrate = true
 			} else {
@@ -175,47 +253,64 @@ func main() {
 
 			rl.EndTextureMode()
 
-			// run 10 instructions per frame
-			for i := 0; i < int(tickrateSpinner); i++ {
-				if rl.WindowShouldClose() {
-					rl.CloseWindow()
-				}
+			// F5 pauses and resumes the emulation, F8 shows the memory view
+			if rl.IsKeyPressed(rl.KeyF5) {
+				paused = !paused
+			}
+			if rl.IsKeyPressed(rl.KeyF8) {
+				memory.toggle(chip)
+			}
 
-				firstByte := chip.Memory[chip.Pc]
-				secondByte := chip.Memory[chip.Pc+1]
-				emulator.Emulate(firstByte, secondByte)
+			// the spinner sets the speed unless -ips or -timing was given
+			if timing.followsTickrate() {
+				chip.Timing = chip8.TickrateTiming(int(tickrateSpinner))
+			}
 
-				if (firstByte == 0x00 && secondByte == 0xe0) || (firstByte>>4 == 0xd) {
-					rl.BeginTextureMode(target)
-					rl.DrawTexturePro(t, rl.Rectangle{X: 0, Y: 0, Width: float32(textureWidth), Height: float32(textureHeight)}, rl.Rectangle{X: 0, Y: 0, Width: float32(width), Height: float32(height)}, rl.Vector2{X: 0, Y: 0}, 0, rl.White)
-					rl.UpdateTexture(chip.Texture, chip.Screen)
-					rl.EndTextureMode()
+			// play sound while the sound timer is active
+			buzzer := false
+			if !paused {
+				elapsed := time.Duration(float64(rl.GetFrameTime()) * float64(time.Second))
+				for ticks := pacer.Advance(elapsed); ticks > 0; ticks-- {
+					if vip != nil {
+						buzzer = vip.RunTick() || buzzer
+						vip.CopyTo(chip)
+					} else {
+						buzzer = chip.RunTick(memory.onStep(chip)) || buzzer
+					}
+					// the screen is redrawn once per tick, redrawing after every instruction would
+					// need onStep which bypasses the engine
+					updateScreen()
+					memory.tracker.NextFrame()
 				}
+			}
+			if buzzer {
+				if !rl.IsSoundPlaying(sound) {
+					rl.PlaySound(sound)
+				}
+			} else {
+				rl.StopSound(sound)
+			}
 
-				// these instructions should not increase pc
-				if firstByte>>4 != 0x1 && firstByte>>4 != 0x2 {
-					chip.Pc += 2
+			// F12 saves screenshot in native resolution, Shift+F12 in the resolution of the window
+			if rl.IsKeyPressed(rl.KeyF12) {
+				scale := 1
+				if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
+					scale = int(width) / int(chip.Width)
 				}
+				saveScreenshot(chip, scale, colorTint)
 			}
 
-			for t := range chip.Timers {
-				if chip.Timers[t] > 0 {
-					// chip.Timers[1] is a sound timer so if it's greater than 0 play sound
-					if t == 1 {
-						if !rl.IsSoundPlaying(sound) {
-							rl.PlaySound(sound)
-						}
-					}
-					chip.Timers[t] -= 1
+			// F10 starts and stops recording of the gameplay
+			if rl.IsKeyPressed(rl.KeyF10) {
+				if activeRecording == nil {
+					activeRecording = newRecording(colorTint)
 				} else {
-					// deactivate sound timer and stop sound
-					if t == 1 {
-						rl.StopSound(sound)
-					}
-					chip.Timers[t] = 0
-
+					stopRecording()
 				}
 			}
+			if activeRecording != nil && !activeRecording.addFrame(chip, buzzer) {
+				stopRecording()
+			}
 
 			// render topUI
 			rl.DrawTexturePro(topUITarget.Texture,
@@ -240,6 +335,11 @@ func main() {
 				rl.White,
 			)
 
+			if memory.visible {
+				memory.update(chip, paused)
+				memory.draw(chip, paused)
+			}
+
 			rl.EndDrawing()
 		} else {
 			// call instruction 0x00e0 to clear the screen
@@ -248,14 +348,17 @@ func main() {
 
 			// create slice of zeroes to reset chip's memory, registers and
 			// timers
+			loadAddress := chip.Platform.Layout().LoadAddress
 			var slice []byte
-			for i := 0; i < len(chip.Memory[0x200:]); i++ {
+			for i := 0; i < len(chip.Memory[loadAddress:]); i++ {
 				slice = append(slice, 0)
 			}
 
-			copy(chip.Memory[0x200:], slice)
+			copy(chip.Memory[loadAddress:], slice)
 			copy(chip.Registers[:], slice[:0xf])
 			copy(chip.Timers[:], slice[:0x2])
+			// pc and colours of CHIP-8X start over for the next program
+			chip.SetPlatform(chip.Platform)
 			program = displayMainMenu(chip, program, pixelFont, centerDropTextX, centerDropTextY)
 		}
 	}
@@ -265,6 +368,29 @@ func main() {
 	rl.UnloadRenderTexture(topUITarget)
 }
 
+// saveScreenshot saves the screen of chip to the current directory.
+func saveScreenshot(chip *chip8.Chip8, scale int, tint rl.Color) {
+	path, err := chip.SaveScreenshot(".", romName, scale, tint)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Fprintln(os.Stdout, "screenshot saved to", path)
+}
+
+// stopRecording saves the current recording to the current directory.
+func stopRecording() {
+	if activeRecording == nil {
+		return
+	}
+
+	err := activeRecording.save(".", romName)
+	activeRecording = nil
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
 func readFileToBuffer(filepath string) []byte {
 	file, err := os.Open(filepath)
 
@@ -383,15 +509,18 @@ func displayMainMenu(chip *chip8.Chip8, program []byte, font rl.Font, centerDrop
 	}
 
 	if rl.IsFileDropped() {
-		program = readFileToBuffer(rl.LoadDroppedFiles()[0])
-		copy(chip.Memory[0x200:], program)
+		path := rl.LoadDroppedFiles()[0]
+		program = readFileToBuffer(path)
+		romName = GetFilenameFromGUI(path)
+		chip.LoadProgram(program)
 		state = "play"
 	}
 
 	if okButton {
 		okButton = false
-		program = readFileToBuffer(defaultGames[listOfGames[gamePicked]])
-		copy(chip.Memory[0x200:], program)
+		romName = defaultGames[listOfGames[gamePicked]]
+		program = readFileToBuffer(romName)
+		chip.LoadProgram(program)
 		state = "play"
 	}
 
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"chip8emulator/chip8"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	gui "github.com/gen2brain/raylib-go/raygui"
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const memoryViewColumns = 16
+const memoryViewWidth = int32(540)
+const memoryViewHeaderHeight = int32(40)
+const memoryViewRowHeight = int32(22)
+const memoryViewCellWidth = int32(28)
+const memoryViewAddressWidth = int32(70)
+
+// flashFrames is the number of frames a written byte stays highlighted.
+const flashFrames = 30
+
+// memoryView is a hex view of the chip's memory docked to the left or right side of the screen.
+// While the emulation is paused bytes can be edited in place by typing hexadecimal digits.
+type memoryView struct {
+	visible   bool
+	dockRight bool
+	// top is the address of the first visible row
+	top    uint16
+	cursor uint16
+	// typedNibble is true after the high nibble of the byte at the cursor was typed
+	typedNibble bool
+
+	addressInput string
+	addressEdit  bool
+
+	tracker *chip8.MemoryWriteTracker
+	// heatmap colors executed addresses by the number of executions counted by profiler
+	heatmap  bool
+	profiler *chip8.Profiler
+	// sprite is the memory region read by the last draw instruction
+	spriteAddress uint16
+	spriteLength  uint16
+}
+
+// newMemoryView creates hidden view which tracks writes to memory of chip. The chip is profiled
+// only while the heatmap is shown, profiling makes the engine fall back to Step.
+func newMemoryView(chip *chip8.Chip8) *memoryView {
+	tracker := chip8.NewMemoryWriteTracker()
+	chip.MemoryObserver = tracker
+	profiler := chip8.NewProfiler(len(chip.Memory))
+	// the view starts at the program
+	start := chip.Platform.Layout().LoadAddress
+	return &memoryView{dockRight: true, top: start, cursor: start, tracker: tracker, profiler: profiler}
+}
+
+// rows returns the number of rows which fit on the screen.
+func (v *memoryView) rows() int {
+	return int((height - topUIHeight - memoryViewHeaderHeight) / memoryViewRowHeight)
+}
+
+func (v *memoryView) bounds() rl.Rectangle {
+	x := float32(0)
+	if v.dockRight {
+		x = float32(width - memoryViewWidth)
+	}
+	return rl.NewRectangle(x, float32(topUIHeight), float32(memoryViewWidth), float32(height-topUIHeight))
+}
+
+// scroll moves the view by rows, it stops at the beginning and the end of memory.
+func (v *memoryView) scroll(rows int, memorySize int) {
+	top := int(v.top) + rows*memoryViewColumns
+	last := memorySize - v.rows()*memoryViewColumns
+	if top > last {
+		top = last
+	}
+	if top < 0 {
+		top = 0
+	}
+	v.top = uint16(top)
+}
+
+// goTo moves the cursor to address and scrolls the view to show it.
+func (v *memoryView) goTo(address uint16, memorySize int) {
+	if int(address) >= memorySize {
+		return
+	}
+	v.cursor = address
+	v.typedNibble = false
+
+	visible := uint16(v.rows() * memoryViewColumns)
+	if address < v.top || address >= v.top+visible {
+		v.top = address - address%memoryViewColumns
+		v.scroll(0, memorySize)
+	}
+}
+
+// toggle shows or hides the view.
+func (v *memoryView) toggle(chip *chip8.Chip8) {
+	v.visible = !v.visible
+	v.attachProfiler(chip)
+}
+
+// toggleHeatmap shows or hides the heatmap of executed instructions.
+func (v *memoryView) toggleHeatmap(chip *chip8.Chip8) {
+	v.heatmap = !v.heatmap
+	v.attachProfiler(chip)
+}
+
+// attachProfiler profiles chip while the heatmap is shown.
+func (v *memoryView) attachProfiler(chip *chip8.Chip8) {
+	chip.Profiler = nil
+	if v.visible && v.heatmap {
+		chip.Profiler = v.profiler
+	}
+}
+
+// onStep returns the function passed to RunTick, it observes executed instructions while the view
+// is shown. It's nil while the view is hidden so RunTick can use the engine.
+func (v *memoryView) onStep(chip *chip8.Chip8) func(firstByte, secondByte byte) {
+	if !v.visible {
+		return nil
+	}
+	return func(firstByte, secondByte byte) {
+		v.observeInstruction(chip, firstByte, secondByte)
+	}
+}
+
+// observeInstruction remembers the sprite region of draw instructions.
+func (v *memoryView) observeInstruction(chip *chip8.Chip8, firstByte, secondByte byte) {
+	if instruction := chip.DecodeBytes(firstByte, secondByte); instruction.Op == chip8.OpDraw {
+		v.spriteAddress = chip.I
+		v.spriteLength = uint16(instruction.N)
+	}
+}
+
+// typeDigit edits the byte at the cursor, the first digit replaces the high nibble and
+// the second one the low nibble and moves the cursor to the next byte. Recorded instructions
+// can't be undone after the edit.
+func (v *memoryView) typeDigit(chip *chip8.Chip8, digit byte) {
+	if chip.Undo != nil {
+		chip.Undo.Clear()
+	}
+	value := chip.ReadMemory(v.cursor)
+	if !v.typedNibble {
+		chip.WriteMemory(v.cursor, digit<<4|value&0xf)
+		v.typedNibble = true
+		return
+	}
+
+	chip.WriteMemory(v.cursor, value&0xf0|digit)
+	v.goTo(v.cursor+1, len(chip.Memory))
+}
+
+// addressAt returns the address of the byte under point.
+func (v *memoryView) addressAt(point rl.Vector2, memorySize int) (uint16, bool) {
+	bounds := v.bounds()
+	x := int32(point.X-bounds.X) - 10 - memoryViewAddressWidth
+	y := int32(point.Y-bounds.Y) - memoryViewHeaderHeight
+	if x < 0 || y < 0 || x >= memoryViewColumns*memoryViewCellWidth {
+		return 0, false
+	}
+
+	row := int(y / memoryViewRowHeight)
+	if row >= v.rows() {
+		return 0, false
+	}
+	address := int(v.top) + row*memoryViewColumns + int(x/memoryViewCellWidth)
+	if address >= memorySize {
+		return 0, false
+	}
+	return uint16(address), true
+}
+
+// parseAddressInput parses hexadecimal address typed by the user, with or without 0x.
+func parseAddressInput(text string) (uint16, bool) {
+	text = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(text)), "0x")
+	address, err := strconv.ParseUint(text, 16, 16)
+	return uint16(address), err == nil
+}
+
+// update handles scrolling, navigation and editing, memory can be edited only when paused.
+func (v *memoryView) update(chip *chip8.Chip8, paused bool) {
+	memorySize := len(chip.Memory)
+	rl.SetMouseOffset(0, 0)
+	mouse := rl.GetMousePosition()
+
+	if rl.CheckCollisionPointRec(mouse, v.bounds()) {
+		v.scroll(-int(rl.GetMouseWheelMove()), memorySize)
+	}
+	if rl.IsKeyPressed(rl.KeyPageUp) {
+		v.scroll(-v.rows(), memorySize)
+	}
+	if rl.IsKeyPressed(rl.KeyPageDown) {
+		v.scroll(v.rows(), memorySize)
+	}
+
+	if !paused || v.addressEdit {
+		return
+	}
+
+	if rl.IsMouseButtonPressed(rl.MouseButtonLeft) {
+		if address, ok := v.addressAt(mouse, memorySize); ok {
+			v.goTo(address, memorySize)
+		}
+	}
+
+	switch {
+	case rl.IsKeyPressed(rl.KeyLeft) && v.cursor > 0:
+		v.goTo(v.cursor-1, memorySize)
+	case rl.IsKeyPressed(rl.KeyRight):
+		v.goTo(v.cursor+1, memorySize)
+	case rl.IsKeyPressed(rl.KeyUp) && v.cursor >= memoryViewColumns:
+		v.goTo(v.cursor-memoryViewColumns, memorySize)
+	case rl.IsKeyPressed(rl.KeyDown):
+		v.goTo(v.cursor+memoryViewColumns, memorySize)
+	}
+
+	for char := rl.GetCharPressed(); char != 0; char = rl.GetCharPressed() {
+		if digit, err := strconv.ParseUint(string(char), 16, 8); err == nil {
+			v.typeDigit(chip, byte(digit))
+		}
+	}
+}
+
+// draw draws the view on the screen, it should be called between BeginDrawing and EndDrawing.
+func (v *memoryView) draw(chip *chip8.Chip8, paused bool) {
+	bounds := v.bounds()
+	rl.DrawRectangleRec(bounds, rl.NewColor(0x16, 0x13, 0x13, 0xf0))
+	rl.SetMouseOffset(0, 0)
+
+	// header with address navigation
+	header := func(x, w float32) rl.Rectangle {
+		return rl.NewRectangle(bounds.X+x, bounds.Y+5, w, 30)
+	}
+	if gui.TextBox(header(10, 80), &v.addressInput, 5, v.addressEdit) {
+		v.addressEdit = !v.addressEdit
+		if address, ok := parseAddressInput(v.addressInput); ok && !v.addressEdit {
+			v.goTo(address, len(chip.Memory))
+		}
+	}
+	if gui.Button(header(95, 45), "Go") {
+		if address, ok := parseAddressInput(v.addressInput); ok {
+			v.goTo(address, len(chip.Memory))
+		}
+	}
+	if gui.Button(header(145, 45), "PC") {
+		v.goTo(chip.Pc, len(chip.Memory))
+	}
+	if gui.Button(header(195, 45), "I") {
+		v.goTo(chip.I, len(chip.Memory))
+	}
+	dockText := "Dock <"
+	if !v.dockRight {
+		dockText = "Dock >"
+	}
+	if gui.Button(header(245, 70), dockText) {
+		v.dockRight = !v.dockRight
+	}
+	if gui.Button(header(320, 55), "Heat") {
+		v.toggleHeatmap(chip)
+	}
+	if paused {
+		rl.DrawText("PAUSED", int32(bounds.X)+385, int32(bounds.Y)+10, 20, uiTextColor)
+	}
+
+	maxHits := uint64(0)
+	if v.heatmap {
+		maxHits = v.profiler.MaxHits()
+	}
+
+	for row := 0; row < v.rows(); row++ {
+		rowAddress := int(v.top) + row*memoryViewColumns
+		if rowAddress >= len(chip.Memory) {
+			break
+		}
+		y := int32(bounds.Y) + memoryViewHeaderHeight + int32(row)*memoryViewRowHeight
+		rl.DrawText(fmt.Sprintf("%03X", rowAddress), int32(bounds.X)+10, y+2, 20, rl.Gray)
+
+		for column := 0; column < memoryViewColumns && rowAddress+column < len(chip.Memory); column++ {
+			address := uint16(rowAddress + column)
+			x := int32(bounds.X) + 10 + memoryViewAddressWidth + int32(column)*memoryViewCellWidth
+			v.drawCell(chip, address, x, y, paused, maxHits)
+		}
+	}
+}
+
+// heatColor returns color of an instruction executed hits times, the scale is logarithmic
+// so rarely executed code is still visible next to the main loop.
+func heatColor(hits, maxHits uint64) rl.Color {
+	heat := math.Log1p(float64(hits)) / math.Log1p(float64(maxHits))
+	return rl.NewColor(0xff, uint8(0xc0*(1-heat)), 0x00, uint8(0x40+0x90*heat))
+}
+
+func (v *memoryView) drawCell(chip *chip8.Chip8, address uint16, x, y int32, paused bool, maxHits uint64) {
+	cell := rl.NewRectangle(float32(x)-2, float32(y), float32(memoryViewCellWidth), float32(memoryViewRowHeight))
+	textColor := uiTextColor
+
+	// both bytes of an executed instruction are colored
+	if maxHits > 0 {
+		hits := v.profiler.Hits(address)
+		if hits == 0 && address > 0 {
+			hits = v.profiler.Hits(address - 1)
+		}
+		if hits > 0 && v.profiler.Kind(address)&chip8.KindCode != 0 {
+			rl.DrawRectangleRec(cell, heatColor(hits, maxHits))
+		}
+	}
+
+	switch {
+	case address == chip.Pc || address == chip.Pc+1:
+		rl.DrawRectangleRec(cell, uiTextColor)
+		textColor = rl.Black
+	case address == chip.I:
+		rl.DrawRectangleRec(cell, rl.DarkBlue)
+	case address >= v.spriteAddress && address < v.spriteAddress+v.spriteLength:
+		rl.DrawRectangleRec(cell, rl.DarkPurple)
+	}
+
+	// recently written bytes flash and fade out
+	if age, ok := v.tracker.Age(address); ok && age < flashFrames {
+		alpha := uint8(0xff * (flashFrames - age) / flashFrames)
+		if age/4%2 == 0 {
+			rl.DrawRectangleRec(cell, rl.NewColor(0xe6, 0x29, 0x37, alpha))
+		}
+	}
+
+	if paused && address == v.cursor {
+		rl.DrawRectangleLinesEx(cell, 2, rl.White)
+	}
+
+	rl.DrawText(fmt.Sprintf("%02X", chip.ReadMemory(address)), x, y+2, 20, textColor)
+}
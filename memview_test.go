@@ -0,0 +1,112 @@
+package main
+
+import (
+	"chip8emulator/chip8"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestMemoryView(t *testing.T) {
+	t.Run("Typing two digits edits the byte and moves the cursor", func(t *testing.T) {
+		chip := chip8.NewChip8()
+		view := newMemoryView(chip)
+		view.goTo(0x300, len(chip.Memory))
+
+		view.typeDigit(chip, 0xa)
+		view.typeDigit(chip, 0x5)
+		view.typeDigit(chip, 0xf)
+
+		if chip.Memory[0x300] != 0xa5 {
+			t.Errorf("got %02X, want A5", chip.Memory[0x300])
+		}
+		if chip.Memory[0x301] != 0xf0 || view.cursor != 0x301 {
+			t.Errorf("got %02X at cursor %03X, want F0 at 301", chip.Memory[0x301], view.cursor)
+		}
+	})
+
+	t.Run("Scrolling stops at the end of memory", func(t *testing.T) {
+		chip := chip8.NewChip8()
+		view := newMemoryView(chip)
+
+		view.scroll(1000, len(chip.Memory))
+		want := uint16(len(chip.Memory) - view.rows()*memoryViewColumns)
+		if view.top != want {
+			t.Errorf("got top %03X, want %03X", view.top, want)
+		}
+
+		view.scroll(-1000, len(chip.Memory))
+		if view.top != 0 {
+			t.Errorf("got top %03X, want 0", view.top)
+		}
+	})
+
+	t.Run("Going to an address scrolls to its row", func(t *testing.T) {
+		chip := chip8.NewChip8()
+		view := newMemoryView(chip)
+
+		view.goTo(0x5a7, len(chip.Memory))
+
+		if view.top != 0x5a0 || view.cursor != 0x5a7 {
+			t.Errorf("got top %03X and cursor %03X, want 5A0 and 5A7", view.top, view.cursor)
+		}
+	})
+
+	t.Run("Address under the mouse", func(t *testing.T) {
+		chip := chip8.NewChip8()
+		view := newMemoryView(chip)
+		view.dockRight = false
+		view.top = 0x200
+
+		point := rl.NewVector2(float32(10+memoryViewAddressWidth+2*memoryViewCellWidth+5),
+			float32(topUIHeight+memoryViewHeaderHeight+memoryViewRowHeight+5))
+		address, ok := view.addressAt(point, len(chip.Memory))
+
+		if !ok || address != 0x212 {
+			t.Errorf("got %03X %v, want 212", address, ok)
+		}
+	})
+
+	t.Run("RunTick uses the engine while the view is hidden", func(t *testing.T) {
+		chip := chip8.NewChip8()
+		view := newMemoryView(chip)
+		engine := &countingEngine{}
+		chip.Engine = engine
+
+		chip.RunTick(view.onStep(chip))
+		if engine.instructions == 0 {
+			t.Errorf("the hidden view bypassed the engine")
+		}
+
+		// showing the view observes instructions but profiles only with the heatmap
+		view.toggle(chip)
+		if view.onStep(chip) == nil || chip.Profiler != nil {
+			t.Errorf("got onStep %v and profiler %v, want only onStep", view.onStep(chip) != nil, chip.Profiler)
+		}
+		view.toggleHeatmap(chip)
+		view.toggle(chip)
+		if chip.Profiler != nil {
+			t.Errorf("the profiler stayed attached after hiding the heatmap")
+		}
+	})
+
+	t.Run("Parse address input", func(t *testing.T) {
+		for input, want := range map[string]uint16{"200": 0x200, "0x3A0": 0x3a0, " fff ": 0xfff} {
+			if got, ok := parseAddressInput(input); !ok || got != want {
+				t.Errorf("got %03X %v, want %03X", got, ok, want)
+			}
+		}
+		if _, ok := parseAddressInput("zz"); ok {
+			t.Errorf("didn't expect zz to be an address")
+		}
+	})
+}
+
+// countingEngine counts the instructions it was asked to run.
+type countingEngine struct {
+	instructions int
+}
+
+func (e *countingEngine) Run(instructions int) {
+	e.instructions += instructions
+}
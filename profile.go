@@ -0,0 +1,69 @@
+package main
+
+import (
+	"chip8emulator/chip8"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runProfile runs the program without the window and reports how often every address executed.
+func runProfile(args []string) error {
+	flags := flag.NewFlagSet("profile", flag.ContinueOnError)
+	frames := flags.Int("frames", 600, "number of frames to run")
+	timing := addTimingFlags(flags)
+	platform := addPlatformFlag(flags)
+	movie := flags.String("movie", "", "input movie played back during the run")
+	format := flags.String("format", "text", "format of the report: text, json or disasm (annotated disassembly)")
+	top := flags.Int("top", 20, "number of the hottest addresses in the text report, 0 lists all")
+	output := flags.String("o", "", "output file, defaults to the standard output")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: profile [flags] file.ch8")
+	}
+
+	selected, err := platform.platform()
+	if err != nil {
+		return err
+	}
+	headless, err := newHeadlessRun(flags.Arg(0), *movie, selected)
+	if err != nil {
+		return err
+	}
+	chip := headless.chip
+	if err := timing.attach(chip); err != nil {
+		return err
+	}
+	profiler := chip8.NewProfiler(len(chip.Memory))
+	chip.Profiler = profiler
+	tracker := chip8.NewCodeTracker(len(chip.Memory))
+	chip.CodeTracker = tracker
+	headless.run(*frames, nil)
+
+	report := profiler.Report(chip.Platform, chip.Memory)
+	report.SelfModifications = tracker.Modifications()
+
+	var write func(w io.Writer) error
+	switch *format {
+	case "text":
+		write = func(w io.Writer) error { return report.WriteText(w, *top) }
+	case "json":
+		write = report.WriteJSON
+	case "disasm":
+		write = func(w io.Writer) error {
+			return profiler.WriteAnnotatedDisassembly(w, chip.Platform, chip.Memory, int(chip.Platform.Layout().LoadAddress), len(chip.Memory), tracker)
+		}
+	default:
+		return fmt.Errorf("unknown report format %q", *format)
+	}
+
+	if *output == "" {
+		return write(os.Stdout)
+	}
+	return writeFile(*output, write)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"chip8emulator/chip8"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunProfile(t *testing.T) {
+	t.Run("Writes JSON report of snake.ch8", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "snake.json")
+
+		err := runProfile([]string{"-frames", "30", "-format", "json", "-o", path, "snake.ch8"})
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		var report chip8.ProfileReport
+		if err := json.Unmarshal(content, &report); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		if report.Instructions != 300 || len(report.Addresses) == 0 {
+			t.Errorf("got %d instructions at %d addresses, want 300 at some addresses", report.Instructions, len(report.Addresses))
+		}
+	})
+
+	t.Run("Return error for unknown format", func(t *testing.T) {
+		err := runProfile([]string{"-frames", "1", "-format", "xml", "snake.ch8"})
+
+		assertErrorExpected(t, err)
+	})
+}
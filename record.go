@@ -0,0 +1,137 @@
+package main
+
+import (
+	"chip8emulator/chip8"
+	"errors"
+	"flag"
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const recordingScale = 4
+const maxRecordingSeconds = 30
+
+// runRecord runs the program without the window and saves its frames as an animated GIF.
+func runRecord(args []string) error {
+	flags := flag.NewFlagSet("record", flag.ContinueOnError)
+	frames := flags.Int("frames", 600, "number of frames to run")
+	timing := addTimingFlags(flags)
+	scale := flags.Int("scale", recordingScale, "integer scale of the recording")
+	primary := flags.String("color", "ffffff", "primary color in RRGGBB format")
+	seconds := flags.Int("max", maxRecordingSeconds, "maximum length of the recording in seconds, 0 means no limit")
+	movie := flags.String("movie", "", "input movie played back during the run")
+	trace := addTraceFlags(flags)
+	engine := addEngineFlag(flags)
+	quirks := addQuirksFlag(flags)
+	platform := addPlatformFlag(flags)
+	output := flags.String("o", "", "output file, defaults to <rom>_<time>.gif in the current directory")
+	wav := flags.String("wav", "", "also write the sound of the buzzer to this WAV file")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: record [flags] file.ch8")
+	}
+
+	tint, err := parseHexColor(*primary)
+	if err != nil {
+		return err
+	}
+
+	rom := flags.Arg(0)
+	selected, err := platform.platform()
+	if err != nil {
+		return err
+	}
+	headless, err := newHeadlessRun(rom, *movie, selected)
+	if err != nil {
+		return err
+	}
+	if err := engine.attach(headless.chip); err != nil {
+		return err
+	}
+	if err := quirks.attach(headless.chip); err != nil {
+		return err
+	}
+	if err := timing.attach(headless.chip); err != nil {
+		return err
+	}
+	closeTrace, err := trace.attach(headless.chip)
+	if err != nil {
+		return err
+	}
+
+	recorder := chip8.NewRecorder(*scale, tint, *seconds*60)
+	audio := chip8.NewAudioRecorder(*seconds * 60)
+	headless.run(*frames, func(frame int, sound bool) bool {
+		audio.AddFrame(sound)
+		return recorder.AddFrame(headless.chip)
+	})
+	if err := closeTrace(); err != nil {
+		return err
+	}
+
+	path := *output
+	if path == "" {
+		path, err = recorder.SaveGIF(".", rom)
+		if err != nil {
+			return err
+		}
+	} else {
+		if err := writeFile(path, recorder.WriteGIF); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stdout, "recorded %d frames to %s\n", recorder.Frames(), path)
+
+	if *wav != "" {
+		if err := writeFile(*wav, audio.WriteWAV); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "recorded %d frames of audio to %s\n", audio.Frames(), *wav)
+	}
+	return nil
+}
+
+// recording is a recording of the video and the sound started from the window.
+type recording struct {
+	start time.Time
+	video *chip8.Recorder
+	audio *chip8.AudioRecorder
+}
+
+func newRecording(tint color.RGBA) *recording {
+	return &recording{
+		start: time.Now(),
+		video: chip8.NewRecorder(recordingScale, tint, maxRecordingSeconds*60),
+		audio: chip8.NewAudioRecorder(maxRecordingSeconds * 60),
+	}
+}
+
+// addFrame records the screen of chip and the state of the buzzer.
+// It returns false when the recording reached its maximum length.
+func (r *recording) addFrame(chip *chip8.Chip8, buzzer bool) bool {
+	r.audio.AddFrame(buzzer)
+	return r.video.AddFrame(chip)
+}
+
+// save writes GIF and WAV files named after rom and the start of the recording to dir.
+func (r *recording) save(dir, rom string) error {
+	videoPath := filepath.Join(dir, chip8.RecordingFilename(rom, r.start))
+	if err := writeFile(videoPath, r.video.WriteGIF); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, "recording saved to", videoPath)
+
+	audioPath := filepath.Join(dir, chip8.AudioFilename(rom, r.start))
+	if err := writeFile(audioPath, r.audio.WriteWAV); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, "audio saved to", audioPath)
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunRecord(t *testing.T) {
+	t.Run("Records snake.ch8 with input movie up to the maximum length", func(t *testing.T) {
+		dir := t.TempDir()
+		moviePath := filepath.Join(dir, "snake.txt")
+		if err := os.WriteFile(moviePath, []byte("20 6\n40 .\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, "snake.gif")
+		wavPath := filepath.Join(dir, "snake.wav")
+
+		err := runRecord([]string{"-frames", "120", "-max", "1", "-scale", "1", "-movie", moviePath, "-o", path, "-wav", wavPath, "snake.ch8"})
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		defer file.Close()
+
+		animation, err := gif.DecodeAll(file)
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		total := 0
+		for _, delay := range animation.Delay {
+			total += delay
+		}
+		if total != 100 {
+			t.Errorf("got %d hundredths of a second, want 100", total)
+		}
+
+		info, err := os.Stat(wavPath)
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		// one second of 16-bit samples and the header
+		if info.Size() != 44+44100*2 {
+			t.Errorf("got WAV size %d, want %d", info.Size(), 44+44100*2)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"chip8emulator/chip8"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+var update = flag.Bool("update", false, "regenerate golden files in testdata")
+
+// romTest runs a whole program and compares the final screen with a golden file in testdata.
+// Goldens ending with .txt contain text art of the screen and goldens ending with .png an image.
+type romTest struct {
+	rom      string
+	frames   int
+	tickrate int
+	// quirks is the name of the quirk profile, empty means chip8
+	quirks string
+	// movie in testdata with keys pressed during the run, empty means no input
+	movie  string
+	golden string
+}
+
+var romTests = []romTest{
+	{rom: "snake.ch8", frames: 120, tickrate: 10, golden: "snake_start.txt"},
+	{rom: "snake.ch8", frames: 667, tickrate: 10, movie: "snake_turns.txt", golden: "snake_turns.txt"},
+	{rom: "down8.ch8", frames: 180, tickrate: 10, golden: "down8.png"},
+	{rom: "flightrunner.ch8", frames: 240, tickrate: 10, movie: "flightrunner_start.txt", golden: "flightrunner.txt"},
+	{rom: "slipperyslope.ch8", frames: 120, tickrate: 10, golden: "slipperyslope.png"},
+	{rom: "testdata/roms/displaywait.ch8", frames: 60, tickrate: 20, golden: "displaywait_chip8.txt"},
+	{rom: "testdata/roms/displaywait.ch8", frames: 60, tickrate: 20, quirks: "vip", golden: "displaywait_vip.txt"},
+}
+
+func TestROMs(t *testing.T) {
+	for _, test := range romTests {
+		t.Run(test.rom+" "+test.golden, func(t *testing.T) {
+			moviePath := ""
+			if test.movie != "" {
+				moviePath = filepath.Join("testdata", "movies", test.movie)
+			}
+
+			headless, err := newHeadlessRun(test.rom, moviePath, chip8.PlatformChip8)
+			if err != nil {
+				t.Fatalf("didn't expect an error, got %v", err)
+			}
+			headless.chip.Timing = chip8.TickrateTiming(test.tickrate)
+			if test.quirks != "" {
+				headless.chip.Quirks, err = chip8.QuirkProfile(test.quirks)
+				if err != nil {
+					t.Fatalf("didn't expect an error, got %v", err)
+				}
+			}
+			headless.run(test.frames, nil)
+
+			goldenPath := filepath.Join("testdata", "golden", test.golden)
+			if strings.HasSuffix(test.golden, ".png") {
+				assertGoldenPNG(t, goldenPath, headless.chip.Image(1, rl.White))
+			} else {
+				assertGoldenText(t, goldenPath, headless.chip.ScreenText())
+			}
+		})
+	}
+}
+
+func assertGoldenText(t testing.TB, path, got string) {
+	t.Helper()
+	if *update {
+		writeGolden(t, path, []byte(got))
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("can't read golden file, run go test -update to create it: %v", err)
+	}
+
+	if got != string(want) {
+		t.Errorf("screen doesn't match %s\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func assertGoldenPNG(t testing.TB, path string, got *image.RGBA) {
+	t.Helper()
+	if *update {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, got); err != nil {
+			t.Fatal(err)
+		}
+		writeGolden(t, path, buf.Bytes())
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("can't read golden file, run go test -update to create it: %v", err)
+	}
+	defer file.Close()
+
+	want, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("can't decode golden file: %v", err)
+	}
+
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("got size %v, want %v", got.Bounds(), want.Bounds())
+	}
+
+	for y := 0; y < got.Bounds().Dy(); y++ {
+		for x := 0; x < got.Bounds().Dx(); x++ {
+			if got.RGBAAt(x, y) != color.RGBAModel.Convert(want.At(x, y)) {
+				t.Fatalf("screen doesn't match %s, first difference at (%d, %d)", path, x, y)
+			}
+		}
+	}
+}
+
+func writeGolden(t testing.TB, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
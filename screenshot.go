@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runScreenshot runs the program for the given number of frames without opening the window
+// and saves the screen to a PNG file.
+func runScreenshot(args []string) error {
+	flags := flag.NewFlagSet("screenshot", flag.ContinueOnError)
+	frames := flags.Int("frames", 60, "number of frames to run before taking the screenshot")
+	timing := addTimingFlags(flags)
+	scale := flags.Int("scale", 1, "integer scale of the screenshot")
+	primary := flags.String("color", "ffffff", "primary color in RRGGBB format")
+	movie := flags.String("movie", "", "input movie played back during the run")
+	trace := addTraceFlags(flags)
+	engine := addEngineFlag(flags)
+	quirks := addQuirksFlag(flags)
+	platform := addPlatformFlag(flags)
+	output := flags.String("o", "", "output file, defaults to <rom>_<time>.png in the current directory")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: screenshot [flags] file.ch8")
+	}
+
+	tint, err := parseHexColor(*primary)
+	if err != nil {
+		return err
+	}
+
+	rom := flags.Arg(0)
+	selected, err := platform.platform()
+	if err != nil {
+		return err
+	}
+	headless, err := newHeadlessRun(rom, *movie, selected)
+	if err != nil {
+		return err
+	}
+	if err := engine.attach(headless.chip); err != nil {
+		return err
+	}
+	if err := quirks.attach(headless.chip); err != nil {
+		return err
+	}
+	if err := timing.attach(headless.chip); err != nil {
+		return err
+	}
+	closeTrace, err := trace.attach(headless.chip)
+	if err != nil {
+		return err
+	}
+	headless.run(*frames, nil)
+	if err := closeTrace(); err != nil {
+		return err
+	}
+	chip := headless.chip
+
+	path := *output
+	if path == "" {
+		path, err = chip.SaveScreenshot(".", rom, *scale, tint)
+		if err != nil {
+			return err
+		}
+	} else {
+		err := writeFile(path, func(w io.Writer) error {
+			return chip.WritePNG(w, *scale, tint)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(os.Stdout, "screenshot saved to", path)
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunScreenshot(t *testing.T) {
+	t.Run("Saves scaled screenshot of snake.ch8", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "snake.png")
+
+		err := runScreenshot([]string{"-frames", "30", "-scale", "2", "-o", path, "snake.ch8"})
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		defer file.Close()
+
+		img, err := png.Decode(file)
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		if img.Bounds().Dx() != 128 || img.Bounds().Dy() != 64 {
+			t.Errorf("got size %v, want 128x64", img.Bounds())
+		}
+	})
+
+	t.Run("Engines save the same screenshot", func(t *testing.T) {
+		var screenshots [][]byte
+		for _, engine := range []string{"interpreter", "cached", "compiled"} {
+			path := filepath.Join(t.TempDir(), engine+".png")
+
+			err := runScreenshot([]string{"-frames", "120", "-tickrate", "50", "-engine", engine, "-o", path, "snake.ch8"})
+			if err != nil {
+				t.Fatalf("didn't expect an error, got %v", err)
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("didn't expect an error, got %v", err)
+			}
+			screenshots = append(screenshots, content)
+		}
+
+		if !bytes.Equal(screenshots[0], screenshots[1]) || !bytes.Equal(screenshots[0], screenshots[2]) {
+			t.Errorf("got different screenshots from engines")
+		}
+	})
+
+	t.Run("Return error for unknown engine", func(t *testing.T) {
+		err := runScreenshot([]string{"-frames", "1", "-engine", "turbo", "snake.ch8"})
+
+		assertErrorExpected(t, err)
+	})
+
+	t.Run("Return error for unknown quirk profile", func(t *testing.T) {
+		err := runScreenshot([]string{"-frames", "1", "-quirks", "vip2", "snake.ch8"})
+
+		assertErrorExpected(t, err)
+	})
+
+	t.Run("Saves 64x64 screenshot of a HIRES program", func(t *testing.T) {
+		// 1260 enters HIRES, the program loops at 0x2C0
+		program := make([]byte, 0xc2)
+		copy(program, []byte{0x12, 0x60})
+		copy(program[0xc0:], []byte{0x12, 0xc0})
+		rom := filepath.Join(t.TempDir(), "hires.ch8")
+		if err := os.WriteFile(rom, program, 0o644); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		path := filepath.Join(t.TempDir(), "hires.png")
+
+		err := runScreenshot([]string{"-frames", "1", "-platform", "hires", "-o", path, rom})
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		defer file.Close()
+		img, err := png.Decode(file)
+		if err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		if img.Bounds().Dx() != 64 || img.Bounds().Dy() != 64 {
+			t.Errorf("got size %v, want 64x64", img.Bounds())
+		}
+	})
+
+	t.Run("Return error for unknown platform", func(t *testing.T) {
+		err := runScreenshot([]string{"-frames", "1", "-platform", "schip", "snake.ch8"})
+
+		assertErrorExpected(t, err)
+	})
+
+	t.Run("Return error if no rom was given", func(t *testing.T) {
+		err := runScreenshot([]string{"-frames", "1"})
+
+		assertErrorExpected(t, err)
+	})
+}
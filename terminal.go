@@ -0,0 +1,64 @@
+package main
+
+import (
+	"chip8emulator/chip8"
+	"chip8emulator/terminal"
+	"errors"
+	"flag"
+	"os"
+)
+
+// runTerminal runs the program in the terminal instead of the window.
+func runTerminal(args []string) error {
+	flags := flag.NewFlagSet("terminal", flag.ContinueOnError)
+	timing := addTimingFlags(flags)
+	braille := flags.Bool("braille", false, "draw pixels with braille characters instead of half blocks")
+	primary := flags.String("color", "38f620", "primary color in RRGGBB format")
+	trace := addTraceFlags(flags)
+	quirks := addQuirksFlag(flags)
+	platform := addPlatformFlag(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: terminal [flags] file.ch8")
+	}
+
+	tint, err := parseHexColor(*primary)
+	if err != nil {
+		return err
+	}
+
+	program, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	chip := chip8.NewChip8()
+	if err := platform.attach(chip); err != nil {
+		return err
+	}
+	chip.ClearScreen()
+	chip.LoadFont()
+	chip.LoadProgram(program)
+	if err := quirks.attach(chip); err != nil {
+		return err
+	}
+	if err := timing.attach(chip); err != nil {
+		return err
+	}
+
+	closeTrace, err := trace.attach(chip)
+	if err != nil {
+		return err
+	}
+	defer closeTrace()
+
+	options := terminal.Options{Mode: terminal.HalfBlock, Primary: tint}
+	if *braille {
+		options.Mode = terminal.Braille
+	}
+
+	return terminal.Run(chip, os.Stdin, os.Stdout, options)
+}
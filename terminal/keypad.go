@@ -0,0 +1,76 @@
+package terminal
+
+import "sync"
+
+// keyHoldFrames is the number of frames a key stays down after it was typed. Terminals don't
+// report releasing keys, holding a key down is seen as repeated typing.
+const keyHoldFrames = 8
+
+// keymap uses the same layout as the window: 1-4, Q-R, A-F and Z-V.
+var keymap = map[byte]byte{
+	'1': 0x1, '2': 0x2, '3': 0x3, '4': 0xc,
+	'q': 0x4, 'w': 0x5, 'e': 0x6, 'r': 0xd,
+	'a': 0x7, 's': 0x8, 'd': 0x9, 'f': 0xe,
+	'z': 0xa, 'x': 0x0, 'c': 0xb, 'v': 0xf,
+}
+
+// Keypad is a chip8.Keypad fed with characters typed in the terminal.
+type Keypad struct {
+	mu      sync.Mutex
+	frame   int
+	heldTo  [16]int
+	pressed []byte
+}
+
+// NewKeypad creates keypad without any keys held.
+func NewKeypad() *Keypad {
+	k := &Keypad{}
+	for i := range k.heldTo {
+		k.heldTo[i] = -1
+	}
+	return k
+}
+
+// Type handles a character typed in the terminal. It returns false if the character is not on the keypad.
+func (k *Keypad) Type(ch byte) bool {
+	// accept capital letters when caps lock is on
+	if ch >= 'A' && ch <= 'Z' {
+		ch += 'a' - 'A'
+	}
+	key, ok := keymap[ch]
+	if !ok {
+		return false
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.heldTo[key] < k.frame {
+		k.pressed = append(k.pressed, key)
+	}
+	k.heldTo[key] = k.frame + keyHoldFrames
+	return true
+}
+
+// NextFrame advances time used to release keys.
+func (k *Keypad) NextFrame() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.frame++
+}
+
+func (k *Keypad) IsKeyDown(key byte) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.heldTo[key&0xf] >= k.frame
+}
+
+func (k *Keypad) KeyPressed() (byte, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(k.pressed) == 0 {
+		return 0, false
+	}
+	key := k.pressed[0]
+	k.pressed = k.pressed[1:]
+	return key, true
+}
@@ -0,0 +1,102 @@
+package terminal
+
+import (
+	"chip8emulator/chip8"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// Mode selects characters used to draw pixels.
+type Mode int
+
+const (
+	// HalfBlock draws two pixels (top and bottom) per character using '▀' with foreground and background colors.
+	HalfBlock Mode = iota
+	// Braille draws 2x4 pixels per character using braille dots.
+	Braille
+)
+
+// brailleDots contains bits of the braille character for pixel (x, y) of the 2x4 cell.
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// Render draws the screen of the chip to w starting from the top left corner of the terminal.
+// Lit pixels are drawn with primary color and the rest with the secondary color of the chip.
+func Render(w io.Writer, c *chip8.Chip8, mode Mode, primary color.RGBA) error {
+	var b strings.Builder
+	// move cursor to the top left corner
+	b.WriteString("\x1b[H")
+
+	if mode == Braille {
+		renderBraille(&b, c, primary)
+	} else {
+		renderHalfBlock(&b, c, primary)
+	}
+
+	// reset colors
+	b.WriteString("\x1b[0m")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func renderHalfBlock(b *strings.Builder, c *chip8.Chip8, primary color.RGBA) {
+	for y := 0; y < int(c.Height); y += 2 {
+		var lastTop, lastBottom color.RGBA
+		for x := 0; x < int(c.Width); x++ {
+			top := pixelColor(c, x, y, primary)
+			bottom := pixelColor(c, x, y+1, primary)
+
+			// colors are only written when they change to keep the output small
+			if x == 0 || top != lastTop {
+				fmt.Fprintf(b, "\x1b[38;2;%d;%d;%dm", top.R, top.G, top.B)
+			}
+			if x == 0 || bottom != lastBottom {
+				fmt.Fprintf(b, "\x1b[48;2;%d;%d;%dm", bottom.R, bottom.G, bottom.B)
+			}
+			b.WriteRune('▀')
+			lastTop, lastBottom = top, bottom
+		}
+		b.WriteString("\x1b[0m\r\n")
+	}
+}
+
+func renderBraille(b *strings.Builder, c *chip8.Chip8, primary color.RGBA) {
+	fmt.Fprintf(b, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm", primary.R, primary.G, primary.B,
+		c.SecondaryColor.R, c.SecondaryColor.G, c.SecondaryColor.B)
+
+	for y := 0; y < int(c.Height); y += 4 {
+		for x := 0; x < int(c.Width); x += 2 {
+			var dots rune
+			for dy := 0; dy < 4; dy++ {
+				for dx := 0; dx < 2; dx++ {
+					if isLit(c, x+dx, y+dy) {
+						dots |= brailleDots[dy][dx]
+					}
+				}
+			}
+			b.WriteRune(0x2800 + dots)
+		}
+		b.WriteString("\r\n")
+	}
+}
+
+// isLit reports whether pixel (x, y) is on, pixels outside of the screen are off.
+func isLit(c *chip8.Chip8, x, y int) bool {
+	if x >= int(c.Width) || y >= int(c.Height) {
+		return false
+	}
+	return c.Screen[x+y*int(c.Width)] == c.PrimaryColor
+}
+
+func pixelColor(c *chip8.Chip8, x, y int, primary color.RGBA) color.RGBA {
+	if isLit(c, x, y) {
+		return primary
+	}
+	return c.SecondaryColor
+}
@@ -0,0 +1,134 @@
+// Package terminal runs chip8 programs in a terminal, the screen is drawn with
+// unicode characters and ANSI colors and keys are read from the TTY in raw mode.
+package terminal
+
+import (
+	"chip8emulator/chip8"
+	"image/color"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// escape key quits the emulator, ctrl+c is also accepted because raw mode disables signals.
+const escape = 0x1b
+const ctrlC = 0x03
+
+// Options configure Run, the speed of the program is set by Timing of the chip.
+type Options struct {
+	Mode    Mode
+	Primary color.RGBA
+}
+
+// Run executes the program loaded into chip until escape is pressed. The screen is drawn
+// to stdout and keys are read from stdin which must be a terminal.
+func Run(chip *chip8.Chip8, in *os.File, out io.Writer, options Options) error {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(in.Fd()), state)
+
+	keypad := NewKeypad()
+	chip.Keypad = keypad
+
+	quit := make(chan struct{})
+	go readKeys(in, keypad, quit)
+
+	// hide cursor and clear the terminal, show the cursor again on exit
+	io.WriteString(out, "\x1b[?25l\x1b[2J")
+	defer io.WriteString(out, "\x1b[0m\x1b[?25h\r\n")
+
+	ticker := time.NewTicker(chip8.TickDuration)
+	defer ticker.Stop()
+
+	// the ticker drops ticks when drawing is slow, the pacer runs them later
+	pacer := chip8.Pacer{MaxTicks: 10}
+	last := time.Now()
+	var lastScreen []color.RGBA
+	buzzer := false
+	for {
+		var now time.Time
+		select {
+		case <-quit:
+			return nil
+		case now = <-ticker.C:
+		}
+
+		ticks := pacer.Advance(now.Sub(last))
+		last = now
+		if ticks == 0 {
+			continue
+		}
+		keypad.NextFrame()
+		sound := false
+		for ; ticks > 0; ticks-- {
+			sound = chip.RunTick(nil) || sound
+		}
+		// ring the terminal bell when the buzzer starts
+		if sound && !buzzer {
+			io.WriteString(out, "\a")
+		}
+		buzzer = sound
+
+		if slices.Equal(lastScreen, chip.Screen) {
+			continue
+		}
+		lastScreen = slices.Clone(chip.Screen)
+		if err := Render(out, chip, options.Mode, options.Primary); err != nil {
+			return err
+		}
+	}
+}
+
+// readKeys passes typed characters to keypad and closes quit when escape is pressed.
+func readKeys(in io.Reader, keypad *Keypad, quit chan<- struct{}) {
+	defer close(quit)
+
+	buf := make([]byte, 16)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+		for i := 0; i < n; {
+			// escape sequences of arrow keys etc. arrive in one read, a lone escape is the escape key
+			if buf[i] == escape {
+				length := escapeSequenceLength(buf[i:n])
+				if length == 1 {
+					return
+				}
+				i += length
+				continue
+			}
+			if buf[i] == ctrlC {
+				return
+			}
+			keypad.Type(buf[i])
+			i++
+		}
+	}
+}
+
+// escapeSequenceLength returns the length of the escape sequence at the start of data, e.g. ESC [ A
+// of the up arrow or ESC O P of F1. Escape followed by another character is alt with the key.
+func escapeSequenceLength(data []byte) int {
+	if len(data) < 2 {
+		return len(data)
+	}
+	switch data[1] {
+	case '[':
+		// parameters and intermediate bytes are followed by one final byte
+		length := 2
+		for length < len(data) && data[length] >= 0x20 && data[length] <= 0x3f {
+			length++
+		}
+		return min(length+1, len(data))
+	case 'O':
+		return min(3, len(data))
+	}
+	return 2
+}
@@ -0,0 +1,115 @@
+package terminal
+
+import (
+	"chip8emulator/chip8"
+	"io"
+	"strings"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func newTestChip(width, height byte) *chip8.Chip8 {
+	chip := chip8.NewChip8()
+	chip.Width = width
+	chip.Height = height
+	chip.Screen = make([]rl.Color, int(width)*int(height))
+	chip.ClearScreen()
+	return chip
+}
+
+func TestRender(t *testing.T) {
+	t.Run("Half blocks draw top pixel in foreground and bottom pixel in background", func(t *testing.T) {
+		chip := newTestChip(2, 2)
+		chip.Screen[0] = chip.PrimaryColor
+		chip.Screen[3] = chip.PrimaryColor
+
+		var b strings.Builder
+		if err := Render(&b, chip, HalfBlock, rl.Red); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		want := "\x1b[H" +
+			"\x1b[38;2;230;41;55m\x1b[48;2;0;0;0m▀" +
+			"\x1b[38;2;0;0;0m\x1b[48;2;230;41;55m▀" +
+			"\x1b[0m\r\n\x1b[0m"
+		if got := b.String(); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Braille draws 2x4 pixels per character", func(t *testing.T) {
+		chip := newTestChip(2, 4)
+		chip.Screen[0] = chip.PrimaryColor
+		chip.Screen[7] = chip.PrimaryColor
+
+		var b strings.Builder
+		if err := Render(&b, chip, Braille, rl.White); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		if got := b.String(); !strings.Contains(got, "⢁\r\n") {
+			t.Errorf("got %q, want braille character \\u2881", got)
+		}
+	})
+}
+
+func TestKeypad(t *testing.T) {
+	t.Run("Typed key is held for a few frames and pressed once", func(t *testing.T) {
+		keypad := NewKeypad()
+
+		if !keypad.Type('W') {
+			t.Fatalf("expected W to be on the keypad")
+		}
+		if !keypad.IsKeyDown(0x5) {
+			t.Errorf("expected key 5 to be down")
+		}
+
+		key, ok := keypad.KeyPressed()
+		if !ok || key != 0x5 {
+			t.Errorf("got %x %v, want 5 true", key, ok)
+		}
+		if _, ok := keypad.KeyPressed(); ok {
+			t.Errorf("didn't expect a second key press")
+		}
+
+		for i := 0; i <= keyHoldFrames; i++ {
+			keypad.NextFrame()
+		}
+		if keypad.IsKeyDown(0x5) {
+			t.Errorf("expected key 5 to be released")
+		}
+	})
+
+	t.Run("Keys typed with an escape sequence aren't lost", func(t *testing.T) {
+		keypad := NewKeypad()
+		quit := make(chan struct{})
+
+		// up arrow, W, F1 and S arrive in one read
+		readKeys(strings.NewReader("\x1b[AW\x1bOPS"), keypad, quit)
+
+		if !keypad.IsKeyDown(0x5) || !keypad.IsKeyDown(0x8) {
+			t.Errorf("expected keys 5 and 8 to be down")
+		}
+	})
+
+	t.Run("Lone escape quits", func(t *testing.T) {
+		keypad := NewKeypad()
+		quit := make(chan struct{})
+
+		// W arrives in the read after the escape key
+		readKeys(io.MultiReader(strings.NewReader("\x1b"), strings.NewReader("W")), keypad, quit)
+
+		if keypad.IsKeyDown(0x5) {
+			t.Errorf("didn't expect keys after escape")
+		}
+	})
+
+	t.Run("Characters outside of the keypad are ignored", func(t *testing.T) {
+		keypad := NewKeypad()
+
+		if keypad.Type('p') {
+			t.Errorf("didn't expect p to be on the keypad")
+		}
+	})
+}
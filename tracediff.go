@@ -0,0 +1,114 @@
+package main
+
+import (
+	"chip8emulator/chip8"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var errTracesDiverge = errors.New("traces diverge")
+
+// traceFieldNames maps names accepted by -ignore to compared fields.
+var traceFieldNames = map[string]chip8.TraceField{
+	"registers": chip8.TraceRegisters,
+	"index":     chip8.TraceIndex,
+	"stack":     chip8.TraceStack,
+	"timers":    chip8.TraceTimers,
+}
+
+// runTraceDiff compares two execution traces and reports the first instruction where they diverge.
+func runTraceDiff(args []string) error {
+	flags := flag.NewFlagSet("tracediff", flag.ContinueOnError)
+	ignore := flags.String("ignore", "", "comma separated fields which are not compared: registers, index, stack, timers")
+	context := flags.Int("context", 5, "number of instructions printed before the divergence")
+	noAlign := flags.Bool("no-align", false, "compare traces from their first entries instead of aligning them")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("usage: tracediff [flags] a.trace b.trace")
+	}
+
+	fields := chip8.TraceAll
+	if *ignore != "" {
+		for _, name := range strings.Split(*ignore, ",") {
+			field, ok := traceFieldNames[strings.TrimSpace(name)]
+			if !ok {
+				return fmt.Errorf("unknown trace field %q", name)
+			}
+			fields &^= field
+		}
+	}
+
+	a, err := readTraceFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := readTraceFile(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	return diffTraces(os.Stdout, flags.Arg(0), flags.Arg(1), a, b, fields, *context, !*noAlign)
+}
+
+func readTraceFile(path string) ([]chip8.TraceEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, err := chip8.ReadTrace(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// diffTraces writes report about the first divergence of a and b to w, it returns errTracesDiverge
+// if traces differ.
+func diffTraces(w io.Writer, nameA, nameB string, a, b []chip8.TraceEntry, fields chip8.TraceField, context int, align bool) error {
+	offset := 0
+	if align {
+		offset = chip8.AlignTraces(a, b)
+		if offset < 0 {
+			fmt.Fprintf(w, "%s never executes the first instruction of %s (%s)\n", nameB, nameA, a[0])
+			return errTracesDiverge
+		}
+		if offset > 0 {
+			fmt.Fprintf(w, "skipped %d entries of %s to align the traces\n", offset, nameB)
+		}
+	}
+
+	divergence := chip8.DiffTraces(a, b, offset, fields)
+	if divergence == nil {
+		compared := min(len(a), len(b)-offset)
+		fmt.Fprintf(w, "traces match for %d instructions\n", compared)
+		if len(a) != len(b)-offset {
+			fmt.Fprintf(w, "%s has %d entries and %s has %d entries after alignment\n", nameA, len(a), nameB, len(b)-offset)
+		}
+		return nil
+	}
+
+	fmt.Fprintf(w, "traces diverge at entry %d of %s and entry %d of %s\n", divergence.IndexA, nameA, divergence.IndexB, nameB)
+	if start := max(0, divergence.IndexA-context); start < divergence.IndexA {
+		fmt.Fprintln(w, "previous instructions:")
+		for _, entry := range a[start:divergence.IndexA] {
+			fmt.Fprintf(w, "  %s\n", entry)
+		}
+	}
+	fmt.Fprintf(w, "%s:\n  %s\n", nameA, divergence.A)
+	fmt.Fprintf(w, "%s:\n  %s\n", nameB, divergence.B)
+	fmt.Fprintln(w, "differences:")
+	for _, difference := range divergence.Differences {
+		fmt.Fprintf(w, "  %s\n", difference)
+	}
+
+	return errTracesDiverge
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"chip8emulator/chip8"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunTraceDiff(t *testing.T) {
+	t.Run("Traces of the same run match", func(t *testing.T) {
+		dir := t.TempDir()
+		text := filepath.Join(dir, "snake.txt")
+		binary := filepath.Join(dir, "snake.bin")
+		frames := []string{"-frames", "30", "-o", filepath.Join(dir, "snake.png")}
+
+		if err := runScreenshot(append(frames, "-trace", text, "snake.ch8")); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+		if err := runScreenshot(append(frames, "-trace", binary, "-trace-format", "binary", "snake.ch8")); err != nil {
+			t.Fatalf("didn't expect an error, got %v", err)
+		}
+
+		if err := runTraceDiff([]string{text, binary}); err != nil {
+			t.Errorf("didn't expect an error, got %v", err)
+		}
+	})
+
+	t.Run("Report the first divergence", func(t *testing.T) {
+		a := readTestTrace(t, "PC=0200 OP=6001 V=01000000000000000000000000000000 I=0000 SP=00 DT=00 ST=00\n"+
+			"PC=0202 OP=7001 V=02000000000000000000000000000000 I=0000 SP=00 DT=00 ST=00\n")
+		b := readTestTrace(t, "PC=0200 OP=6001 V=01000000000000000000000000000000 I=0000 SP=00 DT=00 ST=00\n"+
+			"PC=0202 OP=7001 V=03000000000000000000000000000000 I=0000 SP=00 DT=00 ST=00\n")
+
+		var report strings.Builder
+		err := diffTraces(&report, "a", "b", a, b, chip8.TraceAll, 5, true)
+
+		if err != errTracesDiverge {
+			t.Fatalf("got %v, want %v", err, errTracesDiverge)
+		}
+		if !strings.Contains(report.String(), "entry 1 of a") || !strings.Contains(report.String(), "V0: 02 != 03") {
+			t.Errorf("unexpected report:\n%s", report.String())
+		}
+	})
+}
+
+func readTestTrace(t testing.TB, text string) []chip8.TraceEntry {
+	t.Helper()
+	entries, err := chip8.ReadTrace(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}